@@ -0,0 +1,67 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/emma769/a-realtor/internal/migrate"
+	"github.com/emma769/a-realtor/migrations"
+)
+
+var errIntegrity = errors.New("integrity checks found issues")
+
+func (a *app) check(ctx context.Context) error {
+	migrator, err := migrate.New(a.store.DB(), migrations.FS, a.logger)
+	if err != nil {
+		return err
+	}
+
+	version, dirty, err := migrator.Version(ctx)
+	if err != nil {
+		return err
+	}
+
+	results, err := a.store.RunIntegrityChecks(ctx)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "CHECK\tCOUNT\tSTATUS")
+
+	latest := migrator.Latest()
+	failed := !migrate.Compatible(version, latest, dirty)
+
+	fmt.Fprintf(w, "schema version %d of %d (dirty: %t)\t-\t%s\n",
+		version, latest, dirty, status(failed))
+
+	if version > latest {
+		fmt.Fprintln(w, "schema is ahead of this build\t-\tinfo")
+	}
+
+	for _, r := range results {
+		fmt.Fprintf(w, "%s\t%d\t%s\n", r.Name, r.Count, status(r.Count > 0))
+		failed = failed || r.Count > 0
+	}
+
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	if failed {
+		return errIntegrity
+	}
+
+	return nil
+}
+
+func status(failed bool) string {
+	if failed {
+		return "FAIL"
+	}
+
+	return "ok"
+}
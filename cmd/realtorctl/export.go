@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/google/uuid"
+
+	"github.com/emma769/a-realtor/internal/entity"
+	"github.com/emma769/a-realtor/internal/export"
+)
+
+func (a *app) export(ctx context.Context, args []string) error {
+	if len(args) == 0 {
+		return errUsage
+	}
+
+	fs := flag.NewFlagSet("export", flag.ContinueOnError)
+	format := fs.String("format", "xlsx", "xlsx or csv")
+	out := fs.String("out", "", "output file, stdout when empty")
+
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+
+	if *format != "xlsx" && *format != "csv" {
+		return fmt.Errorf("invalid format %q", *format)
+	}
+
+	var (
+		table *export.Table
+		err   error
+	)
+
+	switch args[0] {
+	case "landlords":
+		table, err = a.landlordTable(ctx)
+	case "tenants":
+		table, err = a.tenantTable(ctx)
+	default:
+		return errUsage
+	}
+
+	if err != nil {
+		return err
+	}
+
+	var w io.Writer = os.Stdout
+
+	if *out != "" {
+		f, err := os.Create(*out)
+		if err != nil {
+			return err
+		}
+
+		defer f.Close()
+		w = f
+	}
+
+	if *format == "csv" {
+		err = table.WriteCSV(w)
+	} else {
+		err = table.WriteXlsx(w)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if *out != "" {
+		fmt.Fprintf(os.Stderr, "exported %d %s to %s\n", len(table.Rows), args[0], *out)
+	}
+
+	return nil
+}
+
+func (a *app) landlordTable(ctx context.Context) (*export.Table, error) {
+	landlords, err := a.store.GetAllLandlords(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return export.Landlords(landlords), nil
+}
+
+func (a *app) tenantTable(ctx context.Context) (*export.Table, error) {
+	tenants, err := a.store.FindAllTenants(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	cache := map[uuid.UUID]*entity.Landlord{}
+
+	findLandlord := func(id uuid.UUID) *entity.Landlord {
+		if landlord, ok := cache[id]; ok {
+			return landlord
+		}
+
+		landlord, _ := a.store.FindLandlord(ctx, id)
+		cache[id] = landlord
+
+		return landlord
+	}
+
+	return export.Tenants(tenants, findLandlord), nil
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/emma769/a-realtor/internal/config"
+	"github.com/emma769/a-realtor/internal/passhash"
+	"github.com/emma769/a-realtor/internal/repository/psql"
+)
+
+const usage = `usage: realtorctl <command> [flags]
+
+commands:
+  user create -email E -name N [-role agent|manager|admin] [-password P]
+  admin create -email E -name N [-password P]
+  user reset-password -email E [-password P]
+  session list -email E
+  session revoke -email E [-id N]
+  export landlords|tenants [-format xlsx|csv] [-out FILE]
+  check
+`
+
+var errUsage = errors.New(usage)
+
+type app struct {
+	cfg    *config.Config
+	store  *psql.Repository
+	hasher passhash.Hasher
+	logger *slog.Logger
+}
+
+func main() {
+	if err := run(os.Args[1:]); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func run(args []string) error {
+	if len(args) == 0 {
+		return errUsage
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGTERM, syscall.SIGINT)
+	defer cancel()
+
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{}))
+
+	cfg, err := config.Load()
+	if err != nil {
+		return err
+	}
+
+	store, err := psql.New(ctx, cfg.PostgresUri, logger, &psql.RepositoryOptions{
+		MaxOpenConns: 2,
+		MaxIdleConns: 2,
+	})
+	if err != nil {
+		return err
+	}
+
+	defer store.Close()
+
+	hasher, err := passhash.New(cfg)
+	if err != nil {
+		return err
+	}
+
+	a := &app{cfg, store, hasher, logger}
+
+	switch args[0] {
+	case "user":
+		return a.user(ctx, args[1:])
+	case "admin":
+		if len(args) < 2 || args[1] != "create" {
+			return errUsage
+		}
+		return a.createUser(ctx, args[2:], "admin")
+	case "session":
+		return a.session(ctx, args[1:])
+	case "export":
+		return a.export(ctx, args[1:])
+	case "check":
+		return a.check(ctx)
+	}
+
+	return errUsage
+}
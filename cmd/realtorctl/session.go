@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/emma769/a-realtor/internal/repository"
+)
+
+func (a *app) session(ctx context.Context, args []string) error {
+	if len(args) == 0 {
+		return errUsage
+	}
+
+	fs := flag.NewFlagSet("session", flag.ContinueOnError)
+	email := fs.String("email", "", "email address")
+	id := fs.Int64("id", 0, "session id, all sessions when zero")
+
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+
+	user, err := a.findUser(ctx, *email)
+	if err != nil {
+		return err
+	}
+
+	switch args[0] {
+	case "list":
+		sessions, err := a.store.FindUserSessions(ctx, user.UserID)
+		if err != nil {
+			return err
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "ID\tCREATED\tEXPIRES")
+
+		for _, s := range sessions {
+			fmt.Fprintf(
+				w,
+				"%d\t%s\t%s\n",
+				s.SessionID,
+				s.CreatedAt.Format(time.RFC3339),
+				s.ValidTill.Format(time.RFC3339),
+			)
+		}
+
+		return w.Flush()
+	case "revoke":
+		if *id == 0 {
+			if err := a.store.DeleteUserSessions(ctx, user.UserID); err != nil {
+				return err
+			}
+
+			fmt.Printf("revoked all sessions for %s\n", user.Email)
+			return nil
+		}
+
+		err := a.store.DeleteSessionByID(ctx, user.UserID, *id)
+
+		if err != nil && errors.Is(err, repository.ErrNotFound) {
+			return fmt.Errorf("no session %d for %s", *id, user.Email)
+		}
+
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("revoked session %d for %s\n", *id, user.Email)
+		return nil
+	}
+
+	return errUsage
+}
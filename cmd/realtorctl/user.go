@@ -0,0 +1,174 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/emma769/a-realtor/internal/entity"
+	funclib "github.com/emma769/a-realtor/internal/lib/func"
+	"github.com/emma769/a-realtor/internal/repository"
+	"github.com/emma769/a-realtor/internal/token"
+)
+
+type userParam struct {
+	name,
+	email string
+	password []byte
+}
+
+func (param userParam) Name() string {
+	return param.name
+}
+
+func (param userParam) Email() string {
+	return param.email
+}
+
+func (param userParam) Password() []byte {
+	return param.password
+}
+
+func (a *app) user(ctx context.Context, args []string) error {
+	if len(args) == 0 {
+		return errUsage
+	}
+
+	switch args[0] {
+	case "create":
+		return a.createUser(ctx, args[1:], string(entity.RoleAgent))
+	case "reset-password":
+		return a.resetPassword(ctx, args[1:])
+	}
+
+	return errUsage
+}
+
+func password(given string) (string, bool, error) {
+	if given != "" {
+		return given, false, nil
+	}
+
+	opaque, err := token.NewOpaque()
+	if err != nil {
+		return "", false, err
+	}
+
+	return opaque.Plain[:20], true, nil
+}
+
+func (a *app) createUser(ctx context.Context, args []string, defaultRole string) error {
+	fs := flag.NewFlagSet("create", flag.ContinueOnError)
+	email := fs.String("email", "", "email address")
+	name := fs.String("name", "", "display name")
+	role := fs.String("role", defaultRole, "agent, manager or admin")
+	plain := fs.String("password", "", "password, generated when empty")
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	*email = strings.TrimSpace(*email)
+
+	if !funclib.ValidEmail(*email) || strings.TrimSpace(*name) == "" {
+		return errors.New("a valid -email and a -name are required")
+	}
+
+	if !entity.Role(*role).Valid() {
+		return fmt.Errorf("invalid role %q", *role)
+	}
+
+	pw, generated, err := password(*plain)
+	if err != nil {
+		return err
+	}
+
+	if len(pw) < 8 {
+		return errors.New("password must be at least 8 characters")
+	}
+
+	hash, err := a.hasher.Hash(pw)
+	if err != nil {
+		return err
+	}
+
+	user, err := a.store.CreateVerifiedUser(ctx, userParam{
+		name:     strings.TrimSpace(*name),
+		email:    *email,
+		password: hash,
+	}, entity.Role(*role))
+
+	if err != nil && errors.Is(err, repository.ErrDuplicateKey) {
+		return fmt.Errorf("a user with email %s already exists", *email)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("created %s %s (%s)\n", *role, user.Email, user.UserID)
+
+	if generated {
+		fmt.Fprintf(os.Stderr, "generated password: %s\n", pw)
+	}
+
+	return nil
+}
+
+func (a *app) resetPassword(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("reset-password", flag.ContinueOnError)
+	email := fs.String("email", "", "email address")
+	plain := fs.String("password", "", "new password, generated when empty")
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	user, err := a.findUser(ctx, *email)
+	if err != nil {
+		return err
+	}
+
+	pw, generated, err := password(*plain)
+	if err != nil {
+		return err
+	}
+
+	if len(pw) < 8 {
+		return errors.New("password must be at least 8 characters")
+	}
+
+	hash, err := a.hasher.Hash(pw)
+	if err != nil {
+		return err
+	}
+
+	if err := a.store.ChangePassword(ctx, user.UserID, hash); err != nil {
+		return err
+	}
+
+	fmt.Printf("password reset for %s, all sessions revoked\n", user.Email)
+
+	if generated {
+		fmt.Fprintf(os.Stderr, "generated password: %s\n", pw)
+	}
+
+	return nil
+}
+
+func (a *app) findUser(ctx context.Context, email string) (*entity.User, error) {
+	if email == "" {
+		return nil, errors.New("-email is required")
+	}
+
+	user, err := a.store.FindUserByEmail(ctx, strings.TrimSpace(email))
+
+	if err != nil && errors.Is(err, repository.ErrNotFound) {
+		return nil, fmt.Errorf("no user with email %s", email)
+	}
+
+	return user, err
+}
@@ -2,24 +2,41 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"log/slog"
 	"net/http"
 	"os"
 	"os/signal"
+	"sync"
 	"syscall"
 	"time"
 
 	"github.com/go-chi/chi/v5"
 
 	"github.com/emma769/a-realtor/internal/config"
+	"github.com/emma769/a-realtor/internal/ctrl/admin"
+	"github.com/emma769/a-realtor/internal/ctrl/apikey"
+	"github.com/emma769/a-realtor/internal/ctrl/assignment"
+	"github.com/emma769/a-realtor/internal/ctrl/audit"
+	"github.com/emma769/a-realtor/internal/ctrl/health"
 	"github.com/emma769/a-realtor/internal/ctrl/landlord"
 	"github.com/emma769/a-realtor/internal/ctrl/tenant"
 	"github.com/emma769/a-realtor/internal/ctrl/user"
+	handlerlib "github.com/emma769/a-realtor/internal/lib/handler"
+	"github.com/emma769/a-realtor/internal/mailer"
+	"github.com/emma769/a-realtor/internal/metrics"
 	"github.com/emma769/a-realtor/internal/middleware"
+	"github.com/emma769/a-realtor/internal/migrate"
+	"github.com/emma769/a-realtor/internal/oidc"
+	"github.com/emma769/a-realtor/internal/openapi"
+	"github.com/emma769/a-realtor/internal/passhash"
 	"github.com/emma769/a-realtor/internal/repository/psql"
 	"github.com/emma769/a-realtor/internal/token"
+	"github.com/emma769/a-realtor/internal/tracing"
+	"github.com/emma769/a-realtor/internal/worker"
+	"github.com/emma769/a-realtor/migrations"
 )
 
 func main() {
@@ -32,46 +49,210 @@ func run() error {
 	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGTERM, syscall.SIGINT)
 	defer cancel()
 
-	logger := slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{}))
+	logger := slog.New(tracing.NewLogHandler(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{})))
 
 	cfg, err := config.Load()
 	if err != nil {
 		return err
 	}
 
-	store, err := psql.New(ctx, cfg.PostgresUri, logger, &psql.RepositoryOptions{})
+	// Query metrics are bound after the migrate subcommand has returned, so a one-off migration
+	// starts no telemetry.
+	var observeQuery psql.QueryObserver
+
+	store, err := psql.New(ctx, cfg.PostgresUri, logger, &psql.RepositoryOptions{
+		QueryObserver: func(name string, duration time.Duration, err error) {
+			if observeQuery != nil {
+				observeQuery(name, duration, err)
+			}
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	migrator, err := migrate.New(store.DB(), migrations.FS, logger)
+	if err != nil {
+		return err
+	}
+
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		defer store.Close()
+		return runMigrate(ctx, migrator, os.Args[2:])
+	}
+
+	shutdownTracing, err := tracing.Setup(ctx, cfg)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		if err := shutdownTracing(ctx); err != nil {
+			logger.LogAttrs(ctx, slog.LevelError, "could not flush traces", slog.Attr{
+				Key:   "detail",
+				Value: slog.StringValue(err.Error()),
+			})
+		}
+	}()
+
+	metrics := metrics.New()
+	observeQuery = metrics.ObserveQuery
+
+	metrics.RegisterDB(store.DB())
+	metrics.RegisterBusiness(store, 5*time.Second)
+
+	if cfg.AutoMigrate {
+		if err := migrator.Up(ctx); err != nil && !errors.Is(err, migrate.ErrNoChange) {
+			return err
+		}
+	}
+
+	mgr, err := token.NewMgr(cfg, store)
 	if err != nil {
 		return err
 	}
 
-	mgr := token.NewMgr(cfg, store)
+	hasher, err := passhash.New(cfg)
+	if err != nil {
+		return err
+	}
+
+	mail, err := mailer.New(cfg, logger)
+	if err != nil {
+		return err
+	}
+
+	cleanup := worker.NewCleanup(store, cfg, logger)
+
+	var wg sync.WaitGroup
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		cleanup.Run(ctx)
+	}()
 
 	router := chi.NewRouter()
 
-	router.Use(middleware.RecoverWithOptions(&middleware.RecoverOptions{
+	realIP, err := middleware.RealIP(cfg.TrustedProxies)
+	if err != nil {
+		return err
+	}
+
+	router.Use(realIP)
+	router.Use(middleware.Tracing)
+	router.Use(middleware.Metrics(metrics))
+	router.Use(middleware.RequestID)
+
+	router.Use(middleware.LoggerWithOptions(&middleware.LoggerOptions{
 		Logger: logger,
 	}))
 
-	router.Use(middleware.LoggerWithOptions(&middleware.LoggerOptions{
+	router.Use(middleware.RecoverWithOptions(&middleware.RecoverOptions{
 		Logger: logger,
 	}))
 
 	router.Use(middleware.EnableCorsWithOptions(&middleware.CorsOptions{
 		Origins: []string{cfg.TrustedOrigin},
-		Headers: []string{"Content-Type", "Accept", "Authorization"},
-		Methods: []string{"GET", "POST", "OPTIONS", "PUT", "DELETE"},
+		Headers: []string{
+			"Content-Type",
+			"Accept",
+			"Authorization",
+			"X-API-Key",
+			"X-CSRF-Token",
+			"X-Request-ID",
+			"Idempotency-Key",
+			"If-Match",
+			"If-None-Match",
+		},
+		Methods: []string{"GET", "POST", "OPTIONS", "PUT", "PATCH", "DELETE"},
+		ExposedHeaders: []string{
+			"X-Request-ID",
+			"Retry-After",
+			"RateLimit-Limit",
+			"RateLimit-Remaining",
+			"RateLimit-Reset",
+			"RateLimit-Policy",
+			"Idempotent-Replayed",
+			"ETag",
+		},
+		AllowCredentials: cfg.AuthCookieMode,
 	}))
 
-	router.Use(middleware.Authenticate(middleware.NewAuthService(mgr, store)))
+	doc := openapi.New("A-Realtor API", "1.0.0")
+	meta := doc.With(openapi.Public, openapi.Tag("meta"))
+
+	if cfg.MetricsEnabled {
+		if err := mountMetrics(router, meta, cfg, metrics.Handler()); err != nil {
+			return err
+		}
+	}
+
+	var limitByIP, limitByClient func(http.Handler) http.Handler
+
+	if cfg.RateLimitEnabled {
+		limitByIP, limitByClient, err = newRateLimiters(cfg, store, logger)
+		if err != nil {
+			return err
+		}
+	}
+
+	api := router.With(chain(
+		limitByIP,
+		middleware.Authenticate(middleware.NewAuthService(mgr, store)),
+		middleware.Audit,
+		limitByClient,
+		middleware.IdempotencyWithOptions(&middleware.IdempotencyOptions{
+			Store:  store,
+			TTL:    cfg.IdempotencyTTL,
+			Lease:  cfg.IdempotencyLease,
+			Logger: logger,
+		}),
+	)...)
 
-	user := user.NewCtrl(store, cfg, mgr)
-	router.Route("/api/auth", user.Routes)
+	api.Get("/api/openapi.json", doc.Handler())
+	api.Get("/api/docs", openapi.UI("/api/openapi.json"))
 
-	landlord := landlord.New(store, logger)
-	router.Route("/api/landlords", landlord.Routes)
+	meta.Get("/api/openapi.json", openapi.Operation{
+		Summary:  "This OpenAPI document",
+		Response: map[string]any{},
+	})
+	meta.Get("/api/docs", openapi.Operation{
+		Summary:     "Interactive API documentation",
+		ContentType: "text/html",
+	})
 
-	tenant := tenant.New(store, logger)
-	router.Route("/api/tenants", tenant.Routes)
+	jwks := func(w http.ResponseWriter, r *http.Request) error {
+		w.Header().Set("Cache-Control", "public, max-age=300")
+		return handlerlib.WriteJson(w, 200, mgr.JWKS())
+	}
+
+	api.Get("/.well-known/jwks.json", handlerlib.Wrap(jwks))
+	meta.Get("/.well-known/jwks.json", openapi.Operation{
+		Summary:  "Public keys for verifying access tokens",
+		Response: token.JWKS{},
+	})
+
+	health := health.New(store, migrator)
+	health.Register("cleanup", cleanup)
+
+	mountAPI(api, doc, ctrls{
+		health:     health,
+		user:       user.NewCtrl(store, cfg, mgr, hasher, oidc.New(cfg), mail, logger),
+		apikey:     apikey.New(store),
+		admin:      admin.New(store, cfg, mgr, mail, logger),
+		audit:      audit.New(store),
+		assignment: assignment.New(store),
+		landlord:   landlord.New(store, logger),
+		tenant:     tenant.New(store, logger),
+	}, cfg, middleware.Visibility(store, cfg.VisibilityMode))
+
+	if err := openapi.Verify(router, doc); err != nil {
+		return err
+	}
 
 	server := &http.Server{
 		Addr:         fmt.Sprintf(":%d", cfg.Port),
@@ -98,6 +279,23 @@ func run() error {
 	case err := <-errch:
 		return err
 	case <-ctx.Done():
+		health.Drain()
+
+		ctx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownDrain+10*time.Second)
+		defer cancel()
+
+		logger.LogAttrs(ctx, slog.LevelInfo, "server is draining", slog.Attr{
+			Key:   "delay",
+			Value: slog.DurationValue(cfg.ShutdownDrain),
+		})
+
+		time.Sleep(cfg.ShutdownDrain)
+
+		logger.LogAttrs(ctx, slog.LevelInfo, "server is shutting down")
+		err := server.Shutdown(ctx)
+
+		wg.Wait()
+
 		if err := store.Close(); err != nil {
 			logger.LogAttrs(ctx, slog.LevelError, "could not close db", slog.Attr{
 				Key:   "detail",
@@ -105,9 +303,6 @@ func run() error {
 			})
 		}
 
-		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-		defer cancel()
-		logger.LogAttrs(ctx, slog.LevelInfo, "server is shutting down")
-		return server.Shutdown(ctx)
+		return err
 	}
 }
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"text/tabwriter"
+
+	"github.com/emma769/a-realtor/internal/migrate"
+)
+
+var errMigrateUsage = errors.New("usage: myapp migrate up|down [N]|status|to N|force N")
+
+func runMigrate(ctx context.Context, m *migrate.Migrator, args []string) error {
+	if len(args) == 0 {
+		return errMigrateUsage
+	}
+
+	var err error
+
+	switch args[0] {
+	case "up":
+		err = m.Up(ctx)
+	case "down":
+		steps := 1
+
+		if len(args) > 1 {
+			if steps, err = strconv.Atoi(args[1]); err != nil || steps < 1 {
+				return errMigrateUsage
+			}
+		}
+
+		err = m.Down(ctx, steps)
+	case "to", "force":
+		if len(args) < 2 {
+			return errMigrateUsage
+		}
+
+		v, perr := strconv.ParseUint(args[1], 10, 64)
+		if perr != nil {
+			return errMigrateUsage
+		}
+
+		if args[0] == "to" {
+			err = m.To(ctx, uint(v))
+		} else {
+			err = m.Force(ctx, uint(v))
+		}
+	case "status":
+		return printStatus(ctx, m)
+	default:
+		return errMigrateUsage
+	}
+
+	if err != nil && errors.Is(err, migrate.ErrNoChange) {
+		fmt.Println("no change")
+		return nil
+	}
+
+	return err
+}
+
+func printStatus(ctx context.Context, m *migrate.Migrator) error {
+	status, err := m.Status(ctx)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("version: %d (dirty: %t, latest: %d)\n\n", status.Version, status.Dirty, m.Latest())
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "VERSION\tNAME\tSTATE")
+
+	for _, mg := range status.Migrations {
+		state := "pending"
+
+		if mg.Applied {
+			state = "applied"
+		}
+
+		fmt.Fprintf(w, "%06d\t%s\t%s\n", mg.Version, mg.Name, state)
+	}
+
+	return w.Flush()
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/emma769/a-realtor/internal/config"
+	"github.com/emma769/a-realtor/internal/middleware"
+	"github.com/emma769/a-realtor/internal/ratelimit"
+	"github.com/emma769/a-realtor/internal/repository/psql"
+)
+
+// newRateLimiters returns an IP-keyed limiter to mount before authentication, so requests with
+// bad credentials are still charged, and a per-client limiter to mount after it.
+func newRateLimiters(
+	cfg *config.Config,
+	store *psql.Repository,
+	logger *slog.Logger,
+) (func(http.Handler) http.Handler, func(http.Handler) http.Handler, error) {
+	limiter, err := ratelimit.New(cfg, store)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	auth, err := ratelimit.ParseLimit(cfg.RateLimitAuth)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	ip, err := ratelimit.ParseLimit(cfg.RateLimitIP)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	export, err := ratelimit.ParseLimit(cfg.RateLimitExport)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	crud, err := ratelimit.ParseLimit(cfg.RateLimitDefault)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	byIP := middleware.RateLimitWithOptions(&middleware.RateLimitOptions{
+		Limiter: limiter,
+		Policies: []middleware.RateLimitPolicy{
+			{
+				Name:  "auth",
+				Limit: auth,
+				Match: middleware.MatchPrefix("/api/auth/"),
+				Key:   middleware.KeyByIP,
+			},
+			{
+				Name:  "ip",
+				Limit: ip,
+				Match: middleware.MatchPrefix("/api/"),
+				Key:   middleware.KeyByIP,
+			},
+		},
+		Logger: logger,
+	})
+
+	byClient := middleware.RateLimitWithOptions(&middleware.RateLimitOptions{
+		Limiter: limiter,
+		Policies: []middleware.RateLimitPolicy{
+			{
+				Name:  "export",
+				Limit: export,
+				Match: middleware.MatchSuffix("/xlsx"),
+				Key:   middleware.KeyByClient,
+			},
+			{
+				Name:  "default",
+				Limit: crud,
+				Match: middleware.MatchPrefix("/api/"),
+				Key:   middleware.KeyByClient,
+			},
+		},
+		Logger: logger,
+	})
+
+	return byIP, byClient, nil
+}
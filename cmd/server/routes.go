@@ -0,0 +1,109 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/emma769/a-realtor/internal/config"
+	"github.com/emma769/a-realtor/internal/ctrl/admin"
+	"github.com/emma769/a-realtor/internal/ctrl/apikey"
+	"github.com/emma769/a-realtor/internal/ctrl/assignment"
+	"github.com/emma769/a-realtor/internal/ctrl/audit"
+	"github.com/emma769/a-realtor/internal/ctrl/health"
+	"github.com/emma769/a-realtor/internal/ctrl/landlord"
+	"github.com/emma769/a-realtor/internal/ctrl/tenant"
+	"github.com/emma769/a-realtor/internal/ctrl/user"
+	"github.com/emma769/a-realtor/internal/middleware"
+	"github.com/emma769/a-realtor/internal/openapi"
+)
+
+// mountMetrics puts /metrics on the bare router, ahead of Authenticate, which would otherwise
+// reject the scraper's bearer token as a malformed access token before RestrictMetrics sees it.
+func mountMetrics(
+	router chi.Router,
+	meta *openapi.Group,
+	cfg *config.Config,
+	metrics http.Handler,
+) error {
+	restrict, err := middleware.RestrictMetrics(cfg.MetricsToken, cfg.MetricsNetworks)
+	if err != nil {
+		return err
+	}
+
+	router.With(restrict).Get("/metrics", metrics.ServeHTTP)
+	meta.Get("/metrics", openapi.Operation{
+		Summary:     "Prometheus metrics",
+		Description: "Restricted by METRICS_TOKEN or METRICS_NETWORKS.",
+		ContentType: "text/plain",
+	})
+
+	return nil
+}
+
+// chain drops the middlewares that are turned off by config.
+func chain(mws ...func(http.Handler) http.Handler) []func(http.Handler) http.Handler {
+	out := make([]func(http.Handler) http.Handler, 0, len(mws))
+
+	for _, mw := range mws {
+		if mw != nil {
+			out = append(out, mw)
+		}
+	}
+
+	return out
+}
+
+type ctrls struct {
+	health     *health.Ctrl
+	user       *user.Ctrl
+	apikey     *apikey.Ctrl
+	admin      *admin.Ctrl
+	audit      *audit.Ctrl
+	assignment *assignment.Ctrl
+	landlord   *landlord.Ctrl
+	tenant     *tenant.Ctrl
+}
+
+// mountAPI registers every controller on the router and in the spec side by side, so the two
+// can be checked against each other with openapi.Verify.
+func mountAPI(
+	router chi.Router,
+	doc *openapi.Doc,
+	c ctrls,
+	cfg *config.Config,
+	visibility func(http.Handler) http.Handler,
+) {
+	c.health.Routes(router)
+	c.health.Docs(doc.Group)
+
+	router.Route("/api/auth", c.user.Routes)
+	doc.Route("/api/auth", c.user.Docs)
+
+	router.Route("/api/keys", c.apikey.Routes)
+	doc.Route("/api/keys", c.apikey.Docs)
+
+	router.Group(func(r chi.Router) {
+		if cfg.RequireEmailVerification {
+			r.Use(middleware.RequireVerified)
+		}
+
+		r.Use(middleware.RequireTwoFactor)
+		r.Use(visibility)
+
+		r.Route("/api/admin", c.admin.Routes)
+		doc.Route("/api/admin", c.admin.Docs)
+
+		r.Route("/api/audit", c.audit.Routes)
+		doc.Route("/api/audit", c.audit.Docs)
+
+		r.Route("/api/assignments", c.assignment.Routes)
+		doc.Route("/api/assignments", c.assignment.Docs)
+
+		r.Route("/api/landlords", c.landlord.Routes)
+		doc.Route("/api/landlords", c.landlord.Docs)
+
+		r.Route("/api/tenants", c.tenant.Routes)
+		doc.Route("/api/tenants", c.tenant.Docs)
+	})
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/emma769/a-realtor/internal/config"
+	"github.com/emma769/a-realtor/internal/ctrl/admin"
+	"github.com/emma769/a-realtor/internal/ctrl/apikey"
+	"github.com/emma769/a-realtor/internal/ctrl/assignment"
+	"github.com/emma769/a-realtor/internal/ctrl/audit"
+	"github.com/emma769/a-realtor/internal/ctrl/health"
+	"github.com/emma769/a-realtor/internal/ctrl/landlord"
+	"github.com/emma769/a-realtor/internal/ctrl/tenant"
+	"github.com/emma769/a-realtor/internal/ctrl/user"
+	"github.com/emma769/a-realtor/internal/middleware"
+	"github.com/emma769/a-realtor/internal/openapi"
+)
+
+// newAPI mounts the real controllers in the same order as the server; none of them touch their
+// dependencies until a request is served, so they can be left nil.
+func newAPI(cfg *config.Config) (*chi.Mux, *openapi.Doc) {
+	router := chi.NewRouter()
+	doc := openapi.New("test", "0.0.0")
+
+	metrics := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("up 1\n"))
+	})
+
+	if err := mountMetrics(router, doc.Group, cfg, metrics); err != nil {
+		panic(err)
+	}
+
+	api := router.With(
+		middleware.Authenticate(middleware.NewAuthService(nil, nil)),
+		middleware.Audit,
+	)
+
+	mountAPI(api, doc, ctrls{
+		health:     health.New(nil, nil),
+		user:       user.NewCtrl(nil, cfg, nil, nil, nil, nil, nil),
+		apikey:     apikey.New(nil),
+		admin:      admin.New(nil, cfg, nil, nil, nil),
+		audit:      audit.New(nil),
+		assignment: assignment.New(nil),
+		landlord:   landlord.New(nil, nil),
+		tenant:     tenant.New(nil, nil),
+	}, cfg, func(next http.Handler) http.Handler { return next })
+
+	return router, doc
+}
+
+func TestRoutesMatchSpec(t *testing.T) {
+	for name, cfg := range map[string]*config.Config{
+		"default": {},
+		"sso":     {OIDCIssuer: "https://idp.test", RequireEmailVerification: true},
+	} {
+		t.Run(name, func(t *testing.T) {
+			router, doc := newAPI(cfg)
+
+			if err := openapi.Verify(router, doc); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
+
+func TestVerifyReportsUndocumentedRoute(t *testing.T) {
+	router, doc := newAPI(&config.Config{})
+	router.Post("/api/keys/{id}/rotate", func(http.ResponseWriter, *http.Request) {})
+
+	if err := openapi.Verify(router, doc); !errors.Is(err, openapi.ErrUndocumentedRoute) {
+		t.Fatalf("err = %v, want ErrUndocumentedRoute", err)
+	}
+}
+
+func TestVerifyReportsStaleOperation(t *testing.T) {
+	router, doc := newAPI(&config.Config{})
+	doc.Route("/api/keys", func(g *openapi.Group) {
+		g.Post("/{id}/rotate", openapi.Operation{Summary: "Rotate an API key"})
+	})
+
+	if err := openapi.Verify(router, doc); !errors.Is(err, openapi.ErrStaleOperation) {
+		t.Fatalf("err = %v, want ErrStaleOperation", err)
+	}
+}
+
+func TestMetricsTokenPassesAuthentication(t *testing.T) {
+	router, _ := newAPI(&config.Config{MetricsToken: "scrape-secret"})
+
+	for _, tc := range []struct {
+		path, auth string
+		want       int
+	}{
+		{"/metrics", "Bearer scrape-secret", http.StatusOK},
+		{"/metrics", "Bearer wrong", http.StatusForbidden},
+		{"/metrics", "", http.StatusForbidden},
+		{"/api/auth/me", "Basic c2NyYXBlOnNlY3JldA==", http.StatusUnauthorized},
+	} {
+		req := httptest.NewRequest(http.MethodGet, tc.path, nil)
+
+		if tc.auth != "" {
+			req.Header.Set("Authorization", tc.auth)
+		}
+
+		res := httptest.NewRecorder()
+		router.ServeHTTP(res, req)
+
+		if res.Code != tc.want {
+			t.Errorf("%s with %q = %d, want %d", tc.path, tc.auth, res.Code, tc.want)
+		}
+	}
+}
@@ -12,11 +12,93 @@ type Config struct {
 	WriteTimeout    time.Duration `env:"WRITE_TIMEOUT,required"`
 	IdleTimeout     time.Duration `env:"IDLE_TIMEOUT,required"`
 	PostgresUri     string        `env:"POSTGRES_URI,required"`
-	JwtAccessSecret string        `env:"JWT_ACCESS_SECRET,required"`
+	JwtAccessSecret string        `env:"JWT_ACCESS_SECRET"`
 	JwtAccessExpire time.Duration `env:"JWT_ACCESS_EXPIRE,required"`
 	SessionExpire   time.Duration `env:"SESSION_EXPIRE,required"`
 	GoEnv           string        `env:"GO_ENV,required"`
 	TrustedOrigin   string        `env:"TRUSTED_ORIGIN,required"`
+	AppUrl          string        `env:"APP_URL"`
+	AutoMigrate     bool          `env:"AUTO_MIGRATE" envDefault:"false"`
+	ShutdownDrain   time.Duration `env:"SHUTDOWN_DRAIN_DELAY" envDefault:"5s"`
+
+	MailerDriver string `env:"MAILER_DRIVER" envDefault:"log"`
+	MailFrom     string `env:"MAIL_FROM" envDefault:"no-reply@arealtor.local"`
+	SmtpHost     string `env:"SMTP_HOST" envDefault:"localhost"`
+	SmtpPort     int    `env:"SMTP_PORT" envDefault:"1025"`
+	SmtpUsername string `env:"SMTP_USERNAME"`
+	SmtpPassword string `env:"SMTP_PASSWORD"`
+
+	PasswordResetExpire      time.Duration `env:"PASSWORD_RESET_EXPIRE" envDefault:"30m"`
+	EmailVerificationExpire  time.Duration `env:"EMAIL_VERIFICATION_EXPIRE" envDefault:"24h"`
+	RequireEmailVerification bool          `env:"REQUIRE_EMAIL_VERIFICATION" envDefault:"false"`
+	InviteExpire             time.Duration `env:"INVITE_EXPIRE" envDefault:"72h"`
+	DisableRegistration      bool          `env:"DISABLE_REGISTRATION" envDefault:"false"`
+
+	PasswordHasher    string `env:"PASSWORD_HASHER" envDefault:"argon2id"`
+	Argon2Memory      uint32 `env:"ARGON2_MEMORY" envDefault:"65536"`
+	Argon2Iterations  uint32 `env:"ARGON2_ITERATIONS" envDefault:"3"`
+	Argon2Parallelism uint8  `env:"ARGON2_PARALLELISM" envDefault:"2"`
+	Argon2SaltLength  uint32 `env:"ARGON2_SALT_LENGTH" envDefault:"16"`
+	Argon2KeyLength   uint32 `env:"ARGON2_KEY_LENGTH" envDefault:"32"`
+	BcryptCost        int    `env:"BCRYPT_COST" envDefault:"10"`
+
+	LoginMaxAttempts     int           `env:"LOGIN_MAX_ATTEMPTS" envDefault:"5"`
+	LoginIpMaxAttempts   int           `env:"LOGIN_IP_MAX_ATTEMPTS" envDefault:"50"`
+	LoginBackoffBase     time.Duration `env:"LOGIN_BACKOFF_BASE" envDefault:"1s"`
+	LoginFailureWindow   time.Duration `env:"LOGIN_FAILURE_WINDOW" envDefault:"15m"`
+	LoginLockoutDuration time.Duration `env:"LOGIN_LOCKOUT_DURATION" envDefault:"15m"`
+
+	JwtIssuer         string   `env:"JWT_ISSUER" envDefault:"a-realtor"`
+	JwtAudience       string   `env:"JWT_AUDIENCE" envDefault:"a-realtor"`
+	JwtPrivateKeyFile string   `env:"JWT_PRIVATE_KEY_FILE"`
+	JwtPublicKeyFiles []string `env:"JWT_PUBLIC_KEY_FILES"`
+	JwtKeyDir         string   `env:"JWT_KEY_DIR"`
+	JwtSigningKid     string   `env:"JWT_SIGNING_KID"`
+
+	AuthCookieMode   bool   `env:"AUTH_COOKIE_MODE" envDefault:"false"`
+	AuthCookieDomain string `env:"AUTH_COOKIE_DOMAIN"`
+	AuthCookieSecure bool   `env:"AUTH_COOKIE_SECURE" envDefault:"true"`
+	AuthCookieSite   string `env:"AUTH_COOKIE_SAMESITE" envDefault:"strict"`
+
+	OIDCIssuer        string            `env:"OIDC_ISSUER"`
+	OIDCClientID      string            `env:"OIDC_CLIENT_ID"`
+	OIDCClientSecret  string            `env:"OIDC_CLIENT_SECRET"`
+	OIDCRedirectURL   string            `env:"OIDC_REDIRECT_URL"`
+	OIDCScopes        []string          `env:"OIDC_SCOPES" envDefault:"openid,profile,email"`
+	OIDCRoleClaim     string            `env:"OIDC_ROLE_CLAIM" envDefault:"groups"`
+	OIDCRoleMap       map[string]string `env:"OIDC_ROLE_MAP"`
+	OIDCDefaultRole   string            `env:"OIDC_DEFAULT_ROLE" envDefault:"agent"`
+	OIDCAutoProvision bool              `env:"OIDC_AUTO_PROVISION" envDefault:"true"`
+	OIDCStateExpire   time.Duration     `env:"OIDC_STATE_EXPIRE" envDefault:"10m"`
+
+	MetricsEnabled  bool     `env:"METRICS_ENABLED" envDefault:"true"`
+	MetricsToken    string   `env:"METRICS_TOKEN"`
+	MetricsNetworks []string `env:"METRICS_ALLOWED_NETWORKS" envDefault:"127.0.0.0/8,::1/128"`
+
+	TracingEnabled   bool    `env:"TRACING_ENABLED" envDefault:"false"`
+	ServiceName      string  `env:"OTEL_SERVICE_NAME" envDefault:"a-realtor"`
+	OTLPEndpoint     string  `env:"OTLP_ENDPOINT" envDefault:"localhost:4318"`
+	OTLPInsecure     bool    `env:"OTLP_INSECURE" envDefault:"true"`
+	TraceSampleRatio float64 `env:"TRACE_SAMPLE_RATIO" envDefault:"1"`
+
+	VisibilityMode string `env:"VISIBILITY_MODE" envDefault:"open"`
+
+	TrustedProxies   []string `env:"TRUSTED_PROXIES"`
+	RateLimitEnabled bool     `env:"RATE_LIMIT_ENABLED" envDefault:"true"`
+	RateLimitBackend string   `env:"RATE_LIMIT_BACKEND" envDefault:"memory"`
+	RateLimitAuth    string   `env:"RATE_LIMIT_AUTH" envDefault:"20/1m"`
+	RateLimitIP      string   `env:"RATE_LIMIT_IP" envDefault:"1200/1m"`
+	RateLimitExport  string   `env:"RATE_LIMIT_EXPORT" envDefault:"10/1m"`
+	RateLimitDefault string   `env:"RATE_LIMIT_DEFAULT" envDefault:"300/1m"`
+
+	IdempotencyTTL   time.Duration `env:"IDEMPOTENCY_TTL" envDefault:"24h"`
+	IdempotencyLease time.Duration `env:"IDEMPOTENCY_LEASE" envDefault:"1m"`
+
+	CleanupInterval  time.Duration `env:"CLEANUP_INTERVAL" envDefault:"10m"`
+	CleanupBatchSize int           `env:"CLEANUP_BATCH_SIZE" envDefault:"1000"`
+
+	TOTPIssuer         string        `env:"TOTP_ISSUER" envDefault:"A-Realtor"`
+	MFAChallengeExpire time.Duration `env:"MFA_CHALLENGE_EXPIRE" envDefault:"5m"`
 }
 
 func Load() (*Config, error) {
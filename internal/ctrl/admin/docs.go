@@ -0,0 +1,67 @@
+package admin
+
+import (
+	"github.com/emma769/a-realtor/internal/entity"
+	"github.com/emma769/a-realtor/internal/openapi"
+)
+
+func (ctrl Ctrl) Docs(d *openapi.Group) {
+	d = d.With(openapi.Session, openapi.Roles(string(entity.RoleAdmin)))
+
+	d.Get("/users", openapi.Operation{
+		Summary:   "List users",
+		Response:  entity.UserOut{},
+		Paginated: true,
+		Query: []openapi.Param{
+			{Name: "role", Description: "admin, manager or agent"},
+			{Name: "status", Description: "pending, active or deactivated"},
+		},
+	})
+	d.Post("/users/invite", openapi.Operation{
+		Summary:  "Invite a user by email",
+		Request:  entity.InviteIn{},
+		Response: entity.UserOut{},
+		Status:   201,
+	})
+	d.Post("/users/{id}/invite/resend", openapi.Operation{
+		Summary:  "Resend a pending invite",
+		Response: entity.UserOut{},
+		Status:   202,
+	})
+	d.Delete("/users/{id}/invite", openapi.Operation{
+		Summary: "Revoke a pending invite",
+		Status:  204,
+	})
+	d.Post("/users/{id}/deactivate", openapi.Operation{
+		Summary:  "Deactivate a user and end their sessions",
+		Response: entity.UserOut{},
+	})
+	d.Post("/users/{id}/reactivate", openapi.Operation{
+		Summary:  "Reactivate a deactivated user",
+		Response: entity.UserOut{},
+	})
+	d.Post("/users/{id}/unlock", openapi.Operation{
+		Summary: "Clear a login lockout",
+		Status:  204,
+	})
+	d.Put("/users/{id}/manager", openapi.Operation{
+		Summary:  "Set or clear a user's manager",
+		Request:  entity.SetManagerIn{},
+		Response: entity.UserOut{},
+	})
+	d.Get("/lockouts", openapi.Operation{
+		Summary:   "List login lockouts",
+		Response:  entity.Lockout{},
+		Paginated: true,
+		Query:     []openapi.Param{{Name: "key", Description: "throttle key to filter by"}},
+	})
+	d.Get("/roles", openapi.Operation{
+		Summary:  "List role policies",
+		Response: []*entity.RolePolicy{},
+	})
+	d.Put("/roles/{role}", openapi.Operation{
+		Summary:  "Update a role policy",
+		Request:  entity.RolePolicyIn{},
+		Response: entity.RolePolicy{},
+	})
+}
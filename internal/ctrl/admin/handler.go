@@ -0,0 +1,286 @@
+package admin
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+
+	"github.com/emma769/a-realtor/internal/config"
+	"github.com/emma769/a-realtor/internal/entity"
+	handlerlib "github.com/emma769/a-realtor/internal/lib/handler"
+	"github.com/emma769/a-realtor/internal/mailer"
+	"github.com/emma769/a-realtor/internal/middleware"
+	"github.com/emma769/a-realtor/internal/validator"
+)
+
+const timeout = 5 * time.Second
+
+type Ctrl struct {
+	*Service
+}
+
+func New(
+	store storer,
+	cfg *config.Config,
+	tokens tokenIssuer,
+	mailer mailer.Mailer,
+	logger *slog.Logger,
+) *Ctrl {
+	return &Ctrl{
+		Service: &Service{
+			store:   store,
+			timeout: timeout,
+			tokens:  tokens,
+			mailer:  mailer,
+			cfg:     cfg,
+			logger:  logger,
+		},
+	}
+}
+
+func (ctrl Ctrl) Routes(r chi.Router) {
+	r.With(
+		middleware.RequireSession,
+		middleware.RequireRole(entity.RoleAdmin),
+	).Group(func(r chi.Router) {
+		r.Get("/users", ctrl.findUsers())
+		r.Post("/users/invite", ctrl.inviteUser())
+		r.Post("/users/{id}/invite/resend", ctrl.resendInvite())
+		r.Delete("/users/{id}/invite", ctrl.revokeInvite())
+		r.Post("/users/{id}/deactivate", ctrl.deactivateUser())
+		r.Post("/users/{id}/reactivate", ctrl.reactivateUser())
+		r.Post("/users/{id}/unlock", ctrl.unlockUser())
+		r.Put("/users/{id}/manager", ctrl.setUserManager())
+		r.Get("/lockouts", ctrl.findLockouts())
+		r.Get("/roles", ctrl.findRolePolicies())
+		r.Put("/roles/{role}", ctrl.updateRolePolicy())
+	})
+}
+
+func (ctrl *Ctrl) findUsers() http.HandlerFunc {
+	return handlerlib.Wrap(func(w http.ResponseWriter, r *http.Request) error {
+		filterParam := FilterParam{
+			role:   entity.Role(handlerlib.GetQuery(r, "role", "")),
+			status: entity.UserStatus(handlerlib.GetQuery(r, "status", "")),
+		}
+
+		paginator := handlerlib.NewPaginator(
+			handlerlib.GetQueryInt(r, "page", 1),
+			handlerlib.GetQueryInt(r, "page_size", 10),
+		)
+
+		users, err := ctrl.Service.findUsers(r.Context(), filterParam, paginator)
+		if err != nil {
+			return err
+		}
+
+		data := make([]*entity.UserOut, len(users))
+
+		for i := range users {
+			data[i] = entity.NewUserOut(users[i])
+		}
+
+		return handlerlib.WriteJson(w, 200, map[string]any{
+			"data":     data,
+			"metadata": paginator.GetMetadata(),
+		})
+	})
+}
+
+func (ctrl *Ctrl) inviteUser() http.HandlerFunc {
+	return handlerlib.Wrap(func(w http.ResponseWriter, r *http.Request) error {
+		in, err := handlerlib.Bind[entity.InviteIn](w, r)
+		if err != nil {
+			return err
+		}
+
+		v := validator.New()
+
+		if entity.ValidateInviteIn(v, in); !v.Valid() {
+			return handlerlib.ValidationError(v.Err())
+		}
+
+		user, err := ctrl.invite(r.Context(), handlerlib.GetCtxUser(r), in)
+		if err != nil {
+			return err
+		}
+
+		return handlerlib.WriteJson(w, 201, entity.NewUserOut(user))
+	})
+}
+
+func (ctrl *Ctrl) resendInvite() http.HandlerFunc {
+	return handlerlib.Wrap(func(w http.ResponseWriter, r *http.Request) error {
+		id, err := uuid.Parse(chi.URLParam(r, "id"))
+		if err != nil {
+			return ErrInvalidID
+		}
+
+		user, err := ctrl.Service.resendInvite(r.Context(), handlerlib.GetCtxUser(r), id)
+		if err != nil {
+			return err
+		}
+
+		return handlerlib.WriteJson(w, 202, entity.NewUserOut(user))
+	})
+}
+
+func (ctrl *Ctrl) revokeInvite() http.HandlerFunc {
+	return handlerlib.Wrap(func(w http.ResponseWriter, r *http.Request) error {
+		id, err := uuid.Parse(chi.URLParam(r, "id"))
+		if err != nil {
+			return ErrInvalidID
+		}
+
+		err = ctrl.Service.revokeInvite(r.Context(), id)
+		if err != nil {
+			return err
+		}
+
+		return handlerlib.SendStatus(w, 204)
+	})
+}
+
+func (ctrl *Ctrl) deactivateUser() http.HandlerFunc {
+	return handlerlib.Wrap(func(w http.ResponseWriter, r *http.Request) error {
+		id, err := uuid.Parse(chi.URLParam(r, "id"))
+		if err != nil {
+			return ErrInvalidID
+		}
+
+		if id == handlerlib.GetCtxUser(r).UserID {
+			return ErrSelfDeactivate
+		}
+
+		user, err := ctrl.deactivate(r.Context(), id)
+
+		if err != nil && errors.Is(err, ErrNotFound) {
+			return handlerlib.NewError(404, "user.not_found", "active user not found")
+		}
+
+		if err != nil {
+			return err
+		}
+
+		return handlerlib.WriteJson(w, 200, entity.NewUserOut(user))
+	})
+}
+
+func (ctrl *Ctrl) reactivateUser() http.HandlerFunc {
+	return handlerlib.Wrap(func(w http.ResponseWriter, r *http.Request) error {
+		id, err := uuid.Parse(chi.URLParam(r, "id"))
+		if err != nil {
+			return ErrInvalidID
+		}
+
+		user, err := ctrl.reactivate(r.Context(), id)
+
+		if err != nil && errors.Is(err, ErrNotFound) {
+			return handlerlib.NewError(404, "user.not_found", "deactivated user not found")
+		}
+
+		if err != nil {
+			return err
+		}
+
+		return handlerlib.WriteJson(w, 200, entity.NewUserOut(user))
+	})
+}
+
+func (ctrl *Ctrl) setUserManager() http.HandlerFunc {
+	return handlerlib.Wrap(func(w http.ResponseWriter, r *http.Request) error {
+		id, err := uuid.Parse(chi.URLParam(r, "id"))
+		if err != nil {
+			return ErrInvalidID
+		}
+
+		in, err := handlerlib.Bind[entity.SetManagerIn](w, r)
+		if err != nil {
+			return err
+		}
+
+		user, err := ctrl.setManager(r.Context(), id, in)
+
+		if err != nil {
+			return err
+		}
+
+		return handlerlib.WriteJson(w, 200, entity.NewUserOut(user))
+	})
+}
+
+func (ctrl *Ctrl) unlockUser() http.HandlerFunc {
+	return handlerlib.Wrap(func(w http.ResponseWriter, r *http.Request) error {
+		id, err := uuid.Parse(chi.URLParam(r, "id"))
+		if err != nil {
+			return ErrInvalidID
+		}
+
+		err = ctrl.unlock(r.Context(), handlerlib.GetCtxUser(r), id)
+		if err != nil {
+			return err
+		}
+
+		return handlerlib.SendStatus(w, 204)
+	})
+}
+
+func (ctrl *Ctrl) findLockouts() http.HandlerFunc {
+	return handlerlib.Wrap(func(w http.ResponseWriter, r *http.Request) error {
+		paginator := handlerlib.NewPaginator(
+			handlerlib.GetQueryInt(r, "page", 1),
+			handlerlib.GetQueryInt(r, "page_size", 10),
+		)
+
+		lockouts, err := ctrl.Service.findLockouts(
+			r.Context(),
+			handlerlib.GetQuery(r, "key", ""),
+			paginator,
+		)
+		if err != nil {
+			return err
+		}
+
+		return handlerlib.WriteJson(w, 200, map[string]any{
+			"data":     lockouts,
+			"metadata": paginator.GetMetadata(),
+		})
+	})
+}
+
+func (ctrl *Ctrl) findRolePolicies() http.HandlerFunc {
+	return handlerlib.Wrap(func(w http.ResponseWriter, r *http.Request) error {
+		policies, err := ctrl.rolePolicies(r.Context())
+		if err != nil {
+			return err
+		}
+
+		return handlerlib.WriteJson(w, 200, policies)
+	})
+}
+
+func (ctrl *Ctrl) updateRolePolicy() http.HandlerFunc {
+	return handlerlib.Wrap(func(w http.ResponseWriter, r *http.Request) error {
+		role := entity.Role(chi.URLParam(r, "role"))
+
+		if !role.Valid() {
+			return ErrRoleNotFound
+		}
+
+		in, err := handlerlib.Bind[entity.RolePolicyIn](w, r)
+		if err != nil {
+			return err
+		}
+
+		policy, err := ctrl.setRolePolicy(r.Context(), role, in)
+		if err != nil {
+			return err
+		}
+
+		return handlerlib.WriteJson(w, 200, policy)
+	})
+}
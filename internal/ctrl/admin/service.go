@@ -0,0 +1,333 @@
+package admin
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/emma769/a-realtor/internal/config"
+	"github.com/emma769/a-realtor/internal/entity"
+	handlerlib "github.com/emma769/a-realtor/internal/lib/handler"
+	loglib "github.com/emma769/a-realtor/internal/lib/log"
+	"github.com/emma769/a-realtor/internal/mailer"
+	"github.com/emma769/a-realtor/internal/repository"
+	"github.com/emma769/a-realtor/internal/repository/psql"
+)
+
+var (
+	ErrInvalidID      = handlerlib.NewError(400, "user.invalid_id", "invalid user id")
+	ErrNotFound       = handlerlib.NewError(404, "user.not_found", "user not found")
+	ErrInviteNotFound = handlerlib.NewError(404, "invite.not_found", "pending invite not found")
+	ErrDuplicateEmail = handlerlib.NewError(409, "user.email_taken", "email already in use")
+	ErrInvalidManager = handlerlib.NewError(
+		422,
+		"user.invalid_manager",
+		"manager must be another active manager or admin",
+	)
+	ErrRoleNotFound   = handlerlib.NewError(404, "role.not_found", "role not found")
+	ErrSelfDeactivate = handlerlib.NewError(
+		409,
+		"user.self_deactivation",
+		"cannot deactivate your own account",
+	)
+)
+
+type storer interface {
+	FindUserByID(context.Context, uuid.UUID) (*entity.User, error)
+	FindUsers(
+		context.Context,
+		psql.UserFilterParam,
+		psql.PaginationParam,
+	) ([]*entity.User, error)
+	CreatePendingUser(context.Context, psql.InviteParam) (*entity.User, error)
+	DeletePendingUser(context.Context, uuid.UUID) error
+	UpdateUserStatus(
+		context.Context,
+		uuid.UUID,
+		entity.UserStatus,
+		entity.UserStatus,
+	) (*entity.User, error)
+	DeactivateUser(context.Context, uuid.UUID) (*entity.User, error)
+	UnlockLogin(context.Context, uuid.UUID, string, uuid.UUID) error
+	FindLockouts(context.Context, string, psql.PaginationParam) ([]*entity.Lockout, error)
+	FindRolePolicies(context.Context) ([]*entity.RolePolicy, error)
+	UpsertRolePolicy(context.Context, entity.Role, bool) (*entity.RolePolicy, error)
+	SetUserManager(context.Context, uuid.UUID, *uuid.UUID) (*entity.User, error)
+}
+
+type tokenIssuer interface {
+	GetUserToken(context.Context, uuid.UUID, entity.TokenPurpose, time.Duration) (string, error)
+}
+
+type Service struct {
+	store   storer
+	timeout time.Duration
+	tokens  tokenIssuer
+	mailer  mailer.Mailer
+	cfg     *config.Config
+	logger  *slog.Logger
+}
+
+func (s *Service) log(ctx context.Context) *slog.Logger {
+	return loglib.From(ctx, s.logger)
+}
+
+type FilterParam struct {
+	role   entity.Role
+	status entity.UserStatus
+}
+
+func (f FilterParam) Role() entity.Role {
+	return f.role
+}
+
+func (f FilterParam) Status() entity.UserStatus {
+	return f.status
+}
+
+func (s *Service) findUsers(
+	ctx context.Context,
+	filterParam FilterParam,
+	paginator *handlerlib.Paginator,
+) ([]*entity.User, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+	return s.store.FindUsers(ctx, filterParam, paginator)
+}
+
+type InviteParam struct {
+	name,
+	email string
+	role entity.Role
+}
+
+func (param InviteParam) Name() string {
+	return param.name
+}
+
+func (param InviteParam) Email() string {
+	return param.email
+}
+
+func (param InviteParam) Role() entity.Role {
+	return param.role
+}
+
+func (s *Service) invite(
+	ctx context.Context,
+	inviter *entity.User,
+	in entity.InviteIn,
+) (*entity.User, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	user, err := s.store.CreatePendingUser(ctx, InviteParam{
+		name:  in.Name,
+		email: in.Email,
+		role:  in.Role,
+	})
+
+	if err != nil && errors.Is(err, repository.ErrDuplicateKey) {
+		return nil, ErrDuplicateEmail
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.sendInvite(ctx, inviter, user); err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+func (s *Service) resendInvite(
+	ctx context.Context,
+	inviter *entity.User,
+	id uuid.UUID,
+) (*entity.User, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	user, err := s.store.FindUserByID(ctx, id)
+
+	if err != nil && errors.Is(err, repository.ErrNotFound) {
+		return nil, ErrInviteNotFound
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if user.Status != entity.StatusPending {
+		return nil, ErrInviteNotFound
+	}
+
+	if err := s.sendInvite(ctx, inviter, user); err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+func (s *Service) revokeInvite(ctx context.Context, id uuid.UUID) error {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	err := s.store.DeletePendingUser(ctx, id)
+
+	if err != nil && errors.Is(err, repository.ErrNotFound) {
+		return ErrInviteNotFound
+	}
+
+	return err
+}
+
+func (s *Service) deactivate(ctx context.Context, id uuid.UUID) (*entity.User, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	user, err := s.store.DeactivateUser(ctx, id)
+
+	if err != nil && errors.Is(err, repository.ErrNotFound) {
+		return nil, ErrNotFound
+	}
+
+	return user, err
+}
+
+func (s *Service) reactivate(ctx context.Context, id uuid.UUID) (*entity.User, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	user, err := s.store.UpdateUserStatus(
+		ctx,
+		id,
+		entity.StatusDeactivated,
+		entity.StatusActive,
+	)
+
+	if err != nil && errors.Is(err, repository.ErrNotFound) {
+		return nil, ErrNotFound
+	}
+
+	return user, err
+}
+
+func (s *Service) unlock(ctx context.Context, admin *entity.User, id uuid.UUID) error {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	user, err := s.store.FindUserByID(ctx, id)
+
+	if err != nil && errors.Is(err, repository.ErrNotFound) {
+		return ErrNotFound
+	}
+
+	if err != nil {
+		return err
+	}
+
+	return s.store.UnlockLogin(ctx, user.UserID, entity.EmailThrottleKey(user.Email), admin.UserID)
+}
+
+func (s *Service) findLockouts(
+	ctx context.Context,
+	key string,
+	paginator *handlerlib.Paginator,
+) ([]*entity.Lockout, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+	return s.store.FindLockouts(ctx, key, paginator)
+}
+
+func (s *Service) rolePolicies(ctx context.Context) ([]*entity.RolePolicy, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+	return s.store.FindRolePolicies(ctx)
+}
+
+func (s *Service) setRolePolicy(
+	ctx context.Context,
+	role entity.Role,
+	in entity.RolePolicyIn,
+) (*entity.RolePolicy, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+	return s.store.UpsertRolePolicy(ctx, role, in.Require2FA)
+}
+
+func (s *Service) setManager(
+	ctx context.Context,
+	id uuid.UUID,
+	in entity.SetManagerIn,
+) (*entity.User, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	if in.ManagerID != nil {
+		if *in.ManagerID == id {
+			return nil, ErrInvalidManager
+		}
+
+		manager, err := s.store.FindUserByID(ctx, *in.ManagerID)
+
+		if err != nil && errors.Is(err, repository.ErrNotFound) {
+			return nil, ErrInvalidManager
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		if !manager.IsActive() || !manager.HasRole(entity.RoleManager, entity.RoleAdmin) {
+			return nil, ErrInvalidManager
+		}
+	}
+
+	user, err := s.store.SetUserManager(ctx, id, in.ManagerID)
+
+	if err != nil && errors.Is(err, repository.ErrNotFound) {
+		return nil, ErrNotFound
+	}
+
+	return user, err
+}
+
+func (s *Service) sendInvite(ctx context.Context, inviter, user *entity.User) error {
+	plain, err := s.tokens.GetUserToken(ctx, user.UserID, entity.PurposeInvite, s.cfg.InviteExpire)
+	if err != nil {
+		return err
+	}
+
+	msg := &mailer.Message{
+		To:      user.Email,
+		Subject: "You have been invited to A-Realtor",
+		Body: fmt.Sprintf(
+			"Hi,\n\n%s has invited you to join A-Realtor as %s. "+
+				"Use the link below to set your password. It expires in %s.\n\n%s\n",
+			inviter.Name,
+			user.Role,
+			s.cfg.InviteExpire,
+			mailer.Link(cmp.Or(s.cfg.AppUrl, s.cfg.TrustedOrigin), "/accept-invite", plain),
+		),
+	}
+
+	if err := s.mailer.Send(ctx, msg); err != nil {
+		s.log(ctx).LogAttrs(ctx, slog.LevelError, "could not send mail", slog.Attr{
+			Key:   "detail",
+			Value: slog.StringValue(err.Error()),
+		}, slog.Attr{
+			Key:   "subject",
+			Value: slog.StringValue(msg.Subject),
+		})
+	}
+
+	return nil
+}
@@ -0,0 +1,27 @@
+package apikey
+
+import (
+	"github.com/emma769/a-realtor/internal/entity"
+	"github.com/emma769/a-realtor/internal/openapi"
+)
+
+func (ctrl Ctrl) Docs(d *openapi.Group) {
+	d = d.With(openapi.Session)
+
+	d.Post("/", openapi.Operation{
+		Summary: "Create an API key",
+		Description: "The plain key is only returned once. " +
+			"Without scopes the key has the owner's full role.",
+		Request:  entity.APIKeyIn{},
+		Response: CreatedAPIKey{},
+		Status:   201,
+	})
+	d.Get("/", openapi.Operation{
+		Summary:  "List your API keys",
+		Response: []*entity.APIKey{},
+	})
+	d.Delete("/{id}", openapi.Operation{
+		Summary: "Revoke an API key",
+		Status:  204,
+	})
+}
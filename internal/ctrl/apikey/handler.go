@@ -0,0 +1,86 @@
+package apikey
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+
+	"github.com/emma769/a-realtor/internal/entity"
+	handlerlib "github.com/emma769/a-realtor/internal/lib/handler"
+	"github.com/emma769/a-realtor/internal/middleware"
+	"github.com/emma769/a-realtor/internal/validator"
+)
+
+const timeout = 5 * time.Second
+
+type Ctrl struct {
+	*Service
+}
+
+func New(store storer) *Ctrl {
+	return &Ctrl{
+		Service: &Service{
+			store,
+			timeout,
+		},
+	}
+}
+
+func (ctrl Ctrl) Routes(r chi.Router) {
+	r.With(middleware.RequireAuth, middleware.RequireSession).Group(func(r chi.Router) {
+		r.Post("/", ctrl.createAPIKey())
+		r.Get("/", ctrl.findAPIKeys())
+		r.Delete("/{id}", ctrl.revokeAPIKey())
+	})
+}
+
+func (ctrl *Ctrl) createAPIKey() http.HandlerFunc {
+	return handlerlib.Wrap(func(w http.ResponseWriter, r *http.Request) error {
+		in, err := handlerlib.Bind[entity.APIKeyIn](w, r)
+		if err != nil {
+			return err
+		}
+
+		v := validator.New()
+
+		if entity.ValidateAPIKeyIn(v, in); !v.Valid() {
+			return handlerlib.ValidationError(v.Err())
+		}
+
+		key, err := ctrl.create(r.Context(), handlerlib.GetCtxUser(r), in)
+		if err != nil {
+			return err
+		}
+
+		return handlerlib.WriteJsonNoStore(w, 201, key)
+	})
+}
+
+func (ctrl *Ctrl) findAPIKeys() http.HandlerFunc {
+	return handlerlib.Wrap(func(w http.ResponseWriter, r *http.Request) error {
+		keys, err := ctrl.findAll(r.Context(), handlerlib.GetCtxUser(r))
+		if err != nil {
+			return err
+		}
+
+		return handlerlib.WriteJson(w, 200, keys)
+	})
+}
+
+func (ctrl *Ctrl) revokeAPIKey() http.HandlerFunc {
+	return handlerlib.Wrap(func(w http.ResponseWriter, r *http.Request) error {
+		id, err := uuid.Parse(chi.URLParam(r, "id"))
+		if err != nil {
+			return ErrInvalidID
+		}
+
+		err = ctrl.revoke(r.Context(), handlerlib.GetCtxUser(r), id)
+		if err != nil {
+			return err
+		}
+
+		return handlerlib.SendStatus(w, 204)
+	})
+}
@@ -0,0 +1,127 @@
+package apikey
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/emma769/a-realtor/internal/entity"
+	handlerlib "github.com/emma769/a-realtor/internal/lib/handler"
+	"github.com/emma769/a-realtor/internal/repository"
+	"github.com/emma769/a-realtor/internal/repository/psql"
+	"github.com/emma769/a-realtor/internal/token"
+)
+
+var (
+	ErrInvalidID = handlerlib.NewError(400, "api_key.invalid_id", "invalid api key id")
+	ErrNotFound  = handlerlib.NewError(404, "api_key.not_found", "api key not found")
+)
+
+type storer interface {
+	CreateAPIKey(context.Context, psql.APIKeyParam) (*entity.APIKey, error)
+	FindAPIKeys(context.Context, uuid.UUID) ([]*entity.APIKey, error)
+	RevokeAPIKey(context.Context, uuid.UUID, uuid.UUID) error
+}
+
+type Service struct {
+	store   storer
+	timeout time.Duration
+}
+
+type APIKeyParam struct {
+	userID    uuid.UUID
+	name      string
+	prefix    string
+	hash      []byte
+	scopes    []string
+	expiresAt *time.Time
+}
+
+func (param APIKeyParam) UserID() uuid.UUID {
+	return param.userID
+}
+
+func (param APIKeyParam) Name() string {
+	return param.name
+}
+
+func (param APIKeyParam) Prefix() string {
+	return param.prefix
+}
+
+func (param APIKeyParam) Hash() []byte {
+	return param.hash
+}
+
+func (param APIKeyParam) Scopes() []string {
+	return param.scopes
+}
+
+func (param APIKeyParam) ExpiresAt() *time.Time {
+	return param.expiresAt
+}
+
+type CreatedAPIKey struct {
+	*entity.APIKey
+	Key string `json:"key"`
+}
+
+func (s *Service) create(
+	ctx context.Context,
+	user *entity.User,
+	in entity.APIKeyIn,
+) (*CreatedAPIKey, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	generated, err := token.NewAPIKey()
+	if err != nil {
+		return nil, err
+	}
+
+	param := APIKeyParam{
+		userID: user.UserID,
+		name:   in.Name,
+		prefix: generated.Prefix,
+		hash:   generated.Hash,
+		scopes: in.Scopes,
+	}
+
+	// Without scopes the key is limited only by the owner's role.
+	if len(param.scopes) == 0 {
+		param.scopes = slices.Clone(entity.Scopes)
+	}
+
+	if in.ExpiresAt != nil {
+		param.expiresAt = &in.ExpiresAt.Time
+	}
+
+	key, err := s.store.CreateAPIKey(ctx, param)
+	if err != nil {
+		return nil, err
+	}
+
+	return &CreatedAPIKey{key, generated.Plain}, nil
+}
+
+func (s *Service) findAll(ctx context.Context, user *entity.User) ([]*entity.APIKey, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+	return s.store.FindAPIKeys(ctx, user.UserID)
+}
+
+func (s *Service) revoke(ctx context.Context, user *entity.User, id uuid.UUID) error {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	err := s.store.RevokeAPIKey(ctx, user.UserID, id)
+
+	if err != nil && errors.Is(err, repository.ErrNotFound) {
+		return ErrNotFound
+	}
+
+	return err
+}
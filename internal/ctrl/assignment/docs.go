@@ -0,0 +1,29 @@
+package assignment
+
+import (
+	"github.com/emma769/a-realtor/internal/entity"
+	"github.com/emma769/a-realtor/internal/openapi"
+)
+
+func (ctrl Ctrl) Docs(d *openapi.Group) {
+	d = d.With(
+		openapi.Session,
+		openapi.Roles(string(entity.RoleAdmin), string(entity.RoleManager)),
+	)
+
+	d.Post("/landlords/{id}", openapi.Operation{
+		Summary: "Assign a landlord to a user",
+		Request: entity.AssignIn{},
+		Status:  204,
+	})
+	d.Post("/tenants/{id}", openapi.Operation{
+		Summary: "Assign a tenant to a user",
+		Request: entity.AssignIn{},
+		Status:  204,
+	})
+	d.Post("/transfer", openapi.Operation{
+		Summary:  "Transfer every record from one user to another",
+		Request:  entity.TransferIn{},
+		Response: entity.PortfolioTransfer{},
+	})
+}
@@ -0,0 +1,104 @@
+package assignment
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+
+	"github.com/emma769/a-realtor/internal/entity"
+	handlerlib "github.com/emma769/a-realtor/internal/lib/handler"
+	"github.com/emma769/a-realtor/internal/middleware"
+	"github.com/emma769/a-realtor/internal/validator"
+)
+
+const timeout = 5 * time.Second
+
+type Ctrl struct {
+	*Service
+}
+
+func New(store storer) *Ctrl {
+	return &Ctrl{
+		Service: &Service{
+			store,
+			timeout,
+		},
+	}
+}
+
+func (ctrl Ctrl) Routes(r chi.Router) {
+	r.With(
+		middleware.RequireSession,
+		middleware.RequireRole(entity.RoleAdmin, entity.RoleManager),
+	).Group(func(r chi.Router) {
+		r.Post("/landlords/{id}", ctrl.assignRecord(entity.AuditLandlord))
+		r.Post("/tenants/{id}", ctrl.assignRecord(entity.AuditTenant))
+		r.Post("/transfer", ctrl.transferPortfolio())
+	})
+}
+
+func (ctrl *Ctrl) assignRecord(entityType string) http.HandlerFunc {
+	return handlerlib.Wrap(func(w http.ResponseWriter, r *http.Request) error {
+		id, err := uuid.Parse(chi.URLParam(r, "id"))
+		if err != nil {
+			return handlerlib.NewError(400, entityType+".invalid_id", "invalid "+entityType+" id")
+		}
+
+		in, err := handlerlib.Bind[entity.AssignIn](w, r)
+		if err != nil {
+			return err
+		}
+
+		v := validator.New()
+
+		if entity.ValidateAssignIn(v, in); !v.Valid() {
+			return handlerlib.ValidationError(v.Err())
+		}
+
+		visibility := handlerlib.GetCtxVisibility(r)
+
+		if entityType == entity.AuditLandlord {
+			err = ctrl.assignLandlord(r.Context(), visibility, id, in)
+		} else {
+			err = ctrl.assignTenant(r.Context(), visibility, id, in)
+		}
+
+		if err != nil && errors.Is(err, ErrNotFound) {
+			return handlerlib.NewError(404, entityType+".not_found", entityType+" not found")
+		}
+		if err != nil {
+			return err
+		}
+
+		return handlerlib.SendStatus(w, 204)
+	})
+}
+
+func (ctrl *Ctrl) transferPortfolio() http.HandlerFunc {
+	return handlerlib.Wrap(func(w http.ResponseWriter, r *http.Request) error {
+		in, err := handlerlib.Bind[entity.TransferIn](w, r)
+		if err != nil {
+			return err
+		}
+
+		v := validator.New()
+
+		if entity.ValidateTransferIn(v, in); !v.Valid() {
+			return handlerlib.ValidationError(v.Err())
+		}
+
+		transfer, err := ctrl.transfer(r.Context(), handlerlib.GetCtxVisibility(r), in)
+
+		if err != nil && errors.Is(err, ErrNotFound) {
+			return handlerlib.NewError(404, "user.not_found", "user not found in your team")
+		}
+		if err != nil {
+			return err
+		}
+
+		return handlerlib.WriteJson(w, 200, transfer)
+	})
+}
@@ -0,0 +1,153 @@
+package assignment
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/emma769/a-realtor/internal/entity"
+	handlerlib "github.com/emma769/a-realtor/internal/lib/handler"
+	"github.com/emma769/a-realtor/internal/repository"
+)
+
+var (
+	ErrNotFound        = handlerlib.NewError(404, "assignment.not_found", "record not found")
+	ErrInvalidAssignee = handlerlib.NewError(
+		422,
+		"assignment.invalid_assignee",
+		"assignee must be an active user in your team",
+	)
+)
+
+type storer interface {
+	FindUserByID(context.Context, uuid.UUID) (*entity.User, error)
+	FindLandlord(context.Context, uuid.UUID) (*entity.Landlord, error)
+	FindTenant(context.Context, uuid.UUID) (*entity.Tenant, error)
+	AssignLandlord(context.Context, uuid.UUID, uuid.UUID) error
+	AssignTenant(context.Context, uuid.UUID, uuid.UUID) error
+	TransferPortfolio(context.Context, uuid.UUID, uuid.UUID) (*entity.PortfolioTransfer, error)
+}
+
+type Service struct {
+	store   storer
+	timeout time.Duration
+}
+
+func (s *Service) checkAssignee(
+	ctx context.Context,
+	visibility *entity.Visibility,
+	id uuid.UUID,
+) error {
+	if !visibility.Allows(id) {
+		return ErrInvalidAssignee
+	}
+
+	user, err := s.store.FindUserByID(ctx, id)
+
+	if err != nil && errors.Is(err, repository.ErrNotFound) {
+		return ErrInvalidAssignee
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if !user.IsActive() {
+		return ErrInvalidAssignee
+	}
+
+	return nil
+}
+
+func (s *Service) assignLandlord(
+	ctx context.Context,
+	visibility *entity.Visibility,
+	id uuid.UUID,
+	in entity.AssignIn,
+) error {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	landlord, err := s.store.FindLandlord(ctx, id)
+
+	if err != nil && errors.Is(err, repository.ErrNotFound) {
+		return ErrNotFound
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if !visibility.Allows(landlord.Owner()) {
+		return ErrNotFound
+	}
+
+	if err := s.checkAssignee(ctx, visibility, in.AssigneeID); err != nil {
+		return err
+	}
+
+	err = s.store.AssignLandlord(ctx, id, in.AssigneeID)
+
+	if err != nil && errors.Is(err, repository.ErrNotFound) {
+		return ErrNotFound
+	}
+
+	return err
+}
+
+func (s *Service) assignTenant(
+	ctx context.Context,
+	visibility *entity.Visibility,
+	id uuid.UUID,
+	in entity.AssignIn,
+) error {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	tenant, err := s.store.FindTenant(ctx, id)
+
+	if err != nil && errors.Is(err, repository.ErrNotFound) {
+		return ErrNotFound
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if !visibility.Allows(tenant.Owner()) {
+		return ErrNotFound
+	}
+
+	if err := s.checkAssignee(ctx, visibility, in.AssigneeID); err != nil {
+		return err
+	}
+
+	err = s.store.AssignTenant(ctx, id, in.AssigneeID)
+
+	if err != nil && errors.Is(err, repository.ErrNotFound) {
+		return ErrNotFound
+	}
+
+	return err
+}
+
+func (s *Service) transfer(
+	ctx context.Context,
+	visibility *entity.Visibility,
+	in entity.TransferIn,
+) (*entity.PortfolioTransfer, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	if !visibility.Allows(in.FromUserID) {
+		return nil, ErrNotFound
+	}
+
+	if err := s.checkAssignee(ctx, visibility, in.ToUserID); err != nil {
+		return nil, err
+	}
+
+	return s.store.TransferPortfolio(ctx, in.FromUserID, in.ToUserID)
+}
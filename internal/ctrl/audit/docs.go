@@ -0,0 +1,26 @@
+package audit
+
+import (
+	"github.com/emma769/a-realtor/internal/entity"
+	"github.com/emma769/a-realtor/internal/openapi"
+)
+
+func (ctrl Ctrl) Docs(d *openapi.Group) {
+	d.With(
+		openapi.Session,
+		openapi.Roles(string(entity.RoleAdmin), string(entity.RoleManager)),
+	).Get("/", openapi.Operation{
+		Summary: "Search the audit log",
+		Description: "Under scoped visibility, managers only see entries for records their " +
+			"team owns or changes their team made.",
+		Response:  entity.AuditEntry{},
+		Paginated: true,
+		Query: []openapi.Param{
+			{Name: "entity_type", Description: "landlord or tenant"},
+			{Name: "entity_id", Format: "uuid"},
+			{Name: "actor_id", Format: "uuid"},
+			{Name: "from", Description: "YYYY-MM-DD or RFC 3339"},
+			{Name: "to", Description: "YYYY-MM-DD or RFC 3339, inclusive"},
+		},
+	})
+}
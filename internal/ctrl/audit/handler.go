@@ -0,0 +1,119 @@
+package audit
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+
+	"github.com/emma769/a-realtor/internal/entity"
+	handlerlib "github.com/emma769/a-realtor/internal/lib/handler"
+	"github.com/emma769/a-realtor/internal/middleware"
+)
+
+const timeout = 5 * time.Second
+
+type Ctrl struct {
+	*Service
+}
+
+func New(store storer) *Ctrl {
+	return &Ctrl{
+		Service: &Service{
+			store,
+			timeout,
+		},
+	}
+}
+
+func (ctrl Ctrl) Routes(r chi.Router) {
+	r.With(
+		middleware.RequireSession,
+		middleware.RequireRole(entity.RoleAdmin, entity.RoleManager),
+	).Get("/", ctrl.findAuditLog())
+}
+
+func (ctrl *Ctrl) findAuditLog() http.HandlerFunc {
+	return handlerlib.Wrap(func(w http.ResponseWriter, r *http.Request) error {
+		filterParam := FilterParam{
+			entityType: handlerlib.GetQuery(r, "entity_type", ""),
+			owners:     handlerlib.GetCtxVisibility(r).Owners(),
+		}
+
+		var err error
+
+		if filterParam.entityID, err = queryUUID(r, "entity_id"); err != nil {
+			return handlerlib.NewError(400, "audit.invalid_filter", "invalid entity_id")
+		}
+
+		if filterParam.actorID, err = queryUUID(r, "actor_id"); err != nil {
+			return handlerlib.NewError(400, "audit.invalid_filter", "invalid actor_id")
+		}
+
+		if filterParam.from, err = queryTime(r, "from", 0); err != nil {
+			return handlerlib.NewError(
+				400,
+				"audit.invalid_filter",
+				"invalid from, use YYYY-MM-DD or RFC 3339",
+			)
+		}
+
+		if filterParam.to, err = queryTime(r, "to", 24*time.Hour); err != nil {
+			return handlerlib.NewError(
+				400,
+				"audit.invalid_filter",
+				"invalid to, use YYYY-MM-DD or RFC 3339",
+			)
+		}
+
+		paginator := handlerlib.NewPaginator(
+			handlerlib.GetQueryInt(r, "page", 1),
+			handlerlib.GetQueryInt(r, "page_size", 20),
+		)
+
+		entries, err := ctrl.findAll(r.Context(), filterParam, paginator)
+		if err != nil {
+			return err
+		}
+
+		return handlerlib.WriteJson(w, 200, map[string]any{
+			"metadata": paginator.GetMetadata(),
+			"data":     entries,
+		})
+	})
+}
+
+func queryUUID(r *http.Request, name string) (*uuid.UUID, error) {
+	raw := handlerlib.GetQuery(r, name, "")
+	if raw == "" {
+		return nil, nil
+	}
+
+	id, err := uuid.Parse(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	return &id, nil
+}
+
+func queryTime(r *http.Request, name string, dayOffset time.Duration) (*time.Time, error) {
+	raw := handlerlib.GetQuery(r, name, "")
+	if raw == "" {
+		return nil, nil
+	}
+
+	if t, err := time.Parse(time.RFC3339, raw); err == nil {
+		return &t, nil
+	}
+
+	t, err := time.Parse("2006-01-02", raw)
+	if err != nil {
+		return nil, err
+	}
+
+	t = t.Add(dayOffset)
+
+	return &t, nil
+}
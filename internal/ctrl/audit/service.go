@@ -0,0 +1,68 @@
+package audit
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/emma769/a-realtor/internal/entity"
+	handlerlib "github.com/emma769/a-realtor/internal/lib/handler"
+	"github.com/emma769/a-realtor/internal/repository/psql"
+)
+
+type storer interface {
+	FindAuditLog(
+		context.Context,
+		psql.AuditFilterParam,
+		psql.PaginationParam,
+	) ([]*entity.AuditEntry, error)
+}
+
+type Service struct {
+	store   storer
+	timeout time.Duration
+}
+
+type FilterParam struct {
+	entityType string
+	entityID   *uuid.UUID
+	actorID    *uuid.UUID
+	from       *time.Time
+	to         *time.Time
+	owners     []string
+}
+
+func (f FilterParam) EntityType() string {
+	return f.entityType
+}
+
+func (f FilterParam) EntityID() *uuid.UUID {
+	return f.entityID
+}
+
+func (f FilterParam) ActorID() *uuid.UUID {
+	return f.actorID
+}
+
+func (f FilterParam) From() *time.Time {
+	return f.from
+}
+
+func (f FilterParam) To() *time.Time {
+	return f.to
+}
+
+func (f FilterParam) Owners() []string {
+	return f.owners
+}
+
+func (s *Service) findAll(
+	ctx context.Context,
+	filterParam FilterParam,
+	paginator *handlerlib.Paginator,
+) ([]*entity.AuditEntry, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+	return s.store.FindAuditLog(ctx, filterParam, paginator)
+}
@@ -0,0 +1,25 @@
+package health
+
+import (
+	"net/http"
+
+	"github.com/emma769/a-realtor/internal/openapi"
+)
+
+func (ctrl *Ctrl) Docs(d *openapi.Group) {
+	d = d.With(openapi.Public, openapi.Tag("health"))
+
+	d.Get("/healthz", openapi.Operation{
+		Summary: "Liveness probe",
+		Response: struct {
+			Status string `json:"status"`
+		}{},
+	})
+	d.Get("/readyz", openapi.Operation{
+		Summary: "Readiness probe with database, migration and worker checks",
+		Description: "A schema newer than this build is still ready and is flagged with " +
+			"migrations.ahead, so old replicas keep serving during a rolling deploy.",
+		Response:  Readiness{},
+		Responses: map[int]any{http.StatusServiceUnavailable: Readiness{}},
+	})
+}
@@ -0,0 +1,194 @@
+package health
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	handlerlib "github.com/emma769/a-realtor/internal/lib/handler"
+	"github.com/emma769/a-realtor/internal/migrate"
+	"github.com/emma769/a-realtor/internal/worker"
+)
+
+const timeout = 2 * time.Second
+
+type pinger interface {
+	Ping(context.Context) error
+	Stats() sql.DBStats
+}
+
+type versioner interface {
+	Version(context.Context) (uint, bool, error)
+	Latest() uint
+}
+
+type statuser interface {
+	Status() worker.Status
+}
+
+type Ctrl struct {
+	store    pinger
+	migrator versioner
+	timeout  time.Duration
+
+	mu      sync.RWMutex
+	workers map[string]statuser
+
+	draining atomic.Bool
+}
+
+func New(store pinger, migrator versioner) *Ctrl {
+	return &Ctrl{
+		store:    store,
+		migrator: migrator,
+		timeout:  timeout,
+		workers:  map[string]statuser{},
+	}
+}
+
+func (ctrl *Ctrl) Register(name string, w statuser) {
+	ctrl.mu.Lock()
+	defer ctrl.mu.Unlock()
+	ctrl.workers[name] = w
+}
+
+func (ctrl *Ctrl) Drain() {
+	ctrl.draining.Store(true)
+}
+
+func (ctrl *Ctrl) Routes(r chi.Router) {
+	r.Get("/healthz", ctrl.healthz())
+	r.Get("/readyz", ctrl.readyz())
+}
+
+func (ctrl *Ctrl) healthz() http.HandlerFunc {
+	return handlerlib.Wrap(func(w http.ResponseWriter, r *http.Request) error {
+		return handlerlib.WriteJson(w, 200, map[string]string{"status": "ok"})
+	})
+}
+
+type Check struct {
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+type DatabaseCheck struct {
+	Check
+	LatencyMs       int64 `json:"latencyMs"`
+	OpenConnections int   `json:"openConnections"`
+	InUse           int   `json:"inUse"`
+	Idle            int   `json:"idle"`
+	MaxOpen         int   `json:"maxOpenConnections"`
+	WaitCount       int64 `json:"waitCount"`
+	WaitDurationMs  int64 `json:"waitDurationMs"`
+}
+
+type MigrationCheck struct {
+	Check
+	Version uint `json:"version"`
+	Latest  uint `json:"latest"`
+	Dirty   bool `json:"dirty"`
+	Ahead   bool `json:"ahead,omitempty"`
+}
+
+type WorkerCheck struct {
+	Check
+	worker.Status
+}
+
+type Readiness struct {
+	Status     string                  `json:"status"`
+	Database   DatabaseCheck           `json:"database"`
+	Migrations MigrationCheck          `json:"migrations"`
+	Workers    map[string]*WorkerCheck `json:"workers"`
+}
+
+func status(ok bool) string {
+	if ok {
+		return "ok"
+	}
+
+	return "failing"
+}
+
+func (ctrl *Ctrl) readiness(ctx context.Context) (*Readiness, bool) {
+	ctx, cancel := context.WithTimeout(ctx, ctrl.timeout)
+	defer cancel()
+
+	ready := !ctrl.draining.Load()
+	out := &Readiness{Workers: map[string]*WorkerCheck{}}
+
+	start := time.Now()
+	err := ctrl.store.Ping(ctx)
+	stats := ctrl.store.Stats()
+
+	out.Database = DatabaseCheck{
+		Check:           Check{Status: status(err == nil)},
+		LatencyMs:       time.Since(start).Milliseconds(),
+		OpenConnections: stats.OpenConnections,
+		InUse:           stats.InUse,
+		Idle:            stats.Idle,
+		MaxOpen:         stats.MaxOpenConnections,
+		WaitCount:       stats.WaitCount,
+		WaitDurationMs:  stats.WaitDuration.Milliseconds(),
+	}
+
+	if err != nil {
+		ready = false
+		out.Database.Error = err.Error()
+	}
+
+	version, dirty, err := ctrl.migrator.Version(ctx)
+	latest := ctrl.migrator.Latest()
+	migrated := err == nil && migrate.Compatible(version, latest, dirty)
+
+	out.Migrations = MigrationCheck{
+		Check:   Check{Status: status(migrated)},
+		Version: version,
+		Latest:  latest,
+		Dirty:   dirty,
+		Ahead:   version > latest,
+	}
+
+	if err != nil {
+		out.Migrations.Error = err.Error()
+	}
+
+	ready = ready && migrated
+
+	ctrl.mu.RLock()
+	defer ctrl.mu.RUnlock()
+
+	for name, w := range ctrl.workers {
+		st := w.Status()
+		out.Workers[name] = &WorkerCheck{Check{Status: status(st.Running)}, st}
+		ready = ready && st.Running
+	}
+
+	out.Status = status(ready)
+
+	if ctrl.draining.Load() {
+		out.Status = "draining"
+	}
+
+	return out, ready
+}
+
+func (ctrl *Ctrl) readyz() http.HandlerFunc {
+	return handlerlib.Wrap(func(w http.ResponseWriter, r *http.Request) error {
+		out, ready := ctrl.readiness(r.Context())
+
+		w.Header().Set("Cache-Control", "no-store")
+
+		if !ready {
+			return handlerlib.WriteJson(w, 503, out)
+		}
+
+		return handlerlib.WriteJson(w, 200, out)
+	})
+}
@@ -0,0 +1,61 @@
+package landlord
+
+import (
+	"github.com/emma769/a-realtor/internal/entity"
+	"github.com/emma769/a-realtor/internal/export"
+	"github.com/emma769/a-realtor/internal/openapi"
+)
+
+func (ctrl Ctrl) Docs(d *openapi.Group) {
+	read := d.With(openapi.Scope(entity.ScopeLandlordsRead))
+	write := d.With(openapi.Scope(entity.ScopeLandlordsWrite))
+	reports := d.With(openapi.Scope(entity.ScopeReportsRead))
+
+	write.Post("/", openapi.Operation{
+		Summary:  "Register a landlord with their first property",
+		Request:  entity.LandlordIn{},
+		Response: entity.Landlord{},
+		Status:   201,
+	})
+	read.Get("/", openapi.Operation{
+		Summary:   "List landlords",
+		Response:  entity.LandlordOut{},
+		Paginated: true,
+		Query: []openapi.Param{
+			{Name: "first_name", Description: "exact match, case insensitive"},
+			{Name: "phone", Description: "exact match"},
+			{Name: "address", Description: "full text search on property address"},
+		},
+	})
+	read.Get("/{id}", openapi.Operation{
+		Summary:     "Get a landlord with their properties",
+		Response:    entity.Landlord{},
+		Conditional: true,
+	})
+	read.Get("/{id}/history", openapi.Operation{
+		Summary:   "List audit entries for a landlord",
+		Response:  entity.AuditEntry{},
+		Paginated: true,
+	})
+	write.Delete("/{id}", openapi.Operation{
+		Summary:     "Delete a landlord",
+		Status:      204,
+		Conditional: true,
+	})
+	reports.Get("/count", openapi.Operation{
+		Summary: "Count visible landlords",
+		Response: struct {
+			Total int64 `json:"total"`
+		}{},
+	})
+	write.Put("/{id}/info", openapi.Operation{
+		Summary:     "Add property information to a landlord",
+		Request:     entity.PropertyInfoIn{},
+		Response:    entity.PropertyInfo{},
+		Conditional: true,
+	})
+	reports.Get("/xlsx", openapi.Operation{
+		Summary:     "Export visible landlords as a spreadsheet",
+		ContentType: export.ContentTypeXlsx,
+	})
+}
@@ -1,8 +1,6 @@
 package landlord
 
 import (
-	"errors"
-	"fmt"
 	"log/slog"
 	"net/http"
 	"os"
@@ -10,10 +8,11 @@ import (
 
 	"github.com/go-chi/chi/v5"
 	"github.com/google/uuid"
-	"github.com/xuri/excelize/v2"
 
 	"github.com/emma769/a-realtor/internal/entity"
+	"github.com/emma769/a-realtor/internal/export"
 	handlerlib "github.com/emma769/a-realtor/internal/lib/handler"
+	loglib "github.com/emma769/a-realtor/internal/lib/log"
 	"github.com/emma769/a-realtor/internal/middleware"
 	"github.com/emma769/a-realtor/internal/validator"
 )
@@ -37,13 +36,18 @@ func New(store storer, logger *slog.Logger) *Ctrl {
 
 func (ctrl Ctrl) Routes(r chi.Router) {
 	r.With(middleware.RequireAuth).Group(func(r chi.Router) {
-		r.Post("/", ctrl.createLandlord())
-		r.Get("/", ctrl.findLandlords())
-		r.Get("/{id}", ctrl.findLandlord())
-		r.Delete("/{id}", ctrl.deleteLandlord())
-		r.Get("/count", ctrl.landlordTotal())
-		r.Put("/{id}/info", ctrl.addPropertyInfo())
-		r.Get("/xlsx", ctrl.landlordXlsx())
+		read := r.With(middleware.RequireScope(entity.ScopeLandlordsRead))
+		write := r.With(middleware.RequireScope(entity.ScopeLandlordsWrite))
+		reports := r.With(middleware.RequireScope(entity.ScopeReportsRead))
+
+		write.Post("/", ctrl.createLandlord())
+		read.Get("/", ctrl.findLandlords())
+		read.Get("/{id}", ctrl.findLandlord())
+		read.Get("/{id}/history", ctrl.landlordHistory())
+		write.Delete("/{id}", ctrl.deleteLandlord())
+		reports.Get("/count", ctrl.landlordTotal())
+		write.Put("/{id}/info", ctrl.addPropertyInfo())
+		reports.Get("/xlsx", ctrl.landlordXlsx())
 	})
 }
 
@@ -51,26 +55,21 @@ func (ctrl *Ctrl) createLandlord() http.HandlerFunc {
 	return handlerlib.Wrap(func(w http.ResponseWriter, r *http.Request) error {
 		in, err := handlerlib.Bind[entity.LandlordIn](w, r)
 		if err != nil {
-			return handlerlib.NewError(422, err.Error())
+			return err
 		}
 
 		v := validator.New()
 
 		if entity.ValidateLandlordIn(v, in); !v.Valid() {
-			return handlerlib.WriteJson(w, 422, v.Err())
+			return handlerlib.ValidationError(v.Err())
 		}
 
 		landlord, err := ctrl.create(r.Context(), handlerlib.GetCtxUser(r), in)
-
-		if err != nil && errors.Is(err, ErrDuplicateKey) {
-			return handlerlib.NewError(409, "phone already in use")
-		}
-
 		if err != nil {
 			return err
 		}
 
-		return handlerlib.WriteJson(w, 201, landlord)
+		return handlerlib.WriteJsonETag(w, r, 201, landlord.ETag(), landlord)
 	})
 }
 
@@ -78,20 +77,15 @@ func (ctrl *Ctrl) findLandlord() http.HandlerFunc {
 	return handlerlib.Wrap(func(w http.ResponseWriter, r *http.Request) error {
 		landlordID, err := uuid.Parse(chi.URLParam(r, "id"))
 		if err != nil {
-			return handlerlib.NewError(400, "invalid landlord id")
-		}
-
-		landlord, err := ctrl.findOne(r.Context(), landlordID)
-
-		if err != nil && errors.Is(err, ErrNotFound) {
-			return handlerlib.NewError(404, "landlord not found")
+			return ErrInvalidID
 		}
 
+		landlord, err := ctrl.findOne(r.Context(), handlerlib.GetCtxVisibility(r), landlordID)
 		if err != nil {
 			return err
 		}
 
-		return handlerlib.WriteJson(w, 200, landlord)
+		return handlerlib.WriteJsonETag(w, r, 200, landlord.ETag(), landlord)
 	})
 }
 
@@ -101,6 +95,7 @@ func (ctrl *Ctrl) findLandlords() http.HandlerFunc {
 			firstName: handlerlib.GetQuery(r, "first_name", ""),
 			phone:     handlerlib.GetQuery(r, "phone", ""),
 			address:   handlerlib.GetQuery(r, "address", ""),
+			owners:    handlerlib.GetCtxVisibility(r).Owners(),
 		}
 
 		paginator := handlerlib.NewPaginator(
@@ -113,7 +108,7 @@ func (ctrl *Ctrl) findLandlords() http.HandlerFunc {
 			return err
 		}
 
-		return handlerlib.WriteJson(w, 200, map[string]any{
+		return handlerlib.WriteJsonWeak(w, r, 200, map[string]any{
 			"metadata": paginator.GetMetadata(),
 			"data":     landlords,
 		})
@@ -124,10 +119,16 @@ func (ctrl *Ctrl) deleteLandlord() http.HandlerFunc {
 	return handlerlib.Wrap(func(w http.ResponseWriter, r *http.Request) error {
 		id, err := uuid.Parse(chi.URLParam(r, "id"))
 		if err != nil {
-			return handlerlib.NewError(400, "invalid landlord id")
+			return ErrInvalidID
+		}
+
+		ifMatch, err := handlerlib.IfMatch(r)
+		if err != nil {
+			return err
 		}
 
-		if err := ctrl.deleteOne(r.Context(), id); err != nil {
+		err = ctrl.deleteOne(r.Context(), handlerlib.GetCtxVisibility(r), id, ifMatch)
+		if err != nil {
 			return err
 		}
 
@@ -135,9 +136,33 @@ func (ctrl *Ctrl) deleteLandlord() http.HandlerFunc {
 	})
 }
 
+func (ctrl *Ctrl) landlordHistory() http.HandlerFunc {
+	return handlerlib.Wrap(func(w http.ResponseWriter, r *http.Request) error {
+		id, err := uuid.Parse(chi.URLParam(r, "id"))
+		if err != nil {
+			return ErrInvalidID
+		}
+
+		paginator := handlerlib.NewPaginator(
+			handlerlib.GetQueryInt(r, "page", 1),
+			handlerlib.GetQueryInt(r, "page_size", 20),
+		)
+
+		entries, err := ctrl.history(r.Context(), handlerlib.GetCtxVisibility(r), id, paginator)
+		if err != nil {
+			return err
+		}
+
+		return handlerlib.WriteJson(w, 200, map[string]any{
+			"metadata": paginator.GetMetadata(),
+			"data":     entries,
+		})
+	})
+}
+
 func (ctrl *Ctrl) landlordTotal() http.HandlerFunc {
 	return handlerlib.Wrap(func(w http.ResponseWriter, r *http.Request) error {
-		total, err := ctrl.total(r.Context())
+		total, err := ctrl.total(r.Context(), handlerlib.GetCtxVisibility(r))
 		if err != nil {
 			return err
 		}
@@ -152,79 +177,52 @@ func (ctrl *Ctrl) addPropertyInfo() http.HandlerFunc {
 	return handlerlib.Wrap(func(w http.ResponseWriter, r *http.Request) error {
 		id, err := uuid.Parse(chi.URLParam(r, "id"))
 		if err != nil {
-			return handlerlib.NewError(400, "invalid landord id")
+			return ErrInvalidID
+		}
+
+		ifMatch, err := handlerlib.IfMatch(r)
+		if err != nil {
+			return err
 		}
 
 		in, err := handlerlib.Bind[entity.PropertyInfoIn](w, r)
 		if err != nil {
-			return handlerlib.NewError(422, err.Error())
+			return err
 		}
 
 		v := validator.New()
 
 		if entity.ValidatePropertyInfoIn(v, in); !v.Valid() {
-			return handlerlib.WriteJson(w, 422, v.Err())
+			return handlerlib.ValidationError(v.Err())
 		}
 
-		info, err := ctrl.createPropertyInfo(r.Context(), id, in)
+		info, etag, err := ctrl.createPropertyInfo(
+			r.Context(),
+			handlerlib.GetCtxVisibility(r),
+			id,
+			ifMatch,
+			in,
+		)
 		if err != nil {
 			return err
 		}
 
+		w.Header().Set("ETag", etag)
+
 		return handlerlib.WriteJson(w, 200, info)
 	})
 }
 
 func (ctrl *Ctrl) landlordXlsx() http.HandlerFunc {
 	return handlerlib.Wrap(func(w http.ResponseWriter, r *http.Request) error {
-		landlords, err := ctrl.getAll(r.Context())
+		landlords, err := ctrl.getAll(r.Context(), handlerlib.GetCtxVisibility(r))
 		if err != nil {
 			return err
 		}
 
-		file := excelize.NewFile()
-
-		headers := []string{
-			"Firstname",
-			"Lastname",
-			"Email",
-			"Phone",
-			"Address",
-			"Property Type",
-			"Flat No",
-			"Lease Price",
-			"Lease Period",
-			"Start Date",
-			"End Date",
-		}
-
-		for i, header := range headers {
-			file.SetCellValue("Sheet1", fmt.Sprintf("%s%d", string(rune(65+i)), 1), header)
-		}
-
-		data := make([][]any, len(landlords))
-
-		for i, landlord := range landlords {
-			data[i] = []any{
-				landlord.FirstName,
-				landlord.LastName,
-				landlord.Email,
-				landlord.Phone,
-				landlord.Address,
-				landlord.PropertyType.String(),
-				landlord.AdditionalInfo["flatNo"],
-				landlord.LeasePrice,
-				landlord.LeasePeriod,
-				landlord.StartDate.Format("02/01/2006"),
-				landlord.EndDate.Format("02/01/2006"),
-			}
-		}
-
-		for i, row := range data {
-			dr := i + 2
-			for j, col := range row {
-				file.SetCellValue("Sheet1", fmt.Sprintf("%s%d", string(rune(65+j)), dr), col)
-			}
+		file, err := export.Landlords(landlords).Xlsx()
+		if err != nil {
+			return err
 		}
 
 		temp, err := os.CreateTemp("", "landlords.xlsx")
@@ -234,7 +232,7 @@ func (ctrl *Ctrl) landlordXlsx() http.HandlerFunc {
 
 		defer func() {
 			if err := os.Remove(temp.Name()); err != nil {
-				ctrl.logger.ErrorContext(
+				loglib.From(r.Context(), ctrl.logger).ErrorContext(
 					r.Context(),
 					"could not remove temp file",
 					"detail",
@@ -245,7 +243,7 @@ func (ctrl *Ctrl) landlordXlsx() http.HandlerFunc {
 
 		defer func() {
 			if err := temp.Close(); err != nil {
-				ctrl.logger.ErrorContext(
+				loglib.From(r.Context(), ctrl.logger).ErrorContext(
 					r.Context(),
 					"could not close temp file",
 					"detail",
@@ -258,8 +256,7 @@ func (ctrl *Ctrl) landlordXlsx() http.HandlerFunc {
 			return err
 		}
 
-		w.Header().
-			Set("Content-Type", "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet")
+		w.Header().Set("Content-Type", export.ContentTypeXlsx)
 		w.Header().Set("Content-Disposition", "attachment; filename="+temp.Name())
 
 		http.ServeFile(w, r, temp.Name())
@@ -12,11 +12,13 @@ import (
 	handlerlib "github.com/emma769/a-realtor/internal/lib/handler"
 	"github.com/emma769/a-realtor/internal/repository"
 	"github.com/emma769/a-realtor/internal/repository/psql"
+	"github.com/emma769/a-realtor/internal/tracing"
 )
 
 var (
-	ErrNotFound     = errors.New("not found")
-	ErrDuplicateKey = errors.New("duplicate key")
+	ErrInvalidID    = handlerlib.NewError(400, "landlord.invalid_id", "invalid landlord id")
+	ErrNotFound     = handlerlib.NewError(404, "landlord.not_found", "landlord not found")
+	ErrDuplicateKey = handlerlib.NewError(409, "landlord.phone_taken", "phone already in use")
 )
 
 type storer interface {
@@ -28,14 +30,21 @@ type storer interface {
 		psql.PaginationParam,
 	) ([]*entity.LandlordOut, error)
 	UpdateLandlord(context.Context, psql.LandlordParam) (*entity.Landlord, error)
-	DeleteLandlord(context.Context, uuid.UUID) error
-	TotalLandlordCount(context.Context) (int64, error)
+	DeleteLandlord(context.Context, uuid.UUID, int) error
+	TotalLandlordCount(context.Context, []string) (int64, error)
 	CreatePropertyInfo(
 		context.Context,
 		uuid.UUID,
+		int,
 		psql.PropertyInfoParam,
 	) (*entity.PropertyInfo, error)
-	GetAllLandlords(context.Context) ([]*entity.LandlordOut, error)
+	GetAllLandlords(context.Context, []string) ([]*entity.LandlordOut, error)
+	FindEntityHistory(
+		context.Context,
+		string,
+		uuid.UUID,
+		psql.PaginationParam,
+	) ([]*entity.AuditEntry, error)
 }
 
 type Service struct {
@@ -112,6 +121,9 @@ func (s *Service) create(
 	user *entity.User,
 	in entity.LandlordIn,
 ) (*entity.Landlord, error) {
+	ctx, span := tracing.Start(ctx, "landlord.Service.create")
+	defer span.End()
+
 	ctx, cancel := context.WithTimeout(ctx, s.timeout)
 	defer cancel()
 
@@ -143,7 +155,14 @@ func (s *Service) create(
 	return landlord, nil
 }
 
-func (s *Service) findOne(ctx context.Context, id uuid.UUID) (*entity.Landlord, error) {
+func (s *Service) findOne(
+	ctx context.Context,
+	visibility *entity.Visibility,
+	id uuid.UUID,
+) (*entity.Landlord, error) {
+	ctx, span := tracing.Start(ctx, "landlord.Service.findOne")
+	defer span.End()
+
 	ctx, cancel := context.WithTimeout(ctx, s.timeout)
 	defer cancel()
 
@@ -157,6 +176,10 @@ func (s *Service) findOne(ctx context.Context, id uuid.UUID) (*entity.Landlord,
 		return nil, err
 	}
 
+	if !visibility.Allows(landlord.Owner()) {
+		return nil, ErrNotFound
+	}
+
 	return landlord, nil
 }
 
@@ -168,6 +191,7 @@ type FilterParam struct {
 	address,
 	firstName,
 	phone string
+	owners []string
 }
 
 func (f FilterParam) Address() string {
@@ -182,11 +206,18 @@ func (f FilterParam) Phone() string {
 	return f.phone
 }
 
+func (f FilterParam) Owners() []string {
+	return f.owners
+}
+
 func (s *Service) findAll(
 	ctx context.Context,
 	filterParam FilterParam,
 	paginator *handlerlib.Paginator,
 ) ([]*entity.LandlordOut, error) {
+	ctx, span := tracing.Start(ctx, "landlord.Service.findAll")
+	defer span.End()
+
 	ctx, cancel := context.WithTimeout(ctx, s.timeout)
 	defer cancel()
 
@@ -198,16 +229,43 @@ func (s *Service) findAll(
 	return landlords, nil
 }
 
-func (s *Service) deleteOne(ctx context.Context, id uuid.UUID) error {
+func (s *Service) deleteOne(
+	ctx context.Context,
+	visibility *entity.Visibility,
+	id uuid.UUID,
+	ifMatch []string,
+) error {
+	ctx, span := tracing.Start(ctx, "landlord.Service.deleteOne")
+	defer span.End()
+
+	landlord, err := s.findOne(ctx, visibility, id)
+	if err != nil {
+		return err
+	}
+
+	if !handlerlib.MatchETag(ifMatch, landlord.ETag()) {
+		return handlerlib.ErrPreconditionFailed
+	}
+
 	ctx, cancel := context.WithTimeout(ctx, s.timeout)
 	defer cancel()
-	return s.store.DeleteLandlord(ctx, id)
+
+	err = s.store.DeleteLandlord(ctx, id, landlord.Version)
+
+	if err != nil && errors.Is(err, repository.ErrNotFound) {
+		return ErrNotFound
+	}
+
+	return err
 }
 
-func (s *Service) total(ctx context.Context) (int64, error) {
+func (s *Service) total(ctx context.Context, visibility *entity.Visibility) (int64, error) {
+	ctx, span := tracing.Start(ctx, "landlord.Service.total")
+	defer span.End()
+
 	ctx, cancel := context.WithTimeout(ctx, s.timeout)
 	defer cancel()
-	return s.store.TotalLandlordCount(ctx)
+	return s.store.TotalLandlordCount(ctx, visibility.Owners())
 }
 
 type PropertyInfoParam struct {
@@ -251,9 +309,23 @@ func (param PropertyInfoParam) AdditionalInfo() []byte {
 
 func (s *Service) createPropertyInfo(
 	ctx context.Context,
+	visibility *entity.Visibility,
 	id uuid.UUID,
+	ifMatch []string,
 	in entity.PropertyInfoIn,
-) (*entity.PropertyInfo, error) {
+) (*entity.PropertyInfo, string, error) {
+	ctx, span := tracing.Start(ctx, "landlord.Service.createPropertyInfo")
+	defer span.End()
+
+	landlord, err := s.findOne(ctx, visibility, id)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if !handlerlib.MatchETag(ifMatch, landlord.ETag()) {
+		return nil, "", handlerlib.ErrPreconditionFailed
+	}
+
 	ctx, cancel := context.WithTimeout(ctx, s.timeout)
 	defer cancel()
 
@@ -267,16 +339,49 @@ func (s *Service) createPropertyInfo(
 		additionalInfo: in.AdditionalInfo,
 	}
 
-	info, err := s.store.CreatePropertyInfo(ctx, id, param)
+	info, err := s.store.CreatePropertyInfo(ctx, id, landlord.Version, param)
+
+	if err != nil && errors.Is(err, repository.ErrNotFound) {
+		return nil, "", ErrNotFound
+	}
+
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
 
-	return info, nil
+	landlord.Version++
+
+	return info, landlord.ETag(), nil
 }
 
-func (s *Service) getAll(ctx context.Context) ([]*entity.LandlordOut, error) {
+func (s *Service) getAll(
+	ctx context.Context,
+	visibility *entity.Visibility,
+) ([]*entity.LandlordOut, error) {
+	ctx, span := tracing.Start(ctx, "landlord.Service.getAll")
+	defer span.End()
+
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+	return s.store.GetAllLandlords(ctx, visibility.Owners())
+}
+
+func (s *Service) history(
+	ctx context.Context,
+	visibility *entity.Visibility,
+	id uuid.UUID,
+	paginator *handlerlib.Paginator,
+) ([]*entity.AuditEntry, error) {
+	ctx, span := tracing.Start(ctx, "landlord.Service.history")
+	defer span.End()
+
+	if !visibility.All {
+		if _, err := s.findOne(ctx, visibility, id); err != nil {
+			return nil, err
+		}
+	}
+
 	ctx, cancel := context.WithTimeout(ctx, s.timeout)
 	defer cancel()
-	return s.store.GetAllLandlords(ctx)
+	return s.store.FindEntityHistory(ctx, entity.AuditLandlord, id, paginator)
 }
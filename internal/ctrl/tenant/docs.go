@@ -0,0 +1,61 @@
+package tenant
+
+import (
+	"github.com/emma769/a-realtor/internal/entity"
+	"github.com/emma769/a-realtor/internal/export"
+	"github.com/emma769/a-realtor/internal/openapi"
+)
+
+func (ctrl Ctrl) Docs(d *openapi.Group) {
+	read := d.With(openapi.Scope(entity.ScopeTenantsRead))
+	write := d.With(openapi.Scope(entity.ScopeTenantsWrite))
+	reports := d.With(openapi.Scope(entity.ScopeReportsRead))
+
+	write.Post("/", openapi.Operation{
+		Summary:  "Register a tenant with their first tenancy",
+		Request:  entity.TenantIn{},
+		Response: entity.Tenant{},
+		Status:   201,
+	})
+	read.Get("/", openapi.Operation{
+		Summary:   "List tenants",
+		Response:  entity.TenantOut{},
+		Paginated: true,
+		Query: []openapi.Param{
+			{Name: "first_name", Description: "exact match, case insensitive"},
+			{Name: "phone", Description: "exact match"},
+			{Name: "address", Description: "full text search on rented address"},
+		},
+	})
+	read.Get("/{id}", openapi.Operation{
+		Summary:     "Get a tenant with their rent records",
+		Response:    entity.Tenant{},
+		Conditional: true,
+	})
+	read.Get("/{id}/history", openapi.Operation{
+		Summary:   "List audit entries for a tenant",
+		Response:  entity.AuditEntry{},
+		Paginated: true,
+	})
+	write.Delete("/{id}", openapi.Operation{
+		Summary:     "Delete a tenant",
+		Status:      204,
+		Conditional: true,
+	})
+	reports.Get("/count", openapi.Operation{
+		Summary: "Count visible tenants",
+		Response: struct {
+			Total int64 `json:"total"`
+		}{},
+	})
+	write.Put("/{id}/info", openapi.Operation{
+		Summary:     "Add a rent record to a tenant",
+		Request:     entity.RentInfoIn{},
+		Response:    entity.RentInfo{},
+		Conditional: true,
+	})
+	reports.Get("/xlsx", openapi.Operation{
+		Summary:     "Export visible tenants as a spreadsheet",
+		ContentType: export.ContentTypeXlsx,
+	})
+}
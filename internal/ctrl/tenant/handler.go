@@ -1,8 +1,6 @@
 package tenant
 
 import (
-	"errors"
-	"fmt"
 	"log/slog"
 	"net/http"
 	"os"
@@ -10,11 +8,11 @@ import (
 
 	"github.com/go-chi/chi/v5"
 	"github.com/google/uuid"
-	"github.com/xuri/excelize/v2"
 
 	"github.com/emma769/a-realtor/internal/entity"
-	funclib "github.com/emma769/a-realtor/internal/lib/func"
+	"github.com/emma769/a-realtor/internal/export"
 	handlerlib "github.com/emma769/a-realtor/internal/lib/handler"
+	loglib "github.com/emma769/a-realtor/internal/lib/log"
 	"github.com/emma769/a-realtor/internal/middleware"
 	"github.com/emma769/a-realtor/internal/validator"
 )
@@ -38,13 +36,18 @@ func New(store storer, logger *slog.Logger) *Ctrl {
 
 func (ctrl Ctrl) Routes(r chi.Router) {
 	r.With(middleware.RequireAuth).Group(func(r chi.Router) {
-		r.Post("/", ctrl.createTenant())
-		r.Get("/", ctrl.findTenants())
-		r.Get("/{id}", ctrl.findTenant())
-		r.Get("/count", ctrl.tenantTotal())
-		r.Delete("/{id}", ctrl.deleteTenant())
-		r.Put("/{id}/info", ctrl.addRentInfo())
-		r.Get("/xlsx", ctrl.tenantXlsx())
+		read := r.With(middleware.RequireScope(entity.ScopeTenantsRead))
+		write := r.With(middleware.RequireScope(entity.ScopeTenantsWrite))
+		reports := r.With(middleware.RequireScope(entity.ScopeReportsRead))
+
+		write.Post("/", ctrl.createTenant())
+		read.Get("/", ctrl.findTenants())
+		read.Get("/{id}", ctrl.findTenant())
+		read.Get("/{id}/history", ctrl.tenantHistory())
+		reports.Get("/count", ctrl.tenantTotal())
+		write.Delete("/{id}", ctrl.deleteTenant())
+		write.Put("/{id}/info", ctrl.addRentInfo())
+		reports.Get("/xlsx", ctrl.tenantXlsx())
 	})
 }
 
@@ -52,26 +55,21 @@ func (ctrl *Ctrl) createTenant() http.HandlerFunc {
 	return handlerlib.Wrap(func(w http.ResponseWriter, r *http.Request) error {
 		in, err := handlerlib.Bind[entity.TenantIn](w, r)
 		if err != nil {
-			return handlerlib.NewError(422, err.Error())
+			return err
 		}
 
 		v := validator.New()
 
 		if entity.ValidateTenantIn(v, in); !v.Valid() {
-			return handlerlib.WriteJson(w, 422, v.Err())
+			return handlerlib.ValidationError(v.Err())
 		}
 
 		tenant, err := ctrl.create(r.Context(), handlerlib.GetCtxUser(r), in)
-
-		if err != nil && errors.Is(err, ErrDuplicateKey) {
-			return handlerlib.NewError(409, "phone already in use")
-		}
-
 		if err != nil {
 			return err
 		}
 
-		return handlerlib.WriteJson(w, 201, tenant)
+		return handlerlib.WriteJsonETag(w, r, 201, tenant.ETag(), tenant)
 	})
 }
 
@@ -81,6 +79,7 @@ func (ctrl *Ctrl) findTenants() http.HandlerFunc {
 			firstName: handlerlib.GetQuery(r, "first_name", ""),
 			phone:     handlerlib.GetQuery(r, "phone", ""),
 			address:   handlerlib.GetQuery(r, "address", ""),
+			owners:    handlerlib.GetCtxVisibility(r).Owners(),
 		}
 
 		paginator := handlerlib.NewPaginator(
@@ -93,7 +92,7 @@ func (ctrl *Ctrl) findTenants() http.HandlerFunc {
 			return err
 		}
 
-		return handlerlib.WriteJson(w, 200, map[string]any{
+		return handlerlib.WriteJsonWeak(w, r, 200, map[string]any{
 			"data":     tenants,
 			"metadata": paginator.GetMetadata(),
 		})
@@ -104,26 +103,45 @@ func (ctrl *Ctrl) findTenant() http.HandlerFunc {
 	return handlerlib.Wrap(func(w http.ResponseWriter, r *http.Request) error {
 		id, err := uuid.Parse(chi.URLParam(r, "id"))
 		if err != nil {
-			return handlerlib.NewError(400, "invalid tenant id")
+			return ErrInvalidID
+		}
+
+		tenant, err := ctrl.findone(r.Context(), handlerlib.GetCtxVisibility(r), id)
+		if err != nil {
+			return err
 		}
 
-		tenant, err := ctrl.findone(r.Context(), id)
+		return handlerlib.WriteJsonETag(w, r, 200, tenant.ETag(), tenant)
+	})
+}
 
-		if err != nil && errors.Is(err, ErrNotFound) {
-			return handlerlib.NewError(404, "tenant not found")
+func (ctrl *Ctrl) tenantHistory() http.HandlerFunc {
+	return handlerlib.Wrap(func(w http.ResponseWriter, r *http.Request) error {
+		id, err := uuid.Parse(chi.URLParam(r, "id"))
+		if err != nil {
+			return ErrInvalidID
 		}
 
+		paginator := handlerlib.NewPaginator(
+			handlerlib.GetQueryInt(r, "page", 1),
+			handlerlib.GetQueryInt(r, "page_size", 20),
+		)
+
+		entries, err := ctrl.history(r.Context(), handlerlib.GetCtxVisibility(r), id, paginator)
 		if err != nil {
 			return err
 		}
 
-		return handlerlib.WriteJson(w, 200, tenant)
+		return handlerlib.WriteJson(w, 200, map[string]any{
+			"metadata": paginator.GetMetadata(),
+			"data":     entries,
+		})
 	})
 }
 
 func (ctrl *Ctrl) tenantTotal() http.HandlerFunc {
 	return handlerlib.Wrap(func(w http.ResponseWriter, r *http.Request) error {
-		total, err := ctrl.total(r.Context())
+		total, err := ctrl.total(r.Context(), handlerlib.GetCtxVisibility(r))
 		if err != nil {
 			return err
 		}
@@ -138,10 +156,16 @@ func (ctrl *Ctrl) deleteTenant() http.HandlerFunc {
 	return handlerlib.Wrap(func(w http.ResponseWriter, r *http.Request) error {
 		id, err := uuid.Parse(chi.URLParam(r, "id"))
 		if err != nil {
-			return handlerlib.NewError(400, "invalid tenant id")
+			return ErrInvalidID
+		}
+
+		ifMatch, err := handlerlib.IfMatch(r)
+		if err != nil {
+			return err
 		}
 
-		if err := ctrl.delete(r.Context(), id); err != nil {
+		err = ctrl.delete(r.Context(), handlerlib.GetCtxVisibility(r), id, ifMatch)
+		if err != nil {
 			return err
 		}
 
@@ -153,87 +177,57 @@ func (ctrl *Ctrl) addRentInfo() http.HandlerFunc {
 	return handlerlib.Wrap(func(w http.ResponseWriter, r *http.Request) error {
 		id, err := uuid.Parse(chi.URLParam(r, "id"))
 		if err != nil {
-			return handlerlib.NewError(400, "invalid tenant id")
+			return ErrInvalidID
+		}
+
+		ifMatch, err := handlerlib.IfMatch(r)
+		if err != nil {
+			return err
 		}
 
 		in, err := handlerlib.Bind[entity.RentInfoIn](w, r)
 		if err != nil {
-			return handlerlib.NewError(422, err.Error())
+			return err
 		}
 
 		v := validator.New()
 
 		if entity.ValidateRentInfoIn(v, in); !v.Valid() {
-			return handlerlib.WriteJson(w, 422, v.Err())
+			return handlerlib.ValidationError(v.Err())
 		}
 
-		info, err := ctrl.createRentInfo(r.Context(), id, in)
+		info, etag, err := ctrl.createRentInfo(
+			r.Context(),
+			handlerlib.GetCtxVisibility(r),
+			id,
+			ifMatch,
+			in,
+		)
 		if err != nil {
 			return err
 		}
 
+		w.Header().Set("ETag", etag)
+
 		return handlerlib.WriteJson(w, 200, info)
 	})
 }
 
 func (ctrl *Ctrl) tenantXlsx() http.HandlerFunc {
 	return handlerlib.Wrap(func(w http.ResponseWriter, r *http.Request) error {
-		tenants, err := ctrl.getAll(r.Context())
+		tenants, err := ctrl.getAll(r.Context(), handlerlib.GetCtxVisibility(r))
 		if err != nil {
 			return err
 		}
 
-		file := excelize.NewFile()
-
-		headers := []string{
-			"Firstname",
-			"Lastname",
-			"Phone",
-			"Rent Amount",
-			"Address",
-			"Landlord/Investor",
-			"Landlord/Investor Phone",
-			"Duration",
-			"Start Date",
-			"Maturity Date",
-			"Renewal Date",
-		}
-
-		for i, header := range headers {
-			file.SetCellValue("Sheet1", fmt.Sprintf("%s%d", string(rune(65+i)), 1), header)
+		findLandlord := func(id uuid.UUID) *entity.Landlord {
+			landlord, _ := ctrl.getLandlord(r.Context(), id)
+			return landlord
 		}
 
-		data := make([][]any, len(tenants))
-
-		for i, tenant := range tenants {
-			landlord, _ := ctrl.getLandlord(r.Context(), tenant.LandlordID)
-
-			var landlordName string
-
-			if landlord != nil {
-				landlordName = fmt.Sprintf("%s %s", landlord.FirstName, landlord.LastName)
-			}
-
-			data[i] = []any{
-				tenant.FirstName,
-				tenant.LastName,
-				tenant.Phone,
-				tenant.RentFee,
-				tenant.Address,
-				landlordName,
-				landlord.Phone,
-				funclib.DaysBetween(tenant.StartDate, tenant.RenewalDate),
-				tenant.StartDate.Format("02/01/2006"),
-				tenant.MaturityDate.Format("02/01/2006"),
-				tenant.RenewalDate.Format("02/01/2006"),
-			}
-		}
-
-		for i, row := range data {
-			dr := i + 2
-			for j, col := range row {
-				file.SetCellValue("Sheet1", fmt.Sprintf("%s%d", string(rune(65+j)), dr), col)
-			}
+		file, err := export.Tenants(tenants, findLandlord).Xlsx()
+		if err != nil {
+			return err
 		}
 
 		temp, err := os.CreateTemp("", "tenants.xlsx")
@@ -243,7 +237,7 @@ func (ctrl *Ctrl) tenantXlsx() http.HandlerFunc {
 
 		defer func() {
 			if err := os.Remove(temp.Name()); err != nil {
-				ctrl.logger.ErrorContext(
+				loglib.From(r.Context(), ctrl.logger).ErrorContext(
 					r.Context(),
 					"could not remove temp file",
 					"detail",
@@ -254,7 +248,7 @@ func (ctrl *Ctrl) tenantXlsx() http.HandlerFunc {
 
 		defer func() {
 			if err := temp.Close(); err != nil {
-				ctrl.logger.ErrorContext(
+				loglib.From(r.Context(), ctrl.logger).ErrorContext(
 					r.Context(),
 					"could not close temp file",
 					"detail",
@@ -267,8 +261,7 @@ func (ctrl *Ctrl) tenantXlsx() http.HandlerFunc {
 			return err
 		}
 
-		w.Header().
-			Set("Content-Type", "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet")
+		w.Header().Set("Content-Type", export.ContentTypeXlsx)
 		w.Header().Set("Content-Disposition", "attachment; filename="+temp.Name())
 
 		http.ServeFile(w, r, temp.Name())
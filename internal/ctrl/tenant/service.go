@@ -12,11 +12,13 @@ import (
 	handlerlib "github.com/emma769/a-realtor/internal/lib/handler"
 	"github.com/emma769/a-realtor/internal/repository"
 	"github.com/emma769/a-realtor/internal/repository/psql"
+	"github.com/emma769/a-realtor/internal/tracing"
 )
 
 var (
-	ErrDuplicateKey = errors.New("duplicate key")
-	ErrNotFound     = errors.New("not found")
+	ErrInvalidID    = handlerlib.NewError(400, "tenant.invalid_id", "invalid tenant id")
+	ErrNotFound     = handlerlib.NewError(404, "tenant.not_found", "tenant not found")
+	ErrDuplicateKey = handlerlib.NewError(409, "tenant.phone_taken", "phone already in use")
 )
 
 type storer interface {
@@ -27,11 +29,22 @@ type storer interface {
 		psql.PaginationParam,
 	) ([]*entity.TenantOut, error)
 	FindTenant(context.Context, uuid.UUID) (*entity.Tenant, error)
-	TenantTotalCount(context.Context) (int64, error)
-	DeleteTenant(context.Context, uuid.UUID) error
-	CreateRentInfo(context.Context, uuid.UUID, psql.RentInfoParam) (*entity.RentInfo, error)
-	FindAllTenants(context.Context) ([]*entity.TenantOut, error)
+	TenantTotalCount(context.Context, []string) (int64, error)
+	DeleteTenant(context.Context, uuid.UUID, int) error
+	CreateRentInfo(
+		context.Context,
+		uuid.UUID,
+		int,
+		psql.RentInfoParam,
+	) (*entity.RentInfo, error)
+	FindAllTenants(context.Context, []string) ([]*entity.TenantOut, error)
 	FindLandlord(context.Context, uuid.UUID) (*entity.Landlord, error)
+	FindEntityHistory(
+		context.Context,
+		string,
+		uuid.UUID,
+		psql.PaginationParam,
+	) ([]*entity.AuditEntry, error)
 }
 
 type Service struct {
@@ -138,6 +151,9 @@ func (s *Service) create(
 	user *entity.User,
 	in entity.TenantIn,
 ) (*entity.Tenant, error) {
+	ctx, span := tracing.Start(ctx, "tenant.Service.create")
+	defer span.End()
+
 	ctx, cancel := context.WithTimeout(ctx, s.timeout)
 	defer cancel()
 
@@ -179,6 +195,7 @@ type FilterParam struct {
 	firstName,
 	phone,
 	address string
+	owners []string
 }
 
 func (f FilterParam) FirstName() string {
@@ -193,11 +210,18 @@ func (f FilterParam) Address() string {
 	return f.address
 }
 
+func (f FilterParam) Owners() []string {
+	return f.owners
+}
+
 func (s *Service) findall(
 	ctx context.Context,
 	filterParam *FilterParam,
 	paginator *handlerlib.Paginator,
 ) ([]*entity.TenantOut, error) {
+	ctx, span := tracing.Start(ctx, "tenant.Service.findall")
+	defer span.End()
+
 	ctx, cancel := context.WithTimeout(ctx, s.timeout)
 	defer cancel()
 
@@ -209,7 +233,14 @@ func (s *Service) findall(
 	return tenants, nil
 }
 
-func (s *Service) findone(ctx context.Context, id uuid.UUID) (*entity.Tenant, error) {
+func (s *Service) findone(
+	ctx context.Context,
+	visibility *entity.Visibility,
+	id uuid.UUID,
+) (*entity.Tenant, error) {
+	ctx, span := tracing.Start(ctx, "tenant.Service.findone")
+	defer span.End()
+
 	ctx, cancel := context.WithTimeout(ctx, s.timeout)
 	defer cancel()
 
@@ -223,19 +254,50 @@ func (s *Service) findone(ctx context.Context, id uuid.UUID) (*entity.Tenant, er
 		return nil, err
 	}
 
+	if !visibility.Allows(tenant.Owner()) {
+		return nil, ErrNotFound
+	}
+
 	return tenant, nil
 }
 
-func (s *Service) total(ctx context.Context) (int64, error) {
+func (s *Service) total(ctx context.Context, visibility *entity.Visibility) (int64, error) {
+	ctx, span := tracing.Start(ctx, "tenant.Service.total")
+	defer span.End()
+
 	ctx, cancel := context.WithTimeout(ctx, s.timeout)
 	defer cancel()
-	return s.store.TenantTotalCount(ctx)
+	return s.store.TenantTotalCount(ctx, visibility.Owners())
 }
 
-func (s *Service) delete(ctx context.Context, id uuid.UUID) error {
+func (s *Service) delete(
+	ctx context.Context,
+	visibility *entity.Visibility,
+	id uuid.UUID,
+	ifMatch []string,
+) error {
+	ctx, span := tracing.Start(ctx, "tenant.Service.delete")
+	defer span.End()
+
+	tenant, err := s.findone(ctx, visibility, id)
+	if err != nil {
+		return err
+	}
+
+	if !handlerlib.MatchETag(ifMatch, tenant.ETag()) {
+		return handlerlib.ErrPreconditionFailed
+	}
+
 	ctx, cancel := context.WithTimeout(ctx, s.timeout)
 	defer cancel()
-	return s.store.DeleteTenant(ctx, id)
+
+	err = s.store.DeleteTenant(ctx, id, tenant.Version)
+
+	if err != nil && errors.Is(err, repository.ErrNotFound) {
+		return ErrNotFound
+	}
+
+	return err
 }
 
 type RentInfoParam struct {
@@ -273,9 +335,23 @@ func (param RentInfoParam) LandlordID() uuid.UUID {
 
 func (s *Service) createRentInfo(
 	ctx context.Context,
+	visibility *entity.Visibility,
 	id uuid.UUID,
+	ifMatch []string,
 	in entity.RentInfoIn,
-) (*entity.RentInfo, error) {
+) (*entity.RentInfo, string, error) {
+	ctx, span := tracing.Start(ctx, "tenant.Service.createRentInfo")
+	defer span.End()
+
+	tenant, err := s.findone(ctx, visibility, id)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if !handlerlib.MatchETag(ifMatch, tenant.ETag()) {
+		return nil, "", handlerlib.ErrPreconditionFailed
+	}
+
 	ctx, cancel := context.WithTimeout(ctx, s.timeout)
 	defer cancel()
 
@@ -288,17 +364,58 @@ func (s *Service) createRentInfo(
 		rentFee:      in.RentFee,
 	}
 
-	return s.store.CreateRentInfo(ctx, id, param)
+	info, err := s.store.CreateRentInfo(ctx, id, tenant.Version, param)
+
+	if err != nil && errors.Is(err, repository.ErrNotFound) {
+		return nil, "", ErrNotFound
+	}
+
+	if err != nil {
+		return nil, "", err
+	}
+
+	tenant.Version++
+
+	return info, tenant.ETag(), nil
 }
 
-func (s *Service) getAll(ctx context.Context) ([]*entity.TenantOut, error) {
+func (s *Service) getAll(
+	ctx context.Context,
+	visibility *entity.Visibility,
+) ([]*entity.TenantOut, error) {
+	ctx, span := tracing.Start(ctx, "tenant.Service.getAll")
+	defer span.End()
+
 	ctx, cancel := context.WithTimeout(ctx, s.timeout)
 	defer cancel()
-	return s.store.FindAllTenants(ctx)
+	return s.store.FindAllTenants(ctx, visibility.Owners())
 }
 
 func (s *Service) getLandlord(ctx context.Context, id uuid.UUID) (*entity.Landlord, error) {
+	ctx, span := tracing.Start(ctx, "tenant.Service.getLandlord")
+	defer span.End()
+
 	ctx, cancel := context.WithTimeout(ctx, s.timeout)
 	defer cancel()
 	return s.store.FindLandlord(ctx, id)
 }
+
+func (s *Service) history(
+	ctx context.Context,
+	visibility *entity.Visibility,
+	id uuid.UUID,
+	paginator *handlerlib.Paginator,
+) ([]*entity.AuditEntry, error) {
+	ctx, span := tracing.Start(ctx, "tenant.Service.history")
+	defer span.End()
+
+	if !visibility.All {
+		if _, err := s.findone(ctx, visibility, id); err != nil {
+			return nil, err
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+	return s.store.FindEntityHistory(ctx, entity.AuditTenant, id, paginator)
+}
@@ -0,0 +1,85 @@
+package user
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+	"time"
+
+	handlerlib "github.com/emma769/a-realtor/internal/lib/handler"
+	"github.com/emma769/a-realtor/internal/token"
+)
+
+const (
+	refreshCookie = "refresh_token"
+	csrfCookie    = "csrf_token"
+	csrfHeader    = "X-CSRF-Token"
+	cookiePath    = "/api/auth"
+)
+
+func (ctrl *Ctrl) sameSite() http.SameSite {
+	switch strings.ToLower(ctrl.cfg.AuthCookieSite) {
+	case "none":
+		return http.SameSiteNoneMode
+	case "lax":
+		return http.SameSiteLaxMode
+	}
+	return http.SameSiteStrictMode
+}
+
+func (ctrl *Ctrl) cookie(name, value string, expires time.Time, httpOnly bool) *http.Cookie {
+	return &http.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     cookiePath,
+		Domain:   ctrl.cfg.AuthCookieDomain,
+		Expires:  expires,
+		MaxAge:   int(time.Until(expires).Seconds()),
+		Secure:   ctrl.cfg.AuthCookieSecure,
+		HttpOnly: httpOnly,
+		SameSite: ctrl.sameSite(),
+	}
+}
+
+func (ctrl *Ctrl) setAuthCookies(
+	w http.ResponseWriter,
+	refresh *token.RefreshToken,
+) (string, error) {
+	csrf, err := token.NewOpaque()
+	if err != nil {
+		return "", err
+	}
+
+	http.SetCookie(w, ctrl.cookie(refreshCookie, refresh.Token, refresh.ValidTill, true))
+	http.SetCookie(w, ctrl.cookie(csrfCookie, csrf.Plain, refresh.ValidTill, false))
+
+	return csrf.Plain, nil
+}
+
+func (ctrl *Ctrl) clearAuthCookies(w http.ResponseWriter) {
+	for _, name := range []string{refreshCookie, csrfCookie} {
+		c := ctrl.cookie(name, "", time.Unix(0, 0), name == refreshCookie)
+		c.MaxAge = -1
+		http.SetCookie(w, c)
+	}
+}
+
+func refreshFromCookie(r *http.Request) (string, error) {
+	refresh, err := r.Cookie(refreshCookie)
+	if err != nil {
+		return "", nil
+	}
+
+	csrf, err := r.Cookie(csrfCookie)
+	if err != nil {
+		return "", handlerlib.NewError(403, "auth.csrf_missing", "missing csrf token")
+	}
+
+	header := r.Header.Get(csrfHeader)
+
+	if header == "" || subtle.ConstantTimeCompare([]byte(header), []byte(csrf.Value)) != 1 {
+		return "", handlerlib.NewError(403, "auth.csrf_invalid", "invalid csrf token")
+	}
+
+	return refresh.Value, nil
+}
@@ -0,0 +1,142 @@
+package user
+
+import (
+	"net/http"
+
+	"github.com/emma769/a-realtor/internal/entity"
+	handlerlib "github.com/emma769/a-realtor/internal/lib/handler"
+	"github.com/emma769/a-realtor/internal/openapi"
+)
+
+func (ctrl Ctrl) Docs(d *openapi.Group) {
+	public := d.With(openapi.Public)
+	login := map[int]any{http.StatusOK: MFAChallenge{}}
+
+	public.Post("/register", openapi.Operation{
+		Summary:  "Register an account",
+		Request:  entity.UserIn{},
+		Response: entity.UserOut{},
+		Status:   201,
+	})
+	public.Post("/login", openapi.Operation{
+		Summary:     "Log in with email and password",
+		Description: "Accounts with two-factor enabled get a 200 challenge instead of tokens.",
+		Request:     entity.LoginIn{},
+		Response:    TokenPayload{},
+		Status:      201,
+		Responses:   login,
+	})
+	public.Post("/login/2fa", openapi.Operation{
+		Summary: "Complete a two-factor login challenge",
+		Description: "Invalid codes count as failed logins and lock the account like a wrong " +
+			"password; a locked login answers 429 with Retry-After.",
+		Request:  entity.MFALoginIn{},
+		Response: TokenPayload{},
+		Status:   201,
+	})
+	public.Post("/refresh", openapi.Operation{
+		Summary:     "Exchange a refresh token for a new access token",
+		Description: "In cookie mode the refresh token is read from the cookie and the body is ignored.",
+		Request:     RefreshTokenIn{},
+		Response:    TokenPayload{},
+	})
+	public.Post("/logout", openapi.Operation{
+		Summary: "End the current session",
+		Request: RefreshTokenIn{},
+		Status:  204,
+	})
+
+	if ctrl.cfg.OIDCIssuer != "" {
+		public.Get("/oidc/login", openapi.Operation{
+			Summary: "Start single sign-on with the identity provider",
+			Status:  302,
+		})
+		public.Get("/oidc/callback", openapi.Operation{
+			Summary:  "Finish single sign-on",
+			Response: TokenPayload{},
+			Status:   201,
+			Query: []openapi.Param{
+				{Name: "state"},
+				{Name: "code"},
+				{Name: "error"},
+				{Name: "error_description"},
+			},
+			Responses: login,
+		})
+	}
+
+	public.Post("/forgot-password", openapi.Operation{
+		Summary:  "Email a password reset link",
+		Request:  entity.ForgotPasswordIn{},
+		Response: handlerlib.RespMsg{},
+		Status:   202,
+	})
+	public.Post("/reset-password", openapi.Operation{
+		Summary:  "Reset a password with an emailed token",
+		Request:  entity.ResetPasswordIn{},
+		Response: handlerlib.RespMsg{},
+	})
+	public.Post("/verify-email", openapi.Operation{
+		Summary:  "Verify an email address with an emailed token",
+		Request:  entity.VerifyEmailIn{},
+		Response: handlerlib.RespMsg{},
+	})
+	public.Post("/confirm-email", openapi.Operation{
+		Summary:  "Confirm a pending email change with an emailed token",
+		Request:  entity.VerifyEmailIn{},
+		Response: handlerlib.RespMsg{},
+	})
+	public.Post("/accept-invite", openapi.Operation{
+		Summary:  "Accept an invite and set a password",
+		Request:  entity.AcceptInviteIn{},
+		Response: handlerlib.RespMsg{},
+	})
+	d.Get("/me", openapi.Operation{
+		Summary:  "Get the current user",
+		Response: entity.UserOut{},
+	})
+
+	session := d.With(openapi.Session)
+
+	session.Patch("/me", openapi.Operation{
+		Summary: "Update the current user's profile",
+		Description: "Changing the email requires currentPassword. The new address is kept as " +
+			"pendingEmail until confirmed through the link sent to it.",
+		Request:  entity.UpdateMeIn{},
+		Response: entity.UserOut{},
+	})
+	session.Post("/me/password", openapi.Operation{
+		Summary:     "Change password",
+		Description: "Existing sessions are revoked and a fresh token pair is issued.",
+		Request:     entity.ChangePasswordIn{},
+		Response:    TokenPayload{},
+		Status:      201,
+	})
+	session.Post("/verify-email/resend", openapi.Operation{
+		Summary:  "Resend the verification email",
+		Response: handlerlib.RespMsg{},
+		Status:   202,
+	})
+	session.Route("/2fa", func(d *openapi.Group) {
+		d.Post("/enroll", openapi.Operation{
+			Summary:  "Start two-factor enrolment",
+			Response: TOTPEnrolment{},
+			Status:   201,
+		})
+		d.Post("/confirm", openapi.Operation{
+			Summary:  "Confirm two-factor enrolment",
+			Request:  entity.TOTPCodeIn{},
+			Response: RecoveryCodes{},
+		})
+		d.Post("/disable", openapi.Operation{
+			Summary: "Disable two-factor authentication",
+			Request: entity.DisableTOTPIn{},
+			Status:  204,
+		})
+		d.Post("/recovery-codes", openapi.Operation{
+			Summary:  "Regenerate recovery codes",
+			Request:  entity.TOTPCodeIn{},
+			Response: RecoveryCodes{},
+		})
+	})
+}
@@ -2,16 +2,20 @@ package user
 
 import (
 	"errors"
+	"log/slog"
+	"math"
 	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/go-chi/chi/v5"
-	"golang.org/x/crypto/bcrypt"
 
 	"github.com/emma769/a-realtor/internal/config"
 	"github.com/emma769/a-realtor/internal/entity"
 	handlerlib "github.com/emma769/a-realtor/internal/lib/handler"
+	"github.com/emma769/a-realtor/internal/mailer"
 	"github.com/emma769/a-realtor/internal/middleware"
+	"github.com/emma769/a-realtor/internal/passhash"
 	"github.com/emma769/a-realtor/internal/token"
 	"github.com/emma769/a-realtor/internal/validator"
 )
@@ -24,13 +28,28 @@ type Ctrl struct {
 	mgr *token.Manager
 }
 
-func NewCtrl(store storer, cfg *config.Config, mgr *token.Manager) *Ctrl {
+func NewCtrl(
+	store storer,
+	cfg *config.Config,
+	mgr *token.Manager,
+	hasher passhash.Hasher,
+	sso ssoProvider,
+	mailer mailer.Mailer,
+	logger *slog.Logger,
+) *Ctrl {
 	return &Ctrl{
 		mgr: mgr,
 		cfg: cfg,
 		Service: &Service{
-			store,
-			timeout,
+			store:     store,
+			timeout:   timeout,
+			tokens:    mgr,
+			hasher:    hasher,
+			sso:       sso,
+			mailer:    mailer,
+			cfg:       cfg,
+			logger:    logger,
+			dummyHash: dummyHash(hasher),
 		},
 	}
 }
@@ -38,34 +57,72 @@ func NewCtrl(store storer, cfg *config.Config, mgr *token.Manager) *Ctrl {
 func (ctrl Ctrl) Routes(r chi.Router) {
 	r.Post("/register", ctrl.register())
 	r.Post("/login", ctrl.login())
+	r.Post("/login/2fa", ctrl.loginMFA())
 	r.Post("/refresh", ctrl.refresh())
+	r.Post("/logout", ctrl.logout())
+
+	if ctrl.cfg.OIDCIssuer != "" {
+		r.Get("/oidc/login", ctrl.ssoLogin())
+		r.Get("/oidc/callback", ctrl.ssoCallback())
+	}
+
+	r.Post("/forgot-password", ctrl.forgotPassword())
+	r.Post("/reset-password", ctrl.resetPassword())
+	r.Post("/verify-email", ctrl.verifyEmail())
+	r.Post("/confirm-email", ctrl.confirmEmail())
+	r.Post("/accept-invite", ctrl.acceptInvite())
 	r.With(middleware.RequireAuth).Get("/me", ctrl.getMe())
+	r.With(middleware.RequireAuth, middleware.RequireSession).Group(func(r chi.Router) {
+		r.Patch("/me", ctrl.updateMe())
+		r.Post("/me/password", ctrl.changePassword())
+		r.Post("/verify-email/resend", ctrl.resendVerification())
+		r.Route("/2fa", func(r chi.Router) {
+			r.Post("/enroll", ctrl.enrollTOTP())
+			r.Post("/confirm", ctrl.confirmTOTP())
+			r.Post("/disable", ctrl.disableTOTP())
+			r.Post("/recovery-codes", ctrl.regenerateRecoveryCodes())
+		})
+	})
 }
 
 func (ctrl *Ctrl) register() http.HandlerFunc {
 	return handlerlib.Wrap(func(w http.ResponseWriter, r *http.Request) error {
+		if ctrl.cfg.DisableRegistration {
+			return handlerlib.NewError(
+				403,
+				"user.registration_disabled",
+				"registration is disabled, ask an admin for an invite",
+			)
+		}
+
 		in, err := handlerlib.Bind[entity.UserIn](w, r)
 		if err != nil {
-			return handlerlib.NewError(422, err.Error())
+			return err
 		}
 
 		v := validator.New()
 
 		if entity.ValidateUserIn(v, in); !v.Valid() {
-			return handlerlib.WriteJson(w, 422, v.Err())
+			return handlerlib.ValidationError(v.Err())
 		}
 
 		user, err := ctrl.create(r.Context(), in)
-
-		if err != nil && errors.Is(err, ErrDuplicateEmail) {
-			return handlerlib.NewError(409, "email already in use")
-		}
-
 		if err != nil {
 			return err
 		}
 
-		return handlerlib.WriteJson(w, 201, user)
+		// The account exists by now, so a failed verification is logged rather than returned; a
+		// retry would only get 409 and the user can ask for a new link from /verify-email/resend.
+		if err := ctrl.sendVerification(r.Context(), user); err != nil {
+			ctrl.log(r.Context()).LogAttrs(
+				r.Context(),
+				slog.LevelError,
+				"could not issue email verification",
+				slog.String("detail", err.Error()),
+			)
+		}
+
+		return handlerlib.WriteJson(w, 201, entity.NewUserOut(user))
 	})
 }
 
@@ -81,85 +138,210 @@ type RefreshToken struct {
 type TokenPayload struct {
 	AccessToken  AccessToken   `json:"accessToken"`
 	RefreshToken *RefreshToken `json:"refreshToken,omitempty"`
+	CSRFToken    string        `json:"csrfToken,omitempty"`
 }
 
 func (ctrl *Ctrl) login() http.HandlerFunc {
 	return handlerlib.Wrap(func(w http.ResponseWriter, r *http.Request) error {
 		in, err := handlerlib.Bind[entity.LoginIn](w, r)
 		if err != nil {
-			return handlerlib.NewError(422, err.Error())
+			return err
 		}
 
 		v := validator.New()
 
 		if entity.ValidateLoginIn(v, in); !v.Valid() {
-			return handlerlib.WriteJson(w, 422, v.Err())
+			return handlerlib.ValidationError(v.Err())
 		}
 
-		user, err := ctrl.findByEmail(r.Context(), in.Email)
+		ip := handlerlib.ClientIP(r)
 
-		if err != nil && errors.Is(err, ErrNotFound) {
-			return handlerlib.NewError(401, "unauthorized")
+		attempt, wait, err := ctrl.reserveLogin(r.Context(), in.Email, ip)
+		if err != nil {
+			return err
+		}
+
+		if attempt == nil {
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+			return ErrTooManyAttempts
+		}
+
+		user, err := ctrl.authenticate(r.Context(), in)
+
+		if err != nil && errors.Is(err, ErrInvalidCredentials) {
+			if err := ctrl.loginFailed(r.Context(), attempt); err != nil {
+				return err
+			}
+
+			return err
+		}
+
+		if err != nil {
+			return errors.Join(err, ctrl.releaseLogin(r.Context(), attempt))
+		}
+
+		// With a second factor the failures are only reset once the code is accepted, so a
+		// leaked password does not buy unthrottled guesses at the code.
+		if user.IsActive() && user.HasTOTP() {
+			err = ctrl.releaseLogin(r.Context(), attempt)
+		} else {
+			err = ctrl.loginSucceeded(r.Context(), attempt)
 		}
 
 		if err != nil {
 			return err
 		}
 
-		err = bcrypt.CompareHashAndPassword(user.Password, []byte(in.Password))
+		return ctrl.completeLogin(w, r, user)
+	})
+}
+
+func (ctrl *Ctrl) completeLogin(w http.ResponseWriter, r *http.Request, user *entity.User) error {
+	if !user.IsActive() {
+		return ErrDeactivated
+	}
 
-		if err != nil && errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
-			return handlerlib.NewError(401, "unauthorized")
+	if user.HasTOTP() {
+		challenge, err := ctrl.createMFAChallenge(r.Context(), user)
+		if err != nil {
+			return err
 		}
 
+		return handlerlib.WriteJsonNoStore(w, 200, MFAChallenge{
+			ChallengeToken: challenge,
+			Method:         "totp",
+			ExpiresIn:      int(ctrl.cfg.MFAChallengeExpire.Seconds()),
+		})
+	}
+
+	return ctrl.writeTokenPair(w, r, user)
+}
+
+type MFAChallenge struct {
+	ChallengeToken string `json:"challengeToken"`
+	Method         string `json:"method"`
+	ExpiresIn      int    `json:"expiresIn"`
+}
+
+func (ctrl *Ctrl) loginMFA() http.HandlerFunc {
+	return handlerlib.Wrap(func(w http.ResponseWriter, r *http.Request) error {
+		in, err := handlerlib.Bind[entity.MFALoginIn](w, r)
 		if err != nil {
 			return err
 		}
 
-		pair, err := ctrl.mgr.GetTokenPair(r.Context(), user.UserID)
+		v := validator.New()
+
+		if entity.ValidateMFALoginIn(v, in); !v.Valid() {
+			return handlerlib.ValidationError(v.Err())
+		}
+
+		user, wait, err := ctrl.completeMFALogin(r.Context(), in, handlerlib.ClientIP(r))
+
+		if err != nil && errors.Is(err, ErrTooManyAttempts) {
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+			return err
+		}
+
+		if err != nil && errors.Is(err, ErrInvalidToken) {
+			return handlerlib.NewError(401, "auth.challenge_expired", "challenge expired, login again")
+		}
+
+		if err != nil && errors.Is(err, ErrInvalidCode) {
+			return handlerlib.NewError(
+				401,
+				"totp.invalid_code",
+				"invalid two-factor code, login again",
+			)
+		}
+
 		if err != nil {
 			return err
 		}
 
-		payload := TokenPayload{
-			AccessToken: AccessToken{
-				Value: pair.Access.Raw,
-				Type:  "Bearer",
-			},
-			RefreshToken: &RefreshToken{
-				Value: pair.Refresh.Token,
-			},
+		if !user.IsActive() {
+			return ErrDeactivated
 		}
 
-		return handlerlib.WriteJson(w, 201, payload)
+		return ctrl.writeTokenPair(w, r, user)
 	})
 }
 
+func (ctrl *Ctrl) writeTokenPair(w http.ResponseWriter, r *http.Request, user *entity.User) error {
+	pair, err := ctrl.mgr.GetTokenPair(r.Context(), user.UserID)
+	if err != nil {
+		return err
+	}
+
+	payload := TokenPayload{
+		AccessToken: AccessToken{
+			Value: pair.Access.Raw,
+			Type:  "Bearer",
+		},
+	}
+
+	if !ctrl.cfg.AuthCookieMode {
+		payload.RefreshToken = &RefreshToken{
+			Value: pair.Refresh.Token,
+		}
+
+		return handlerlib.WriteJsonNoStore(w, 201, payload)
+	}
+
+	payload.CSRFToken, err = ctrl.setAuthCookies(w, pair.Refresh)
+	if err != nil {
+		return err
+	}
+
+	return handlerlib.WriteJsonNoStore(w, 201, payload)
+}
+
 type RefreshTokenIn struct {
 	RefreshToken string `json:"refreshToken"`
 }
 
+func (ctrl *Ctrl) refreshToken(w http.ResponseWriter, r *http.Request) (string, error) {
+	plain, err := refreshFromCookie(r)
+	if err != nil || plain != "" {
+		return plain, err
+	}
+
+	in, err := handlerlib.Bind[RefreshTokenIn](w, r)
+	if err != nil {
+		return "", err
+	}
+
+	if in.RefreshToken == "" {
+		return "", handlerlib.ValidationError(map[string]string{
+			"refreshToken": "cannot be blank",
+		})
+	}
+
+	return in.RefreshToken, nil
+}
+
 func (ctrl *Ctrl) refresh() http.HandlerFunc {
 	return handlerlib.Wrap(func(w http.ResponseWriter, r *http.Request) error {
-		in, err := handlerlib.Bind[RefreshTokenIn](w, r)
+		plain, err := ctrl.refreshToken(w, r)
 		if err != nil {
-			return handlerlib.NewError(422, err.Error())
-		}
-
-		if in.RefreshToken == "" {
-			return handlerlib.NewError(422, "refreshToken cannot be blank")
+			return err
 		}
 
-		user, err := ctrl.findBySession(r.Context(), in.RefreshToken)
+		user, err := ctrl.findBySession(r.Context(), plain)
 
 		if err != nil && errors.Is(err, ErrNotFound) {
-			return handlerlib.NewError(403, "not logged in, login for access")
+			ctrl.clearAuthCookies(w)
+			return handlerlib.NewError(403, "auth.not_logged_in", "not logged in, login for access")
 		}
 
 		if err != nil {
 			return err
 		}
 
+		if !user.IsActive() {
+			return ErrDeactivated
+		}
+
 		t, err := ctrl.mgr.GetAccessToken(user.UserID)
 		if err != nil {
 			return err
@@ -172,12 +354,308 @@ func (ctrl *Ctrl) refresh() http.HandlerFunc {
 			},
 		}
 
-		return handlerlib.WriteJson(w, 200, payload)
+		return handlerlib.WriteJsonNoStore(w, 200, payload)
+	})
+}
+
+func (ctrl *Ctrl) logout() http.HandlerFunc {
+	return handlerlib.Wrap(func(w http.ResponseWriter, r *http.Request) error {
+		plain, err := ctrl.refreshToken(w, r)
+		if err != nil {
+			return err
+		}
+
+		if err := ctrl.Service.logout(r.Context(), plain); err != nil {
+			return err
+		}
+
+		ctrl.clearAuthCookies(w)
+
+		return handlerlib.SendStatus(w, 204)
 	})
 }
 
 func (ctrl *Ctrl) getMe() http.HandlerFunc {
 	return handlerlib.Wrap(func(w http.ResponseWriter, r *http.Request) error {
-		return handlerlib.WriteJson(w, 200, handlerlib.GetCtxUser(r))
+		return handlerlib.WriteJson(w, 200, entity.NewUserOut(handlerlib.GetCtxUser(r)))
+	})
+}
+
+func (ctrl *Ctrl) updateMe() http.HandlerFunc {
+	return handlerlib.Wrap(func(w http.ResponseWriter, r *http.Request) error {
+		in, err := handlerlib.Bind[entity.UpdateMeIn](w, r)
+		if err != nil {
+			return err
+		}
+
+		v := validator.New()
+
+		if entity.ValidateUpdateMeIn(v, in); !v.Valid() {
+			return handlerlib.ValidationError(v.Err())
+		}
+
+		user, err := ctrl.Service.updateMe(r.Context(), handlerlib.GetCtxUser(r), in)
+		if err != nil {
+			return err
+		}
+
+		return handlerlib.WriteJson(w, 200, entity.NewUserOut(user))
+	})
+}
+
+func (ctrl *Ctrl) changePassword() http.HandlerFunc {
+	return handlerlib.Wrap(func(w http.ResponseWriter, r *http.Request) error {
+		in, err := handlerlib.Bind[entity.ChangePasswordIn](w, r)
+		if err != nil {
+			return err
+		}
+
+		v := validator.New()
+
+		if entity.ValidateChangePasswordIn(v, in); !v.Valid() {
+			return handlerlib.ValidationError(v.Err())
+		}
+
+		user := handlerlib.GetCtxUser(r)
+
+		err = ctrl.Service.changePassword(r.Context(), user, in)
+
+		if err != nil && errors.Is(err, ErrInvalidCredentials) {
+			return handlerlib.NewError(401, "auth.invalid_credentials", "invalid current password")
+		}
+
+		if err != nil {
+			return err
+		}
+
+		return ctrl.writeTokenPair(w, r, user)
+	})
+}
+
+func (ctrl *Ctrl) forgotPassword() http.HandlerFunc {
+	return handlerlib.Wrap(func(w http.ResponseWriter, r *http.Request) error {
+		in, err := handlerlib.Bind[entity.ForgotPasswordIn](w, r)
+		if err != nil {
+			return err
+		}
+
+		v := validator.New()
+
+		if entity.ValidateForgotPasswordIn(v, in); !v.Valid() {
+			return handlerlib.ValidationError(v.Err())
+		}
+
+		ctrl.requestPasswordReset(r.Context(), in.Email)
+
+		return handlerlib.WriteJson(w, 202, handlerlib.RespMsg{
+			Message: "if an account exists for this email, a reset link has been sent",
+		})
+	})
+}
+
+func (ctrl *Ctrl) resetPassword() http.HandlerFunc {
+	return handlerlib.Wrap(func(w http.ResponseWriter, r *http.Request) error {
+		in, err := handlerlib.Bind[entity.ResetPasswordIn](w, r)
+		if err != nil {
+			return err
+		}
+
+		v := validator.New()
+
+		if entity.ValidateResetPasswordIn(v, in); !v.Valid() {
+			return handlerlib.ValidationError(v.Err())
+		}
+
+		err = ctrl.Service.resetPassword(r.Context(), in)
+		if err != nil {
+			return err
+		}
+
+		return handlerlib.WriteJson(w, 200, handlerlib.RespMsg{
+			Message: "password has been reset, login with your new password",
+		})
+	})
+}
+
+func (ctrl *Ctrl) verifyEmail() http.HandlerFunc {
+	return handlerlib.Wrap(func(w http.ResponseWriter, r *http.Request) error {
+		in, err := handlerlib.Bind[entity.VerifyEmailIn](w, r)
+		if err != nil {
+			return err
+		}
+
+		v := validator.New()
+
+		if entity.ValidateVerifyEmailIn(v, in); !v.Valid() {
+			return handlerlib.ValidationError(v.Err())
+		}
+
+		err = ctrl.Service.verifyEmail(r.Context(), in.Token)
+		if err != nil {
+			return err
+		}
+
+		return handlerlib.WriteJson(w, 200, handlerlib.RespMsg{
+			Message: "email address verified",
+		})
+	})
+}
+
+func (ctrl *Ctrl) confirmEmail() http.HandlerFunc {
+	return handlerlib.Wrap(func(w http.ResponseWriter, r *http.Request) error {
+		in, err := handlerlib.Bind[entity.VerifyEmailIn](w, r)
+		if err != nil {
+			return err
+		}
+
+		v := validator.New()
+
+		if entity.ValidateVerifyEmailIn(v, in); !v.Valid() {
+			return handlerlib.ValidationError(v.Err())
+		}
+
+		if err := ctrl.Service.confirmEmail(r.Context(), in.Token); err != nil {
+			return err
+		}
+
+		return handlerlib.WriteJson(w, 200, handlerlib.RespMsg{
+			Message: "email address changed",
+		})
+	})
+}
+
+func (ctrl *Ctrl) resendVerification() http.HandlerFunc {
+	return handlerlib.Wrap(func(w http.ResponseWriter, r *http.Request) error {
+		user := handlerlib.GetCtxUser(r)
+
+		if user.IsVerified() {
+			return handlerlib.NewError(409, "user.already_verified", "email already verified")
+		}
+
+		if err := ctrl.sendVerification(r.Context(), user); err != nil {
+			return err
+		}
+
+		return handlerlib.WriteJson(w, 202, handlerlib.RespMsg{
+			Message: "verification email sent",
+		})
+	})
+}
+
+func (ctrl *Ctrl) acceptInvite() http.HandlerFunc {
+	return handlerlib.Wrap(func(w http.ResponseWriter, r *http.Request) error {
+		in, err := handlerlib.Bind[entity.AcceptInviteIn](w, r)
+		if err != nil {
+			return err
+		}
+
+		v := validator.New()
+
+		if entity.ValidateAcceptInviteIn(v, in); !v.Valid() {
+			return handlerlib.ValidationError(v.Err())
+		}
+
+		err = ctrl.Service.acceptInvite(r.Context(), in)
+		if err != nil {
+			return err
+		}
+
+		return handlerlib.WriteJson(w, 200, handlerlib.RespMsg{
+			Message: "invite accepted, login with your new password",
+		})
+	})
+}
+
+func (ctrl *Ctrl) enrollTOTP() http.HandlerFunc {
+	return handlerlib.Wrap(func(w http.ResponseWriter, r *http.Request) error {
+		enrolment, err := ctrl.Service.enrollTOTP(r.Context(), handlerlib.GetCtxUser(r))
+		if err != nil {
+			return err
+		}
+
+		return handlerlib.WriteJsonNoStore(w, 201, enrolment)
+	})
+}
+
+type RecoveryCodes struct {
+	RecoveryCodes []string `json:"recoveryCodes"`
+}
+
+func (ctrl *Ctrl) confirmTOTP() http.HandlerFunc {
+	return handlerlib.Wrap(func(w http.ResponseWriter, r *http.Request) error {
+		in, err := handlerlib.Bind[entity.TOTPCodeIn](w, r)
+		if err != nil {
+			return err
+		}
+
+		v := validator.New()
+
+		if entity.ValidateTOTPCodeIn(v, in); !v.Valid() {
+			return handlerlib.ValidationError(v.Err())
+		}
+
+		codes, err := ctrl.Service.confirmTOTP(r.Context(), handlerlib.GetCtxUser(r), in.Code)
+
+		if err != nil && errors.Is(err, ErrTOTPNotEnrolled) {
+			return handlerlib.NewError(409, "totp.not_enrolled", "start enrolment first")
+		}
+		if err != nil {
+			return err
+		}
+
+		return handlerlib.WriteJsonNoStore(w, 200, RecoveryCodes{codes})
+	})
+}
+
+func (ctrl *Ctrl) disableTOTP() http.HandlerFunc {
+	return handlerlib.Wrap(func(w http.ResponseWriter, r *http.Request) error {
+		in, err := handlerlib.Bind[entity.DisableTOTPIn](w, r)
+		if err != nil {
+			return err
+		}
+
+		v := validator.New()
+
+		if entity.ValidateDisableTOTPIn(v, in); !v.Valid() {
+			return handlerlib.ValidationError(v.Err())
+		}
+
+		err = ctrl.Service.disableTOTP(r.Context(), handlerlib.GetCtxUser(r), in)
+
+		if err != nil && errors.Is(err, ErrInvalidCredentials) {
+			return handlerlib.NewError(401, "auth.invalid_credentials", "invalid password")
+		}
+		if err != nil {
+			return err
+		}
+
+		return handlerlib.SendStatus(w, 204)
+	})
+}
+
+func (ctrl *Ctrl) regenerateRecoveryCodes() http.HandlerFunc {
+	return handlerlib.Wrap(func(w http.ResponseWriter, r *http.Request) error {
+		in, err := handlerlib.Bind[entity.TOTPCodeIn](w, r)
+		if err != nil {
+			return err
+		}
+
+		v := validator.New()
+
+		if entity.ValidateTOTPCodeIn(v, in); !v.Valid() {
+			return handlerlib.ValidationError(v.Err())
+		}
+
+		codes, err := ctrl.Service.regenerateRecoveryCodes(
+			r.Context(),
+			handlerlib.GetCtxUser(r),
+			in.Code,
+		)
+
+		if err != nil {
+			return err
+		}
+
+		return handlerlib.WriteJsonNoStore(w, 200, RecoveryCodes{codes})
 	})
 }
@@ -0,0 +1,154 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/emma769/a-realtor/internal/config"
+	"github.com/emma769/a-realtor/internal/entity"
+	"github.com/emma769/a-realtor/internal/repository"
+	"github.com/emma769/a-realtor/internal/totp"
+)
+
+// throttleStore issues a valid challenge for every token and keeps login throttles in memory
+// with the same locking rules as the SQL; any other store call panics.
+type throttleStore struct {
+	storer
+
+	user     *entity.User
+	failures map[string]int
+	locked   map[string]time.Time
+	lockouts []*entity.Lockout
+}
+
+func (s *throttleStore) ConsumeUserToken(
+	context.Context,
+	[]byte,
+	entity.TokenPurpose,
+) (uuid.UUID, error) {
+	return s.user.UserID, nil
+}
+
+func (s *throttleStore) FindUserByID(context.Context, uuid.UUID) (*entity.User, error) {
+	return s.user, nil
+}
+
+func (s *throttleStore) FindUserByEmail(context.Context, string) (*entity.User, error) {
+	return s.user, nil
+}
+
+func (s *throttleStore) AdvanceTOTPStep(context.Context, uuid.UUID, int64) error {
+	return nil
+}
+
+func (s *throttleStore) ReserveLoginAttempt(
+	_ context.Context,
+	key string,
+	_ time.Duration,
+) (int, error) {
+	if time.Now().Before(s.locked[key]) {
+		return 0, repository.ErrLocked
+	}
+
+	s.failures[key]++
+
+	return s.failures[key], nil
+}
+
+func (s *throttleStore) ReleaseLoginAttempt(_ context.Context, key string) error {
+	s.failures[key] = max(s.failures[key]-1, 0)
+	return nil
+}
+
+func (s *throttleStore) SetLoginLock(_ context.Context, key string, until time.Time) error {
+	s.locked[key] = until
+	return nil
+}
+
+func (s *throttleStore) ResetLoginFailures(_ context.Context, key string) error {
+	delete(s.failures, key)
+	delete(s.locked, key)
+	return nil
+}
+
+func (s *throttleStore) FindLoginLock(_ context.Context, keys ...string) (*time.Time, error) {
+	until, ok := s.locked[keys[0]]
+	if !ok {
+		return nil, nil
+	}
+
+	return &until, nil
+}
+
+func (s *throttleStore) LockLogin(_ context.Context, lockout *entity.Lockout) error {
+	s.locked[lockout.Key] = lockout.LockedUntil
+	s.failures[lockout.Key] = 0
+	s.lockouts = append(s.lockouts, lockout)
+	return nil
+}
+
+func TestMFAGuessesLockTheAccount(t *testing.T) {
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now()
+	store := &throttleStore{
+		user: &entity.User{
+			UserID:        uuid.New(),
+			Email:         "jane@corp.test",
+			Status:        entity.StatusActive,
+			TOTPSecret:    secret,
+			TOTPEnabledAt: &now,
+		},
+		failures: map[string]int{},
+		locked:   map[string]time.Time{},
+	}
+
+	s := &Service{
+		store:   store,
+		timeout: 5 * time.Second,
+		cfg: &config.Config{
+			LoginMaxAttempts:     3,
+			LoginIpMaxAttempts:   100,
+			LoginLockoutDuration: time.Minute,
+		},
+		logger: slog.New(slog.NewTextHandler(io.Discard, nil)),
+	}
+
+	ctx := context.Background()
+	wrong := totp.Code(secret, totp.Step(now)+10)
+
+	for i := range s.cfg.LoginMaxAttempts {
+		in := entity.MFALoginIn{ChallengeToken: "challenge", Code: wrong}
+
+		if _, _, err := s.completeMFALogin(ctx, in, "203.0.113.7"); !errors.Is(err, ErrInvalidCode) {
+			t.Fatalf("attempt %d: err = %v, want ErrInvalidCode", i+1, err)
+		}
+	}
+
+	key := entity.EmailThrottleKey("jane@corp.test")
+
+	if len(store.lockouts) != 1 || store.lockouts[0].Key != key {
+		t.Fatalf("lockouts = %+v, want one on the email", store.lockouts)
+	}
+
+	in := entity.MFALoginIn{ChallengeToken: "challenge", Code: totp.Code(secret, totp.Step(now))}
+
+	_, wait, err := s.completeMFALogin(ctx, in, "203.0.113.7")
+
+	if !errors.Is(err, ErrTooManyAttempts) {
+		t.Fatalf("err = %v, want ErrTooManyAttempts even with the right code", err)
+	}
+
+	if wait <= 0 || wait > time.Minute {
+		t.Errorf("wait = %s, want the rest of the lockout", wait)
+	}
+}
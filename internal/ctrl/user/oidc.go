@@ -0,0 +1,287 @@
+package user
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/emma769/a-realtor/internal/entity"
+	handlerlib "github.com/emma769/a-realtor/internal/lib/handler"
+	"github.com/emma769/a-realtor/internal/oidc"
+	"github.com/emma769/a-realtor/internal/repository"
+	"github.com/emma769/a-realtor/internal/token"
+)
+
+var (
+	ErrSSOFailed       = handlerlib.NewError(401, "sso.failed", "single sign-on failed")
+	ErrUnverifiedEmail = handlerlib.NewError(
+		403,
+		"sso.email_unverified",
+		"identity provider email is not verified",
+	)
+	ErrNoAccount = handlerlib.NewError(
+		403,
+		"sso.no_account",
+		"no account exists for this identity",
+	)
+	ErrInvitePending = handlerlib.NewError(
+		403,
+		"sso.invite_pending",
+		"accept the invite sent to this email before signing in with single sign-on",
+	)
+)
+
+const oidcStateCookie = "oidc_state"
+
+type ssoProvider interface {
+	Issuer() string
+	AuthCodeURL(ctx context.Context, state, nonce, verifier string) (string, error)
+	Exchange(ctx context.Context, code, verifier string) (string, error)
+	Verify(ctx context.Context, raw, nonce string) (*oidc.Claims, error)
+}
+
+type SSOUserParam struct {
+	name,
+	email string
+	role entity.Role
+}
+
+func (param SSOUserParam) Name() string {
+	return param.name
+}
+
+func (param SSOUserParam) Email() string {
+	return param.email
+}
+
+func (param SSOUserParam) Role() entity.Role {
+	return param.role
+}
+
+func (s *Service) startSSO(ctx context.Context) (string, string, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	state, err := token.NewOpaque()
+	if err != nil {
+		return "", "", err
+	}
+
+	nonce, err := token.NewOpaque()
+	if err != nil {
+		return "", "", err
+	}
+
+	verifier, err := token.NewOpaque()
+	if err != nil {
+		return "", "", err
+	}
+
+	if err := s.store.CreateOIDCState(ctx, &entity.OIDCState{
+		Hash:      state.Hash,
+		Nonce:     nonce.Plain,
+		Verifier:  verifier.Plain,
+		ValidTill: time.Now().Add(s.cfg.OIDCStateExpire),
+	}); err != nil {
+		return "", "", err
+	}
+
+	uri, err := s.sso.AuthCodeURL(ctx, state.Plain, nonce.Plain, verifier.Plain)
+	if err != nil {
+		return "", "", errors.Join(ErrSSOFailed, err)
+	}
+
+	return uri, state.Plain, nil
+}
+
+func (s *Service) finishSSO(ctx context.Context, state, code string) (*entity.User, error) {
+	ctx, cancel := context.WithTimeout(ctx, 3*s.timeout)
+	defer cancel()
+
+	pending, err := s.store.ConsumeOIDCState(ctx, token.HashOpaque(state))
+
+	if err != nil && errors.Is(err, repository.ErrNotFound) {
+		return nil, ErrInvalidToken
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	raw, err := s.sso.Exchange(ctx, code, pending.Verifier)
+	if err != nil {
+		return nil, errors.Join(ErrSSOFailed, err)
+	}
+
+	claims, err := s.sso.Verify(ctx, raw, pending.Nonce)
+	if err != nil {
+		return nil, errors.Join(ErrSSOFailed, err)
+	}
+
+	role, mapped := s.mapRole(claims)
+
+	user, err := s.store.FindUserByIdentity(ctx, s.sso.Issuer(), claims.Subject)
+
+	if err != nil && !errors.Is(err, repository.ErrNotFound) {
+		return nil, err
+	}
+
+	if user == nil {
+		user, err = s.linkSSOUser(ctx, claims, role)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if mapped && user.Role != role {
+		if err := s.store.SetUserRole(ctx, user.UserID, role); err != nil {
+			return nil, err
+		}
+
+		user.Role = role
+	}
+
+	return user, nil
+}
+
+func (s *Service) linkSSOUser(
+	ctx context.Context,
+	claims *oidc.Claims,
+	role entity.Role,
+) (*entity.User, error) {
+	email := strings.ToLower(strings.TrimSpace(claims.Email))
+
+	if email == "" || !claims.Verified() {
+		return nil, ErrUnverifiedEmail
+	}
+
+	identity := &entity.Identity{
+		Issuer:  s.sso.Issuer(),
+		Subject: claims.Subject,
+		Email:   email,
+	}
+
+	user, err := s.store.FindUserByEmail(ctx, email)
+
+	// Linking an invited account would leave it pending and unable to log in; the invite has
+	// to be accepted first.
+	if err == nil && user.Status == entity.StatusPending {
+		return nil, ErrInvitePending
+	}
+
+	if err == nil {
+		identity.UserID = user.UserID
+		return s.store.LinkIdentity(ctx, identity)
+	}
+
+	if !errors.Is(err, repository.ErrNotFound) {
+		return nil, err
+	}
+
+	if !s.cfg.OIDCAutoProvision {
+		return nil, ErrNoAccount
+	}
+
+	param := SSOUserParam{
+		name:  cmp.Or(strings.TrimSpace(claims.Name), email),
+		email: email,
+		role:  role,
+	}
+
+	user, err = s.store.ProvisionSSOUser(ctx, param, identity)
+
+	if err != nil && errors.Is(err, repository.ErrDuplicateKey) {
+		return nil, ErrDuplicateEmail
+	}
+
+	return user, err
+}
+
+var roleRank = []entity.Role{entity.RoleAgent, entity.RoleManager, entity.RoleAdmin}
+
+func (s *Service) mapRole(claims *oidc.Claims) (entity.Role, bool) {
+	best := -1
+
+	for _, value := range claims.Strings(s.cfg.OIDCRoleClaim) {
+		role := entity.Role(s.cfg.OIDCRoleMap[value])
+
+		if rank := slices.Index(roleRank, role); rank > best {
+			best = rank
+		}
+	}
+
+	if best >= 0 {
+		return roleRank[best], true
+	}
+
+	if role := entity.Role(s.cfg.OIDCDefaultRole); role.Valid() {
+		return role, false
+	}
+
+	return entity.RoleAgent, false
+}
+
+func (ctrl *Ctrl) ssoLogin() http.HandlerFunc {
+	return handlerlib.Wrap(func(w http.ResponseWriter, r *http.Request) error {
+		uri, state, err := ctrl.startSSO(r.Context())
+
+		if err != nil && errors.Is(err, ErrSSOFailed) {
+			return handlerlib.NewError(502, "sso.provider_unavailable", "identity provider unavailable")
+		}
+
+		if err != nil {
+			return err
+		}
+
+		c := ctrl.cookie(oidcStateCookie, state, time.Now().Add(ctrl.cfg.OIDCStateExpire), true)
+		c.SameSite = http.SameSiteLaxMode
+		http.SetCookie(w, c)
+
+		http.Redirect(w, r, uri, http.StatusFound)
+
+		return nil
+	})
+}
+
+func (ctrl *Ctrl) ssoCallback() http.HandlerFunc {
+	return handlerlib.Wrap(func(w http.ResponseWriter, r *http.Request) error {
+		if e := r.URL.Query().Get("error"); e != "" {
+			return handlerlib.NewError(
+				401,
+				"sso.failed",
+				cmp.Or(r.URL.Query().Get("error_description"), e),
+			)
+		}
+
+		state, code := r.URL.Query().Get("state"), r.URL.Query().Get("code")
+
+		if state == "" || code == "" {
+			return handlerlib.NewError(400, "sso.invalid_callback", "missing state or code")
+		}
+
+		c, err := r.Cookie(oidcStateCookie)
+		if err != nil || c.Value != state {
+			return handlerlib.NewError(401, "sso.state_mismatch", "sso session mismatch, login again")
+		}
+
+		expired := ctrl.cookie(oidcStateCookie, "", time.Unix(0, 0), true)
+		expired.MaxAge = -1
+		http.SetCookie(w, expired)
+
+		user, err := ctrl.finishSSO(r.Context(), state, code)
+
+		if err != nil && errors.Is(err, ErrInvalidToken) {
+			return handlerlib.NewError(401, "sso.state_expired", "sso session expired, login again")
+		}
+
+		if err != nil {
+			return err
+		}
+
+		return ctrl.completeLogin(w, r, user)
+	})
+}
@@ -0,0 +1,256 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/emma769/a-realtor/internal/config"
+	"github.com/emma769/a-realtor/internal/entity"
+	"github.com/emma769/a-realtor/internal/oidc"
+	"github.com/emma769/a-realtor/internal/oidc/oidctest"
+	"github.com/emma769/a-realtor/internal/repository"
+	"github.com/emma769/a-realtor/internal/repository/psql"
+)
+
+// ssoStore keeps just the state the SSO flow touches; any other store call panics.
+type ssoStore struct {
+	storer
+
+	states      map[string]*entity.OIDCState
+	existing    *entity.User
+	provisioned *entity.User
+	linked      *entity.Identity
+}
+
+func (s *ssoStore) CreateOIDCState(_ context.Context, state *entity.OIDCState) error {
+	s.states[string(state.Hash)] = state
+	return nil
+}
+
+func (s *ssoStore) ConsumeOIDCState(_ context.Context, hash []byte) (*entity.OIDCState, error) {
+	state, ok := s.states[string(hash)]
+	if !ok {
+		return nil, repository.ErrNotFound
+	}
+
+	delete(s.states, string(hash))
+
+	return state, nil
+}
+
+func (s *ssoStore) FindUserByIdentity(context.Context, string, string) (*entity.User, error) {
+	return nil, repository.ErrNotFound
+}
+
+func (s *ssoStore) FindUserByEmail(context.Context, string) (*entity.User, error) {
+	if s.existing == nil {
+		return nil, repository.ErrNotFound
+	}
+
+	return s.existing, nil
+}
+
+func (s *ssoStore) LinkIdentity(
+	_ context.Context,
+	identity *entity.Identity,
+) (*entity.User, error) {
+	s.linked = identity
+	return s.existing, nil
+}
+
+func (s *ssoStore) ProvisionSSOUser(
+	_ context.Context,
+	param psql.SSOUserParam,
+	identity *entity.Identity,
+) (*entity.User, error) {
+	s.provisioned = &entity.User{
+		UserID: uuid.New(),
+		Name:   param.Name(),
+		Email:  param.Email(),
+		Role:   param.Role(),
+		Status: entity.StatusActive,
+	}
+
+	return s.provisioned, nil
+}
+
+func newSSOService(t *testing.T, idp *oidctest.IdP) (*Service, *ssoStore) {
+	t.Helper()
+
+	cfg := &config.Config{
+		OIDCIssuer:        idp.URL,
+		OIDCClientID:      oidctest.ClientID,
+		OIDCClientSecret:  oidctest.ClientSecret,
+		OIDCRedirectURL:   "http://app.test/api/auth/oidc/callback",
+		OIDCScopes:        []string{"openid", "email"},
+		OIDCRoleClaim:     "groups",
+		OIDCRoleMap:       map[string]string{"realtor-managers": "manager"},
+		OIDCDefaultRole:   "agent",
+		OIDCAutoProvision: true,
+		OIDCStateExpire:   time.Minute,
+	}
+
+	store := &ssoStore{states: map[string]*entity.OIDCState{}}
+
+	return &Service{
+		store:   store,
+		timeout: 5 * time.Second,
+		sso:     oidc.New(cfg),
+		cfg:     cfg,
+		logger:  slog.New(slog.NewTextHandler(io.Discard, nil)),
+	}, store
+}
+
+func signIn(t *testing.T, s *Service, idp *oidctest.IdP) (*entity.User, error) {
+	t.Helper()
+
+	ctx := context.Background()
+
+	uri, state, err := s.startSSO(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	code, returned := idp.Authorize(t, uri)
+
+	if returned != state {
+		t.Fatalf("state = %q, want %q", returned, state)
+	}
+
+	return s.finishSSO(ctx, state, code)
+}
+
+func TestSSOProvisionsWithMappedRole(t *testing.T) {
+	idp := oidctest.New(t)
+	idp.Claims["email"] = "Jane@Corp.test"
+	idp.Claims["email_verified"] = true
+	idp.Claims["name"] = "Jane"
+	idp.Claims["groups"] = []string{"everyone", "realtor-managers"}
+
+	s, store := newSSOService(t, idp)
+
+	user, err := signIn(t, s, idp)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if user != store.provisioned {
+		t.Fatal("expected a provisioned user")
+	}
+
+	if user.Role != entity.RoleManager {
+		t.Errorf("role = %q, want %q", user.Role, entity.RoleManager)
+	}
+
+	if user.Email != "jane@corp.test" {
+		t.Errorf("email = %q, want it lowercased", user.Email)
+	}
+}
+
+func TestSSOFallsBackToDefaultRole(t *testing.T) {
+	idp := oidctest.New(t)
+	idp.Claims["email"] = "joe@corp.test"
+	idp.Claims["email_verified"] = "true"
+	idp.Claims["groups"] = "everyone"
+
+	s, _ := newSSOService(t, idp)
+
+	user, err := signIn(t, s, idp)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if user.Role != entity.RoleAgent {
+		t.Errorf("role = %q, want %q", user.Role, entity.RoleAgent)
+	}
+}
+
+func TestSSORejectsUnverifiedEmail(t *testing.T) {
+	idp := oidctest.New(t)
+	idp.Claims["email"] = "jane@corp.test"
+	idp.Claims["email_verified"] = false
+
+	s, store := newSSOService(t, idp)
+
+	if _, err := signIn(t, s, idp); !errors.Is(err, ErrUnverifiedEmail) {
+		t.Fatalf("err = %v, want ErrUnverifiedEmail", err)
+	}
+
+	if store.provisioned != nil {
+		t.Error("unverified identity was provisioned")
+	}
+}
+
+func TestSSORejectsReplayedState(t *testing.T) {
+	idp := oidctest.New(t)
+	idp.Claims["email"] = "jane@corp.test"
+	idp.Claims["email_verified"] = true
+
+	s, _ := newSSOService(t, idp)
+	ctx := context.Background()
+
+	uri, state, err := s.startSSO(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	code, _ := idp.Authorize(t, uri)
+
+	if _, err := s.finishSSO(ctx, state, code); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := s.finishSSO(ctx, state, code); !errors.Is(err, ErrInvalidToken) {
+		t.Fatalf("err = %v, want ErrInvalidToken", err)
+	}
+}
+
+func TestSSOLinksExistingAccount(t *testing.T) {
+	idp := oidctest.New(t)
+	idp.Claims["email"] = "Jane@Corp.test"
+	idp.Claims["email_verified"] = true
+
+	s, store := newSSOService(t, idp)
+	store.existing = &entity.User{
+		UserID: uuid.New(),
+		Email:  "jane@corp.test",
+		Role:   entity.RoleAgent,
+		Status: entity.StatusActive,
+	}
+
+	user, err := signIn(t, s, idp)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if user != store.existing || store.linked == nil || store.linked.UserID != user.UserID {
+		t.Fatalf("linked = %+v, want the existing account", store.linked)
+	}
+}
+
+func TestSSORefusesPendingInvite(t *testing.T) {
+	idp := oidctest.New(t)
+	idp.Claims["email"] = "jane@corp.test"
+	idp.Claims["email_verified"] = true
+
+	s, store := newSSOService(t, idp)
+	store.existing = &entity.User{
+		UserID: uuid.New(),
+		Email:  "jane@corp.test",
+		Status: entity.StatusPending,
+	}
+
+	if _, err := signIn(t, s, idp); !errors.Is(err, ErrInvitePending) {
+		t.Fatalf("err = %v, want ErrInvitePending", err)
+	}
+
+	if store.linked != nil || store.provisioned != nil {
+		t.Error("a pending invite was linked or provisioned over")
+	}
+}
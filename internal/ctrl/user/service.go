@@ -1,32 +1,146 @@
 package user
 
 import (
+	"cmp"
 	"context"
-	"crypto/sha256"
+	"crypto/rand"
+	"encoding/base32"
 	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
 	"time"
 
-	"golang.org/x/crypto/bcrypt"
+	"github.com/google/uuid"
 
+	"github.com/emma769/a-realtor/internal/config"
 	"github.com/emma769/a-realtor/internal/entity"
+	handlerlib "github.com/emma769/a-realtor/internal/lib/handler"
+	loglib "github.com/emma769/a-realtor/internal/lib/log"
+	"github.com/emma769/a-realtor/internal/mailer"
+	"github.com/emma769/a-realtor/internal/passhash"
 	"github.com/emma769/a-realtor/internal/repository"
 	"github.com/emma769/a-realtor/internal/repository/psql"
+	"github.com/emma769/a-realtor/internal/token"
+	"github.com/emma769/a-realtor/internal/totp"
 )
 
 var (
-	ErrNotFound       = errors.New("user not found")
-	ErrDuplicateEmail = errors.New("duplicate email")
+	ErrNotFound       = handlerlib.NewError(404, "user.not_found", "user not found")
+	ErrDuplicateEmail = handlerlib.NewError(409, "user.email_taken", "email already in use")
+	ErrInvalidToken   = handlerlib.NewError(400, "auth.invalid_token", "invalid or expired token")
+	ErrDeactivated    = handlerlib.NewError(403, "user.deactivated", "account is deactivated")
+
+	ErrInvalidCredentials = handlerlib.NewError(
+		401,
+		"auth.invalid_credentials",
+		"invalid credentials",
+	)
+	ErrTooManyAttempts = handlerlib.NewError(
+		429,
+		"auth.too_many_attempts",
+		"too many failed login attempts, try again later",
+	)
+	ErrTOTPEnabled = handlerlib.NewError(
+		409,
+		"totp.already_enabled",
+		"two-factor authentication already enabled",
+	)
+	ErrTOTPNotEnrolled = handlerlib.NewError(
+		409,
+		"totp.not_enabled",
+		"two-factor authentication is not enabled",
+	)
+	ErrTOTPRequired = handlerlib.NewError(
+		409,
+		"totp.required",
+		"two-factor authentication is required for your role",
+	)
+	ErrInvalidCode = handlerlib.NewError(400, "totp.invalid_code", "invalid two-factor code")
 )
 
+const recoveryCodeCount = 10
+
+func dummyHash(hasher passhash.Hasher) func() []byte {
+	return sync.OnceValue(func() []byte {
+		h, err := hasher.Hash("not-a-real-password")
+		if err != nil {
+			panic(err)
+		}
+		return h
+	})
+}
+
 type storer interface {
 	CreateUser(context.Context, psql.UserParam) (*entity.User, error)
 	FindUserByEmail(context.Context, string) (*entity.User, error)
 	FindUserBySession(context.Context, []byte) (*entity.User, error)
+	CreateUserToken(context.Context, *entity.UserToken) error
+	DeleteUserTokens(context.Context, uuid.UUID, entity.TokenPurpose) error
+	ResetPassword(context.Context, []byte, []byte) (uuid.UUID, error)
+	VerifyEmail(context.Context, []byte) (uuid.UUID, error)
+	AcceptInvite(context.Context, []byte, string, []byte) (uuid.UUID, error)
+	FindLoginLock(context.Context, ...string) (*time.Time, error)
+	ReserveLoginAttempt(context.Context, string, time.Duration) (int, error)
+	ReleaseLoginAttempt(context.Context, string) error
+	SetLoginLock(context.Context, string, time.Time) error
+	ResetLoginFailures(context.Context, string) error
+	LockLogin(context.Context, *entity.Lockout) error
+	FindUserByID(context.Context, uuid.UUID) (*entity.User, error)
+	SetTOTPSecret(context.Context, uuid.UUID, []byte) error
+	EnableTOTP(context.Context, uuid.UUID, int64, [][]byte) error
+	DisableTOTP(context.Context, uuid.UUID) error
+	AdvanceTOTPStep(context.Context, uuid.UUID, int64) error
+	ReplaceRecoveryCodes(context.Context, uuid.UUID, [][]byte) error
+	UseRecoveryCode(context.Context, uuid.UUID, []byte) error
+	ConsumeUserToken(context.Context, []byte, entity.TokenPurpose) (uuid.UUID, error)
+	UpdateUserProfile(context.Context, uuid.UUID, string) (*entity.User, error)
+	SetPendingEmail(context.Context, uuid.UUID, string) error
+	ConfirmEmailChange(context.Context, []byte) (*entity.User, string, error)
+	ChangePassword(context.Context, uuid.UUID, []byte) error
+	UpdateUserPassword(context.Context, uuid.UUID, []byte) error
+	DeleteSession(context.Context, []byte) error
+	CreateOIDCState(context.Context, *entity.OIDCState) error
+	ConsumeOIDCState(context.Context, []byte) (*entity.OIDCState, error)
+	FindUserByIdentity(context.Context, string, string) (*entity.User, error)
+	LinkIdentity(context.Context, *entity.Identity) (*entity.User, error)
+	ProvisionSSOUser(context.Context, psql.SSOUserParam, *entity.Identity) (*entity.User, error)
+	SetUserRole(context.Context, uuid.UUID, entity.Role) error
+}
+
+type tokenIssuer interface {
+	GetUserToken(context.Context, uuid.UUID, entity.TokenPurpose, time.Duration) (string, error)
 }
 
 type Service struct {
-	store   storer
-	timeout time.Duration
+	store     storer
+	timeout   time.Duration
+	tokens    tokenIssuer
+	hasher    passhash.Hasher
+	sso       ssoProvider
+	mailer    mailer.Mailer
+	cfg       *config.Config
+	logger    *slog.Logger
+	dummyHash func() []byte
+}
+
+func (s *Service) log(ctx context.Context) *slog.Logger {
+	return loglib.From(ctx, s.logger)
+}
+
+func (s *Service) verifyPassword(user *entity.User, plain string) (bool, error) {
+	rehash, err := s.hasher.Verify(user.Password, plain)
+
+	if err != nil && errors.Is(err, passhash.ErrMismatch) {
+		return false, ErrInvalidCredentials
+	}
+
+	if err != nil && errors.Is(err, passhash.ErrUnknownFormat) {
+		return false, ErrInvalidCredentials
+	}
+
+	return rehash, err
 }
 
 type UserParam struct {
@@ -51,7 +165,7 @@ func (s *Service) create(ctx context.Context, in entity.UserIn) (*entity.User, e
 	ctx, cancel := context.WithTimeout(ctx, s.timeout)
 	defer cancel()
 
-	password, err := bcrypt.GenerateFromPassword([]byte(in.Password), bcrypt.DefaultCost)
+	password, err := s.hasher.Hash(in.Password)
 	if err != nil {
 		return nil, err
 	}
@@ -88,13 +202,208 @@ func (s *Service) findByEmail(ctx context.Context, email string) (*entity.User,
 	return user, nil
 }
 
-func (s *Service) findBySession(ctx context.Context, plain string) (*entity.User, error) {
+func (s *Service) authenticate(ctx context.Context, in entity.LoginIn) (*entity.User, error) {
+	user, err := s.findByEmail(ctx, in.Email)
+
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		return nil, err
+	}
+
+	if user == nil || user.Status == entity.StatusPending {
+		_, _ = s.hasher.Verify(s.dummyHash(), in.Password)
+		return nil, ErrInvalidCredentials
+	}
+
+	rehash, err := s.verifyPassword(user, in.Password)
+	if err != nil {
+		return nil, err
+	}
+
+	if rehash {
+		s.rehash(ctx, user, in.Password)
+	}
+
+	return user, nil
+}
+
+func (s *Service) rehash(ctx context.Context, user *entity.User, plain string) {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	password, err := s.hasher.Hash(plain)
+
+	if err == nil {
+		err = s.store.UpdateUserPassword(ctx, user.UserID, password)
+	}
+
+	if err != nil {
+		s.log(ctx).LogAttrs(ctx, slog.LevelError, "could not rehash password", slog.Attr{
+			Key:   "detail",
+			Value: slog.StringValue(err.Error()),
+		})
+		return
+	}
+
+	user.Password = password
+}
+
+type loginAttempt struct {
+	email, ip string
+	failures,
+	ipFailures int
+}
+
+// reserveLogin counts the attempt against the email and IP keys before the password is checked,
+// so a burst of parallel guesses cannot all pass the lock check before a failure is recorded.
+// A nil attempt means the login is locked for the returned duration.
+func (s *Service) reserveLogin(
+	ctx context.Context,
+	email, ip string,
+) (*loginAttempt, time.Duration, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	emailKey, ipKey := entity.EmailThrottleKey(email), entity.IPThrottleKey(ip)
+
+	failures, err := s.store.ReserveLoginAttempt(ctx, emailKey, s.cfg.LoginFailureWindow)
+
+	if err != nil && !errors.Is(err, repository.ErrLocked) {
+		return nil, 0, err
+	}
+
+	if err != nil || failures > s.cfg.LoginMaxAttempts {
+		wait, err := s.lockedFor(ctx, emailKey)
+		return nil, wait, err
+	}
+
+	ipFailures, err := s.store.ReserveLoginAttempt(ctx, ipKey, s.cfg.LoginFailureWindow)
+
+	if err != nil && !errors.Is(err, repository.ErrLocked) {
+		return nil, 0, err
+	}
+
+	if err != nil || ipFailures > s.cfg.LoginIpMaxAttempts {
+		if err := s.store.ReleaseLoginAttempt(ctx, emailKey); err != nil {
+			return nil, 0, err
+		}
+
+		wait, err := s.lockedFor(ctx, ipKey)
+		return nil, wait, err
+	}
+
+	return &loginAttempt{
+		email:      email,
+		ip:         ip,
+		failures:   failures,
+		ipFailures: ipFailures,
+	}, 0, nil
+}
+
+func (s *Service) lockedFor(ctx context.Context, key string) (time.Duration, error) {
+	until, err := s.store.FindLoginLock(ctx, key)
+	if err != nil {
+		return 0, err
+	}
+
+	// Out of attempts while the attempt that trips the lockout is still in flight.
+	if until == nil {
+		return s.cfg.LoginBackoffBase, nil
+	}
+
+	return max(time.Until(*until), time.Second), nil
+}
+
+func (s *Service) loginFailed(ctx context.Context, attempt *loginAttempt) error {
 	ctx, cancel := context.WithTimeout(ctx, s.timeout)
 	defer cancel()
 
-	h := sha256.Sum256([]byte(plain))
+	key := entity.EmailThrottleKey(attempt.email)
 
-	user, err := s.store.FindUserBySession(ctx, h[:])
+	if attempt.failures >= s.cfg.LoginMaxAttempts {
+		err := s.lockout(ctx, key, attempt.email, attempt.ip, attempt.failures)
+		if err != nil {
+			return err
+		}
+	} else {
+		delay := min(s.cfg.LoginBackoffBase<<(attempt.failures-1), s.cfg.LoginLockoutDuration)
+
+		if err := s.store.SetLoginLock(ctx, key, time.Now().Add(delay)); err != nil {
+			return err
+		}
+	}
+
+	if attempt.ipFailures >= s.cfg.LoginIpMaxAttempts {
+		key = entity.IPThrottleKey(attempt.ip)
+		return s.lockout(ctx, key, attempt.email, attempt.ip, attempt.ipFailures)
+	}
+
+	return nil
+}
+
+func (s *Service) lockout(ctx context.Context, key, email, ip string, failures int) error {
+	lockout := &entity.Lockout{
+		Key:         key,
+		IP:          ip,
+		Failures:    failures,
+		LockedUntil: time.Now().Add(s.cfg.LoginLockoutDuration),
+	}
+
+	if email != "" {
+		user, err := s.store.FindUserByEmail(ctx, email)
+
+		if err != nil && !errors.Is(err, repository.ErrNotFound) {
+			return err
+		}
+
+		if user != nil {
+			lockout.UserID = &user.UserID
+		}
+	}
+
+	if err := s.store.LockLogin(ctx, lockout); err != nil {
+		return err
+	}
+
+	s.log(ctx).LogAttrs(
+		ctx,
+		slog.LevelWarn,
+		"login locked out",
+		slog.String("key", key),
+		slog.String("ip", ip),
+		slog.Int("failures", failures),
+		slog.Time("lockedUntil", lockout.LockedUntil),
+	)
+
+	return nil
+}
+
+func (s *Service) loginSucceeded(ctx context.Context, attempt *loginAttempt) error {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	if err := s.store.ResetLoginFailures(ctx, entity.EmailThrottleKey(attempt.email)); err != nil {
+		return err
+	}
+
+	return s.store.ReleaseLoginAttempt(ctx, entity.IPThrottleKey(attempt.ip))
+}
+
+func (s *Service) releaseLogin(ctx context.Context, attempt *loginAttempt) error {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	if err := s.store.ReleaseLoginAttempt(ctx, entity.EmailThrottleKey(attempt.email)); err != nil {
+		return err
+	}
+
+	return s.store.ReleaseLoginAttempt(ctx, entity.IPThrottleKey(attempt.ip))
+}
+
+func (s *Service) findBySession(ctx context.Context, plain string) (*entity.User, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	user, err := s.store.FindUserBySession(ctx, token.HashOpaque(plain))
 
 	if err != nil && errors.Is(err, repository.ErrNotFound) {
 		return nil, ErrNotFound
@@ -106,3 +415,580 @@ func (s *Service) findBySession(ctx context.Context, plain string) (*entity.User
 
 	return user, nil
 }
+
+func (s *Service) logout(ctx context.Context, plain string) error {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+	return s.store.DeleteSession(ctx, token.HashOpaque(plain))
+}
+
+// requestPasswordReset returns before looking the account up so that response time does not
+// reveal which emails are registered.
+func (s *Service) requestPasswordReset(ctx context.Context, email string) {
+	ctx = context.WithoutCancel(ctx)
+
+	go func() {
+		if err := s.sendPasswordReset(ctx, email); err != nil {
+			s.log(ctx).LogAttrs(ctx, slog.LevelError, "could not send password reset", slog.Attr{
+				Key:   "detail",
+				Value: slog.StringValue(err.Error()),
+			})
+		}
+	}()
+}
+
+func (s *Service) sendPasswordReset(ctx context.Context, email string) error {
+	ctx, cancel := context.WithTimeout(ctx, 3*s.timeout)
+	defer cancel()
+
+	user, err := s.store.FindUserByEmail(ctx, email)
+
+	if err != nil && errors.Is(err, repository.ErrNotFound) {
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if !user.IsActive() {
+		return nil
+	}
+
+	plain, err := s.tokens.GetUserToken(
+		ctx,
+		user.UserID,
+		entity.PurposePasswordReset,
+		s.cfg.PasswordResetExpire,
+	)
+	if err != nil {
+		return err
+	}
+
+	s.send(ctx, &mailer.Message{
+		To:      user.Email,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf(
+			"Hi %s,\n\nUse the link below to reset your password. It expires in %s.\n\n%s\n\n"+
+				"If you did not request a password reset, you can ignore this email.\n",
+			user.Name,
+			s.cfg.PasswordResetExpire,
+			s.link("/reset-password", plain),
+		),
+	})
+
+	return nil
+}
+
+func (s *Service) resetPassword(ctx context.Context, in entity.ResetPasswordIn) error {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	password, err := s.hasher.Hash(in.Password)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.store.ResetPassword(ctx, token.HashOpaque(in.Token), password)
+
+	if err != nil && errors.Is(err, repository.ErrNotFound) {
+		return ErrInvalidToken
+	}
+
+	return err
+}
+
+// updateMe applies name changes right away. A new email needs the current password and only
+// replaces the login email once confirmed from the new address.
+func (s *Service) updateMe(
+	ctx context.Context,
+	user *entity.User,
+	in entity.UpdateMeIn,
+) (*entity.User, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	name, email := user.Name, user.Email
+
+	if in.Name != nil {
+		name = strings.TrimSpace(*in.Name)
+	}
+
+	if in.Email != nil {
+		email = strings.TrimSpace(*in.Email)
+	}
+
+	if email != user.Email {
+		if _, err := s.verifyPassword(user, in.CurrentPassword); err != nil {
+			return nil, err
+		}
+	}
+
+	updated, err := s.store.UpdateUserProfile(ctx, user.UserID, name)
+
+	if err != nil && errors.Is(err, repository.ErrNotFound) {
+		return nil, ErrNotFound
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if email != user.Email {
+		if err := s.requestEmailChange(ctx, updated, email); err != nil {
+			return nil, err
+		}
+	}
+
+	return updated, nil
+}
+
+func (s *Service) requestEmailChange(ctx context.Context, user *entity.User, email string) error {
+	existing, err := s.store.FindUserByEmail(ctx, email)
+
+	if err == nil && existing.UserID != user.UserID {
+		return ErrDuplicateEmail
+	}
+
+	if err != nil && !errors.Is(err, repository.ErrNotFound) {
+		return err
+	}
+
+	if err := s.store.SetPendingEmail(ctx, user.UserID, email); err != nil {
+		return err
+	}
+
+	user.PendingEmail = &email
+
+	plain, err := s.tokens.GetUserToken(
+		ctx,
+		user.UserID,
+		entity.PurposeEmailChange,
+		s.cfg.EmailVerificationExpire,
+	)
+	if err != nil {
+		return err
+	}
+
+	s.send(ctx, &mailer.Message{
+		To:      email,
+		Subject: "Confirm your new email address",
+		Body: fmt.Sprintf(
+			"Hi %s,\n\nConfirm your new email address using the link below. It expires in %s.\n\n"+
+				"%s\n\nYour current address stays in use until then.\n",
+			user.Name,
+			s.cfg.EmailVerificationExpire,
+			s.link("/confirm-email", plain),
+		),
+	})
+
+	s.send(ctx, &mailer.Message{
+		To:      user.Email,
+		Subject: "Email change requested",
+		Body: fmt.Sprintf(
+			"Hi %s,\n\nA change of the email address on your account to %s was requested. "+
+				"It takes effect once confirmed from the new address.\n\n"+
+				"If you did not make this request, change your password and contact an "+
+				"administrator.\n",
+			user.Name,
+			email,
+		),
+	})
+
+	return nil
+}
+
+func (s *Service) confirmEmail(ctx context.Context, plain string) error {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	user, previous, err := s.store.ConfirmEmailChange(ctx, token.HashOpaque(plain))
+
+	if err != nil && errors.Is(err, repository.ErrNotFound) {
+		return ErrInvalidToken
+	}
+
+	if err != nil && errors.Is(err, repository.ErrDuplicateKey) {
+		return ErrDuplicateEmail
+	}
+
+	if err != nil {
+		return err
+	}
+
+	s.send(ctx, &mailer.Message{
+		To:      previous,
+		Subject: "Your email address was changed",
+		Body: fmt.Sprintf(
+			"Hi %s,\n\nThe email address on your account was changed to %s.\n\n"+
+				"If you did not make this change, contact an administrator.\n",
+			user.Name,
+			user.Email,
+		),
+	})
+
+	return nil
+}
+
+func (s *Service) changePassword(
+	ctx context.Context,
+	user *entity.User,
+	in entity.ChangePasswordIn,
+) error {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	if _, err := s.verifyPassword(user, in.CurrentPassword); err != nil {
+		return err
+	}
+
+	password, err := s.hasher.Hash(in.NewPassword)
+	if err != nil {
+		return err
+	}
+
+	return s.store.ChangePassword(ctx, user.UserID, password)
+}
+
+func (s *Service) sendVerification(ctx context.Context, user *entity.User) error {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	plain, err := s.tokens.GetUserToken(
+		ctx,
+		user.UserID,
+		entity.PurposeEmailVerification,
+		s.cfg.EmailVerificationExpire,
+	)
+	if err != nil {
+		return err
+	}
+
+	s.send(ctx, &mailer.Message{
+		To:      user.Email,
+		Subject: "Verify your email address",
+		Body: fmt.Sprintf(
+			"Hi %s,\n\nConfirm your email address using the link below. It expires in %s.\n\n%s\n",
+			user.Name,
+			s.cfg.EmailVerificationExpire,
+			s.link("/verify-email", plain),
+		),
+	})
+
+	return nil
+}
+
+func (s *Service) verifyEmail(ctx context.Context, plain string) error {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	_, err := s.store.VerifyEmail(ctx, token.HashOpaque(plain))
+
+	if err != nil && errors.Is(err, repository.ErrNotFound) {
+		return ErrInvalidToken
+	}
+
+	return err
+}
+
+func (s *Service) acceptInvite(ctx context.Context, in entity.AcceptInviteIn) error {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	password, err := s.hasher.Hash(in.Password)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.store.AcceptInvite(ctx, token.HashOpaque(in.Token), in.Name, password)
+
+	if err != nil && errors.Is(err, repository.ErrNotFound) {
+		return ErrInvalidToken
+	}
+
+	return err
+}
+
+type TOTPEnrolment struct {
+	Secret string `json:"secret"`
+	URI    string `json:"uri"`
+}
+
+func (s *Service) enrollTOTP(ctx context.Context, user *entity.User) (*TOTPEnrolment, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	if user.HasTOTP() {
+		return nil, ErrTOTPEnabled
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.store.SetTOTPSecret(ctx, user.UserID, secret)
+
+	if err != nil && errors.Is(err, repository.ErrNotFound) {
+		return nil, ErrTOTPEnabled
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &TOTPEnrolment{
+		Secret: totp.Encode(secret),
+		URI:    totp.URI(s.cfg.TOTPIssuer, user.Email, secret),
+	}, nil
+}
+
+func (s *Service) confirmTOTP(
+	ctx context.Context,
+	user *entity.User,
+	code string,
+) ([]string, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	if user.HasTOTP() {
+		return nil, ErrTOTPEnabled
+	}
+
+	if len(user.TOTPSecret) == 0 {
+		return nil, ErrTOTPNotEnrolled
+	}
+
+	step, ok := totp.Validate(user.TOTPSecret, strings.TrimSpace(code), time.Now(), 1)
+	if !ok {
+		return nil, ErrInvalidCode
+	}
+
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.store.EnableTOTP(ctx, user.UserID, step, hashes)
+
+	if err != nil && errors.Is(err, repository.ErrNotFound) {
+		return nil, ErrTOTPEnabled
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return codes, nil
+}
+
+func (s *Service) disableTOTP(
+	ctx context.Context,
+	user *entity.User,
+	in entity.DisableTOTPIn,
+) error {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	if !user.HasTOTP() {
+		return ErrTOTPNotEnrolled
+	}
+
+	if user.MFARequired {
+		return ErrTOTPRequired
+	}
+
+	if _, err := s.verifyPassword(user, in.Password); err != nil {
+		return err
+	}
+
+	if err := s.verifySecondFactor(ctx, user, in.Code, ""); err != nil {
+		return err
+	}
+
+	return s.store.DisableTOTP(ctx, user.UserID)
+}
+
+func (s *Service) regenerateRecoveryCodes(
+	ctx context.Context,
+	user *entity.User,
+	code string,
+) ([]string, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	if !user.HasTOTP() {
+		return nil, ErrTOTPNotEnrolled
+	}
+
+	if err := s.verifySecondFactor(ctx, user, code, ""); err != nil {
+		return nil, err
+	}
+
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.store.ReplaceRecoveryCodes(ctx, user.UserID, hashes); err != nil {
+		return nil, err
+	}
+
+	return codes, nil
+}
+
+func (s *Service) createMFAChallenge(ctx context.Context, user *entity.User) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	return s.tokens.GetUserToken(
+		ctx,
+		user.UserID,
+		entity.PurposeMFAChallenge,
+		s.cfg.MFAChallengeExpire,
+	)
+}
+
+// completeMFALogin throttles second factor guesses on the same email and IP keys as passwords,
+// and only resets the email's failures once the code checks out. A locked login returns
+// ErrTooManyAttempts with the time left.
+func (s *Service) completeMFALogin(
+	ctx context.Context,
+	in entity.MFALoginIn,
+	ip string,
+) (*entity.User, time.Duration, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	id, err := s.store.ConsumeUserToken(
+		ctx,
+		token.HashOpaque(in.ChallengeToken),
+		entity.PurposeMFAChallenge,
+	)
+
+	if err != nil && errors.Is(err, repository.ErrNotFound) {
+		return nil, 0, ErrInvalidToken
+	}
+
+	if err != nil {
+		return nil, 0, err
+	}
+
+	user, err := s.store.FindUserByID(ctx, id)
+
+	if err != nil && errors.Is(err, repository.ErrNotFound) {
+		return nil, 0, ErrInvalidToken
+	}
+
+	if err != nil {
+		return nil, 0, err
+	}
+
+	attempt, wait, err := s.reserveLogin(ctx, user.Email, ip)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if attempt == nil {
+		return nil, wait, ErrTooManyAttempts
+	}
+
+	err = s.verifySecondFactor(ctx, user, in.Code, in.RecoveryCode)
+
+	if err != nil && errors.Is(err, ErrInvalidCode) {
+		if err := s.loginFailed(ctx, attempt); err != nil {
+			return nil, 0, err
+		}
+
+		return nil, 0, err
+	}
+
+	if err != nil {
+		return nil, 0, errors.Join(err, s.releaseLogin(ctx, attempt))
+	}
+
+	if err := s.loginSucceeded(ctx, attempt); err != nil {
+		return nil, 0, err
+	}
+
+	return user, 0, nil
+}
+
+func (s *Service) verifySecondFactor(
+	ctx context.Context,
+	user *entity.User,
+	code, recoveryCode string,
+) error {
+	if recoveryCode != "" {
+		err := s.store.UseRecoveryCode(
+			ctx,
+			user.UserID,
+			token.HashOpaque(normalizeRecoveryCode(recoveryCode)),
+		)
+
+		if err != nil && errors.Is(err, repository.ErrNotFound) {
+			return ErrInvalidCode
+		}
+
+		return err
+	}
+
+	step, ok := totp.Validate(user.TOTPSecret, strings.TrimSpace(code), time.Now(), 1)
+	if !ok {
+		return ErrInvalidCode
+	}
+
+	err := s.store.AdvanceTOTPStep(ctx, user.UserID, step)
+
+	if err != nil && errors.Is(err, repository.ErrNotFound) {
+		return ErrInvalidCode
+	}
+
+	return err
+}
+
+func newRecoveryCodes() ([]string, [][]byte, error) {
+	enc := base32.StdEncoding.WithPadding(base32.NoPadding)
+
+	codes := make([]string, recoveryCodeCount)
+	hashes := make([][]byte, recoveryCodeCount)
+
+	for i := range codes {
+		b := make([]byte, 6)
+
+		if _, err := rand.Read(b); err != nil {
+			return nil, nil, err
+		}
+
+		raw := strings.ToLower(enc.EncodeToString(b))
+		codes[i] = raw[:5] + "-" + raw[5:]
+		hashes[i] = token.HashOpaque(raw)
+	}
+
+	return codes, hashes, nil
+}
+
+func normalizeRecoveryCode(code string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '-' || r == ' ' {
+			return -1
+		}
+		return r
+	}, strings.ToLower(code))
+}
+
+func (s *Service) send(ctx context.Context, msg *mailer.Message) {
+	if err := s.mailer.Send(ctx, msg); err != nil {
+		s.log(ctx).LogAttrs(ctx, slog.LevelError, "could not send mail", slog.Attr{
+			Key:   "detail",
+			Value: slog.StringValue(err.Error()),
+		}, slog.Attr{
+			Key:   "subject",
+			Value: slog.StringValue(msg.Subject),
+		})
+	}
+}
+
+func (s *Service) link(path, plain string) string {
+	return mailer.Link(cmp.Or(s.cfg.AppUrl, s.cfg.TrustedOrigin), path, plain)
+}
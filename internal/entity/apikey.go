@@ -0,0 +1,85 @@
+package entity
+
+import (
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/emma769/a-realtor/internal/validator"
+)
+
+type apiKeyCtx struct{}
+
+var APIKeyCtxKey = apiKeyCtx{}
+
+const (
+	ScopeLandlordsRead  = "landlords:read"
+	ScopeLandlordsWrite = "landlords:write"
+	ScopeTenantsRead    = "tenants:read"
+	ScopeTenantsWrite   = "tenants:write"
+	ScopeReportsRead    = "reports:read"
+)
+
+var Scopes = []string{
+	ScopeLandlordsRead,
+	ScopeLandlordsWrite,
+	ScopeTenantsRead,
+	ScopeTenantsWrite,
+	ScopeReportsRead,
+}
+
+type APIKey struct {
+	APIKeyID   uuid.UUID  `json:"apiKeyID"`
+	UserID     uuid.UUID  `json:"userID"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Hash       []byte     `json:"-"`
+	Scopes     []string   `json:"scopes"`
+	ExpiresAt  *time.Time `json:"expiresAt,omitempty"`
+	LastUsedAt *time.Time `json:"lastUsedAt,omitempty"`
+	RevokedAt  *time.Time `json:"revokedAt,omitempty"`
+	CreatedAt  time.Time  `json:"createdAt"`
+}
+
+func (k *APIKey) HasScope(scope string) bool {
+	return slices.Contains(k.Scopes, scope)
+}
+
+type APIKeyIn struct {
+	Name      string    `json:"name"`
+	Scopes    []string  `json:"scopes"`
+	ExpiresAt *DateTime `json:"expiresAt"`
+}
+
+func ValidateAPIKeyIn(v *validator.Validator, in APIKeyIn) {
+	validator.Check(
+		v,
+		in,
+		func(in APIKeyIn) (bool, validator.ValidationMsg) {
+			return strings.TrimSpace(in.Name) != "", validator.ValidationMsg{
+				Prop: "name",
+				Info: "cannot be blank",
+			}
+		},
+		func(in APIKeyIn) (bool, validator.ValidationMsg) {
+			for i := range in.Scopes {
+				if !slices.Contains(Scopes, in.Scopes[i]) {
+					return false, validator.ValidationMsg{
+						Prop: "scopes",
+						Info: "must be any of " + strings.Join(Scopes, ", "),
+					}
+				}
+			}
+			return true, validator.ValidationMsg{}
+		},
+		func(in APIKeyIn) (bool, validator.ValidationMsg) {
+			return in.ExpiresAt == nil || in.ExpiresAt.After(time.Now()),
+				validator.ValidationMsg{
+					Prop: "expiresAt",
+					Info: "must be in the future",
+				}
+		},
+	)
+}
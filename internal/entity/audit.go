@@ -0,0 +1,44 @@
+package entity
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type auditCtx struct{}
+
+var AuditCtxKey = auditCtx{}
+
+type AuditAction string
+
+const (
+	AuditCreate AuditAction = "create"
+	AuditUpdate AuditAction = "update"
+	AuditDelete AuditAction = "delete"
+)
+
+const (
+	AuditLandlord = "landlord"
+	AuditTenant   = "tenant"
+)
+
+type AuditMeta struct {
+	ActorID   *uuid.UUID
+	RequestID string
+	IP        string
+}
+
+type AuditEntry struct {
+	AuditID    int64           `json:"auditID"`
+	ActorID    *uuid.UUID      `json:"actorID,omitempty"`
+	Action     AuditAction     `json:"action"`
+	EntityType string          `json:"entityType"`
+	EntityID   uuid.UUID       `json:"entityID"`
+	Before     json.RawMessage `json:"before"`
+	After      json.RawMessage `json:"after"`
+	RequestID  string          `json:"requestID,omitempty"`
+	IP         string          `json:"ip,omitempty"`
+	CreatedAt  time.Time       `json:"createdAt"`
+}
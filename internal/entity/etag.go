@@ -0,0 +1,22 @@
+package entity
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/google/uuid"
+)
+
+func versionETag(id uuid.UUID, version int) string {
+	sum := sha256.Sum256(fmt.Appendf(nil, "%s:%d", id, version))
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+func (l *Landlord) ETag() string {
+	return versionETag(l.LandlordID, l.Version)
+}
+
+func (t *Tenant) ETag() string {
+	return versionETag(t.TenantID, t.Version)
+}
@@ -0,0 +1,22 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type IdempotencyRecord struct {
+	UserID      uuid.UUID
+	Key         string
+	RequestHash []byte
+	Status      int
+	Header      map[string]string
+	Body        []byte
+	ValidTill   time.Time
+	LockedUntil time.Time
+}
+
+func (r *IdempotencyRecord) Completed() bool {
+	return r.Status != 0
+}
@@ -103,8 +103,10 @@ type Landlord struct {
 	Email        string          `json:"email,omitempty"`
 	Phone        string          `json:"phone"`
 	RegisteredBy uuid.UUID       `json:"-"`
+	AssignedTo   *uuid.UUID      `json:"assignedTo,omitempty"`
 	CreatedAt    time.Time       `json:"createdAt"`
 	UpdatedAt    *time.Time      `json:"updatedAt,omitempty"`
+	Version      int             `json:"version"`
 	PropertyInfo []*PropertyInfo `json:"propertyInfo"`
 }
 
@@ -0,0 +1,28 @@
+package entity
+
+import (
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type Lockout struct {
+	LockoutID   int64      `json:"lockoutID"`
+	Key         string     `json:"key"`
+	UserID      *uuid.UUID `json:"userID,omitempty"`
+	IP          string     `json:"ip"`
+	Failures    int        `json:"failures"`
+	LockedUntil time.Time  `json:"lockedUntil"`
+	UnlockedBy  *uuid.UUID `json:"unlockedBy,omitempty"`
+	UnlockedAt  *time.Time `json:"unlockedAt,omitempty"`
+	CreatedAt   time.Time  `json:"createdAt"`
+}
+
+func EmailThrottleKey(email string) string {
+	return "email:" + strings.ToLower(strings.TrimSpace(email))
+}
+
+func IPThrottleKey(ip string) string {
+	return "ip:" + ip
+}
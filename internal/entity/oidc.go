@@ -0,0 +1,23 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type OIDCState struct {
+	Hash      []byte    `json:"-"`
+	Nonce     string    `json:"-"`
+	Verifier  string    `json:"-"`
+	ValidTill time.Time `json:"validTill"`
+}
+
+type Identity struct {
+	Issuer      string     `json:"issuer"`
+	Subject     string     `json:"subject"`
+	UserID      uuid.UUID  `json:"userID"`
+	Email       string     `json:"email"`
+	CreatedAt   time.Time  `json:"createdAt"`
+	LastLoginAt *time.Time `json:"lastLoginAt"`
+}
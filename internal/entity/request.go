@@ -0,0 +1,5 @@
+package entity
+
+type requestIDCtx struct{}
+
+var RequestIDCtxKey = requestIDCtx{}
@@ -0,0 +1,8 @@
+package entity
+
+type BusinessStats struct {
+	Landlords       int64 `json:"landlords"`
+	Tenants         int64 `json:"tenants"`
+	ActiveTenancies int64 `json:"activeTenancies"`
+	ExpiringLeases  int64 `json:"expiringLeases"`
+}
@@ -46,8 +46,10 @@ type Tenant struct {
 	Occupation     string         `json:"occupation"`
 	AdditionalInfo map[string]any `json:"additionalInfo"`
 	RegisteredBy   uuid.UUID      `json:"-"`
+	AssignedTo     *uuid.UUID     `json:"assignedTo,omitempty"`
 	CreatedAt      time.Time      `json:"createdAt"`
 	UpdatedAt      *time.Time     `json:"updatedAt,omitempty"`
+	Version        int            `json:"version"`
 	RentInfo       []*RentInfo    `json:"rentInfo,omitempty"`
 }
 
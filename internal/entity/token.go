@@ -0,0 +1,25 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type TokenPurpose string
+
+const (
+	PurposePasswordReset     TokenPurpose = "password_reset"
+	PurposeEmailVerification TokenPurpose = "email_verification"
+	PurposeInvite            TokenPurpose = "invite"
+	PurposeMFAChallenge      TokenPurpose = "mfa_challenge"
+	PurposeEmailChange       TokenPurpose = "email_change"
+)
+
+type UserToken struct {
+	Hash      []byte       `json:"-"`
+	UserID    uuid.UUID    `json:"userID"`
+	Purpose   TokenPurpose `json:"purpose"`
+	ValidTill time.Time    `json:"validTill"`
+	CreatedAt time.Time    `json:"createdAt"`
+}
@@ -15,18 +15,102 @@ var UserCtxKey = struct{}{}
 
 var AnonymousUser = new(User)
 
+type Role string
+
+const (
+	RoleAdmin   Role = "admin"
+	RoleManager Role = "manager"
+	RoleAgent   Role = "agent"
+)
+
+func (r Role) Valid() bool {
+	switch r {
+	case RoleAdmin, RoleManager, RoleAgent:
+		return true
+	}
+	return false
+}
+
+type UserStatus string
+
+const (
+	StatusPending     UserStatus = "pending"
+	StatusActive      UserStatus = "active"
+	StatusDeactivated UserStatus = "deactivated"
+)
+
 type User struct {
-	UserID    uuid.UUID `json:"userID"`
-	Name      string    `json:"name"`
-	Email     string    `json:"email"`
-	Password  []byte    `json:"password"`
-	CreatedAt time.Time `json:"createdAt"`
+	UserID          uuid.UUID  `json:"userID"`
+	Name            string     `json:"name"`
+	Email           string     `json:"email"`
+	Password        []byte     `json:"-"`
+	Role            Role       `json:"role"`
+	Status          UserStatus `json:"status"`
+	EmailVerifiedAt *time.Time `json:"emailVerifiedAt,omitempty"`
+	PendingEmail    *string    `json:"pendingEmail,omitempty"`
+	TOTPSecret      []byte     `json:"-"`
+	TOTPEnabledAt   *time.Time `json:"totpEnabledAt,omitempty"`
+	TOTPLastStep    int64      `json:"-"`
+	MFARequired     bool       `json:"mfaRequired"`
+	ManagerID       *uuid.UUID `json:"managerID,omitempty"`
+	CreatedAt       time.Time  `json:"createdAt"`
+}
+
+type UserOut struct {
+	UserID          uuid.UUID  `json:"userID"`
+	Name            string     `json:"name"`
+	Email           string     `json:"email"`
+	Role            Role       `json:"role"`
+	Status          UserStatus `json:"status"`
+	EmailVerifiedAt *time.Time `json:"emailVerifiedAt,omitempty"`
+	PendingEmail    *string    `json:"pendingEmail,omitempty"`
+	TOTPEnabledAt   *time.Time `json:"totpEnabledAt,omitempty"`
+	MFARequired     bool       `json:"mfaRequired"`
+	ManagerID       *uuid.UUID `json:"managerID,omitempty"`
+	CreatedAt       time.Time  `json:"createdAt"`
+}
+
+func NewUserOut(u *User) *UserOut {
+	return &UserOut{
+		UserID:          u.UserID,
+		Name:            u.Name,
+		Email:           u.Email,
+		Role:            u.Role,
+		Status:          u.Status,
+		EmailVerifiedAt: u.EmailVerifiedAt,
+		PendingEmail:    u.PendingEmail,
+		TOTPEnabledAt:   u.TOTPEnabledAt,
+		MFARequired:     u.MFARequired,
+		ManagerID:       u.ManagerID,
+		CreatedAt:       u.CreatedAt,
+	}
 }
 
 func (u *User) IsAnonymous() bool {
 	return u == AnonymousUser
 }
 
+func (u *User) IsVerified() bool {
+	return u.EmailVerifiedAt != nil
+}
+
+func (u *User) IsActive() bool {
+	return u.Status == StatusActive
+}
+
+func (u *User) HasTOTP() bool {
+	return u.TOTPEnabledAt != nil
+}
+
+func (u *User) HasRole(roles ...Role) bool {
+	for i := range roles {
+		if u.Role == roles[i] {
+			return true
+		}
+	}
+	return false
+}
+
 type UserIn struct {
 	Name     string `json:"name"`
 	Email    string `json:"email"`
@@ -95,3 +179,251 @@ func ValidateLoginIn(v *validator.Validator, in LoginIn) {
 		},
 	)
 }
+
+type UpdateMeIn struct {
+	Name            *string `json:"name"`
+	Email           *string `json:"email"`
+	CurrentPassword string  `json:"currentPassword"`
+}
+
+func ValidateUpdateMeIn(v *validator.Validator, in UpdateMeIn) {
+	validator.Check(
+		v,
+		in,
+		func(in UpdateMeIn) (bool, validator.ValidationMsg) {
+			return in.Name != nil || in.Email != nil, validator.ValidationMsg{
+				Prop: "name",
+				Info: "provide name or email to update",
+			}
+		},
+		func(in UpdateMeIn) (bool, validator.ValidationMsg) {
+			return in.Name == nil || strings.TrimSpace(*in.Name) != "", validator.ValidationMsg{
+				Prop: "name",
+				Info: "cannot be blank",
+			}
+		},
+		func(in UpdateMeIn) (bool, validator.ValidationMsg) {
+			return in.Email == nil || funclib.ValidEmail(*in.Email), validator.ValidationMsg{
+				Prop: "email",
+				Info: "provide valid email",
+			}
+		},
+	)
+}
+
+type ChangePasswordIn struct {
+	CurrentPassword string `json:"currentPassword"`
+	NewPassword     string `json:"newPassword"`
+}
+
+func ValidateChangePasswordIn(v *validator.Validator, in ChangePasswordIn) {
+	validator.Check(
+		v,
+		in,
+		func(in ChangePasswordIn) (bool, validator.ValidationMsg) {
+			return strings.TrimSpace(in.CurrentPassword) != "", validator.ValidationMsg{
+				Prop: "currentPassword",
+				Info: "cannot be blank",
+			}
+		},
+		func(in ChangePasswordIn) (bool, validator.ValidationMsg) {
+			n := utf8.RuneCountInString(strings.TrimSpace(in.NewPassword))
+			return n >= 8, validator.ValidationMsg{
+				Prop: "newPassword",
+				Info: "cannot be less than 8 characters",
+			}
+		},
+	)
+}
+
+type ForgotPasswordIn struct {
+	Email string `json:"email"`
+}
+
+func ValidateForgotPasswordIn(v *validator.Validator, in ForgotPasswordIn) {
+	validator.Check(
+		v,
+		in,
+		func(in ForgotPasswordIn) (bool, validator.ValidationMsg) {
+			return funclib.ValidEmail(in.Email), validator.ValidationMsg{
+				Prop: "email",
+				Info: "provide valid email",
+			}
+		},
+	)
+}
+
+type ResetPasswordIn struct {
+	Token    string `json:"token"`
+	Password string `json:"password"`
+}
+
+func ValidateResetPasswordIn(v *validator.Validator, in ResetPasswordIn) {
+	validator.Check(
+		v,
+		in,
+		func(in ResetPasswordIn) (bool, validator.ValidationMsg) {
+			return strings.TrimSpace(in.Token) != "", validator.ValidationMsg{
+				Prop: "token",
+				Info: "cannot be blank",
+			}
+		},
+		func(in ResetPasswordIn) (bool, validator.ValidationMsg) {
+			n := utf8.RuneCountInString(strings.TrimSpace(in.Password))
+			return n >= 8, validator.ValidationMsg{
+				Prop: "password",
+				Info: "cannot be less than 8 characters",
+			}
+		},
+	)
+}
+
+type VerifyEmailIn struct {
+	Token string `json:"token"`
+}
+
+func ValidateVerifyEmailIn(v *validator.Validator, in VerifyEmailIn) {
+	validator.Check(
+		v,
+		in,
+		func(in VerifyEmailIn) (bool, validator.ValidationMsg) {
+			return strings.TrimSpace(in.Token) != "", validator.ValidationMsg{
+				Prop: "token",
+				Info: "cannot be blank",
+			}
+		},
+	)
+}
+
+type InviteIn struct {
+	Name  string `json:"name"`
+	Email string `json:"email"`
+	Role  Role   `json:"role"`
+}
+
+func ValidateInviteIn(v *validator.Validator, in InviteIn) {
+	validator.Check(
+		v,
+		in,
+		func(in InviteIn) (bool, validator.ValidationMsg) {
+			return funclib.ValidEmail(in.Email), validator.ValidationMsg{
+				Prop: "email",
+				Info: "provide valid email",
+			}
+		},
+		func(in InviteIn) (bool, validator.ValidationMsg) {
+			return in.Role.Valid(), validator.ValidationMsg{
+				Prop: "role",
+				Info: "must be one of admin, manager or agent",
+			}
+		},
+	)
+}
+
+type AcceptInviteIn struct {
+	Token    string `json:"token"`
+	Name     string `json:"name"`
+	Password string `json:"password"`
+}
+
+func ValidateAcceptInviteIn(v *validator.Validator, in AcceptInviteIn) {
+	validator.Check(
+		v,
+		in,
+		func(in AcceptInviteIn) (bool, validator.ValidationMsg) {
+			return strings.TrimSpace(in.Token) != "", validator.ValidationMsg{
+				Prop: "token",
+				Info: "cannot be blank",
+			}
+		},
+		func(in AcceptInviteIn) (bool, validator.ValidationMsg) {
+			return strings.TrimSpace(in.Name) != "", validator.ValidationMsg{
+				Prop: "name",
+				Info: "cannot be blank",
+			}
+		},
+		func(in AcceptInviteIn) (bool, validator.ValidationMsg) {
+			n := utf8.RuneCountInString(strings.TrimSpace(in.Password))
+			return n >= 8, validator.ValidationMsg{
+				Prop: "password",
+				Info: "cannot be less than 8 characters",
+			}
+		},
+	)
+}
+
+type TOTPCodeIn struct {
+	Code string `json:"code"`
+}
+
+func ValidateTOTPCodeIn(v *validator.Validator, in TOTPCodeIn) {
+	validator.Check(
+		v,
+		in,
+		func(in TOTPCodeIn) (bool, validator.ValidationMsg) {
+			return strings.TrimSpace(in.Code) != "", validator.ValidationMsg{
+				Prop: "code",
+				Info: "cannot be blank",
+			}
+		},
+	)
+}
+
+type DisableTOTPIn struct {
+	Password string `json:"password"`
+	Code     string `json:"code"`
+}
+
+func ValidateDisableTOTPIn(v *validator.Validator, in DisableTOTPIn) {
+	validator.Check(
+		v,
+		in,
+		func(in DisableTOTPIn) (bool, validator.ValidationMsg) {
+			return strings.TrimSpace(in.Password) != "", validator.ValidationMsg{
+				Prop: "password",
+				Info: "cannot be blank",
+			}
+		},
+		func(in DisableTOTPIn) (bool, validator.ValidationMsg) {
+			return strings.TrimSpace(in.Code) != "", validator.ValidationMsg{
+				Prop: "code",
+				Info: "cannot be blank",
+			}
+		},
+	)
+}
+
+type MFALoginIn struct {
+	ChallengeToken string `json:"challengeToken"`
+	Code           string `json:"code"`
+	RecoveryCode   string `json:"recoveryCode"`
+}
+
+func ValidateMFALoginIn(v *validator.Validator, in MFALoginIn) {
+	validator.Check(
+		v,
+		in,
+		func(in MFALoginIn) (bool, validator.ValidationMsg) {
+			return strings.TrimSpace(in.ChallengeToken) != "", validator.ValidationMsg{
+				Prop: "challengeToken",
+				Info: "cannot be blank",
+			}
+		},
+		func(in MFALoginIn) (bool, validator.ValidationMsg) {
+			return (in.Code == "") != (in.RecoveryCode == ""), validator.ValidationMsg{
+				Prop: "code",
+				Info: "provide either code or recoveryCode",
+			}
+		},
+	)
+}
+
+type RolePolicy struct {
+	Role       Role       `json:"role"`
+	Require2FA bool       `json:"require2FA"`
+	UpdatedAt  *time.Time `json:"updatedAt,omitempty"`
+}
+
+type RolePolicyIn struct {
+	Require2FA bool `json:"require2FA"`
+}
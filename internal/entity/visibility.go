@@ -0,0 +1,117 @@
+package entity
+
+import (
+	"slices"
+
+	"github.com/google/uuid"
+
+	"github.com/emma769/a-realtor/internal/validator"
+)
+
+type visibilityCtx struct{}
+
+var VisibilityCtxKey = visibilityCtx{}
+
+const (
+	VisibilityOpen   = "open"
+	VisibilityScoped = "scoped"
+)
+
+type Visibility struct {
+	All     bool
+	UserIDs []uuid.UUID
+}
+
+func (v *Visibility) Allows(owner uuid.UUID) bool {
+	return v.All || slices.Contains(v.UserIDs, owner)
+}
+
+func (v *Visibility) Owners() []string {
+	if v.All {
+		return nil
+	}
+
+	owners := make([]string, len(v.UserIDs))
+
+	for i := range v.UserIDs {
+		owners[i] = v.UserIDs[i].String()
+	}
+
+	return owners
+}
+
+// A record belongs to its assignee, falling back to whoever registered it, so handing a
+// portfolio off also hands off access.
+func owner(registeredBy uuid.UUID, assignedTo *uuid.UUID) uuid.UUID {
+	if assignedTo != nil {
+		return *assignedTo
+	}
+
+	return registeredBy
+}
+
+func (l *Landlord) Owner() uuid.UUID {
+	return owner(l.RegisteredBy, l.AssignedTo)
+}
+
+func (t *Tenant) Owner() uuid.UUID {
+	return owner(t.RegisteredBy, t.AssignedTo)
+}
+
+type PortfolioTransfer struct {
+	From      uuid.UUID `json:"fromUserID"`
+	To        uuid.UUID `json:"toUserID"`
+	Landlords int       `json:"landlords"`
+	Tenants   int       `json:"tenants"`
+}
+
+type AssignIn struct {
+	AssigneeID uuid.UUID `json:"assigneeID"`
+}
+
+func ValidateAssignIn(v *validator.Validator, in AssignIn) {
+	validator.Check(
+		v,
+		in,
+		func(in AssignIn) (bool, validator.ValidationMsg) {
+			return in.AssigneeID != uuid.Nil, validator.ValidationMsg{
+				Prop: "assigneeID",
+				Info: "cannot be blank",
+			}
+		},
+	)
+}
+
+type TransferIn struct {
+	FromUserID uuid.UUID `json:"fromUserID"`
+	ToUserID   uuid.UUID `json:"toUserID"`
+}
+
+func ValidateTransferIn(v *validator.Validator, in TransferIn) {
+	validator.Check(
+		v,
+		in,
+		func(in TransferIn) (bool, validator.ValidationMsg) {
+			return in.FromUserID != uuid.Nil, validator.ValidationMsg{
+				Prop: "fromUserID",
+				Info: "cannot be blank",
+			}
+		},
+		func(in TransferIn) (bool, validator.ValidationMsg) {
+			return in.ToUserID != uuid.Nil, validator.ValidationMsg{
+				Prop: "toUserID",
+				Info: "cannot be blank",
+			}
+		},
+		func(in TransferIn) (bool, validator.ValidationMsg) {
+			return in.FromUserID != in.ToUserID, validator.ValidationMsg{
+				Prop: "toUserID",
+				Info: "must differ from fromUserID",
+			}
+		},
+	)
+}
+
+type SetManagerIn struct {
+	ManagerID *uuid.UUID `json:"managerID"`
+}
@@ -0,0 +1,170 @@
+package export
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+
+	"github.com/google/uuid"
+	"github.com/xuri/excelize/v2"
+
+	"github.com/emma769/a-realtor/internal/entity"
+	funclib "github.com/emma769/a-realtor/internal/lib/func"
+)
+
+const (
+	ContentTypeXlsx = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	ContentTypeCSV  = "text/csv"
+)
+
+const dateLayout = "02/01/2006"
+
+type Table struct {
+	Headers []string
+	Rows    [][]any
+}
+
+func Landlords(landlords []*entity.LandlordOut) *Table {
+	table := &Table{
+		Headers: []string{
+			"Firstname",
+			"Lastname",
+			"Email",
+			"Phone",
+			"Address",
+			"Property Type",
+			"Flat No",
+			"Lease Price",
+			"Lease Period",
+			"Start Date",
+			"End Date",
+		},
+		Rows: make([][]any, len(landlords)),
+	}
+
+	for i, landlord := range landlords {
+		table.Rows[i] = []any{
+			landlord.FirstName,
+			landlord.LastName,
+			landlord.Email,
+			landlord.Phone,
+			landlord.Address,
+			landlord.PropertyType.String(),
+			landlord.AdditionalInfo["flatNo"],
+			landlord.LeasePrice,
+			landlord.LeasePeriod,
+			landlord.StartDate.Format(dateLayout),
+			landlord.EndDate.Format(dateLayout),
+		}
+	}
+
+	return table
+}
+
+func Tenants(
+	tenants []*entity.TenantOut,
+	findLandlord func(uuid.UUID) *entity.Landlord,
+) *Table {
+	table := &Table{
+		Headers: []string{
+			"Firstname",
+			"Lastname",
+			"Phone",
+			"Rent Amount",
+			"Address",
+			"Landlord/Investor",
+			"Landlord/Investor Phone",
+			"Duration",
+			"Start Date",
+			"Maturity Date",
+			"Renewal Date",
+		},
+		Rows: make([][]any, len(tenants)),
+	}
+
+	for i, tenant := range tenants {
+		var landlordName, landlordPhone string
+
+		if landlord := findLandlord(tenant.LandlordID); landlord != nil {
+			landlordName = fmt.Sprintf("%s %s", landlord.FirstName, landlord.LastName)
+			landlordPhone = landlord.Phone
+		}
+
+		table.Rows[i] = []any{
+			tenant.FirstName,
+			tenant.LastName,
+			tenant.Phone,
+			tenant.RentFee,
+			tenant.Address,
+			landlordName,
+			landlordPhone,
+			funclib.DaysBetween(tenant.StartDate, tenant.RenewalDate),
+			tenant.StartDate.Format(dateLayout),
+			tenant.MaturityDate.Format(dateLayout),
+			tenant.RenewalDate.Format(dateLayout),
+		}
+	}
+
+	return table
+}
+
+func (t *Table) Xlsx() (*excelize.File, error) {
+	file := excelize.NewFile()
+
+	for i, header := range t.Headers {
+		if err := file.SetCellValue("Sheet1", cell(i, 1), header); err != nil {
+			return nil, err
+		}
+	}
+
+	for i, row := range t.Rows {
+		for j, col := range row {
+			if err := file.SetCellValue("Sheet1", cell(j, i+2), col); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return file, nil
+}
+
+func (t *Table) WriteXlsx(w io.Writer) error {
+	file, err := t.Xlsx()
+	if err != nil {
+		return err
+	}
+
+	_, err = file.WriteTo(w)
+	return err
+}
+
+func (t *Table) WriteCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+
+	if err := cw.Write(t.Headers); err != nil {
+		return err
+	}
+
+	record := make([]string, len(t.Headers))
+
+	for _, row := range t.Rows {
+		for i, col := range row {
+			record[i] = fmt.Sprint(col)
+
+			if col == nil {
+				record[i] = ""
+			}
+		}
+
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+func cell(col, row int) string {
+	return fmt.Sprintf("%s%d", string(rune(65+col)), row)
+}
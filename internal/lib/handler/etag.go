@@ -0,0 +1,112 @@
+package handlerlib
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+var (
+	ErrPreconditionFailed = NewError(
+		412,
+		CodePreconditionFailed,
+		"resource has changed since it was last read",
+	)
+	errPreconditionRequired = NewError(
+		428,
+		CodePreconditionRequired,
+		"If-Match header is required",
+	)
+)
+
+// WriteJsonETag writes data with a strong ETag, answering conditional reads with 304.
+func WriteJsonETag[T any](
+	w http.ResponseWriter,
+	r *http.Request,
+	code int,
+	etag string,
+	data T,
+) error {
+	w.Header().Set("ETag", etag)
+
+	if notModified(r, etag) {
+		return SendStatus(w, http.StatusNotModified)
+	}
+
+	return WriteJson(w, code, data)
+}
+
+// WriteJsonWeak derives a weak ETag from the encoded body, which is enough for lists whose
+// representation depends on pagination and filters.
+func WriteJsonWeak[T any](w http.ResponseWriter, r *http.Request, code int, data T) error {
+	var buf bytes.Buffer
+
+	if err := json.NewEncoder(&buf).Encode(data); err != nil {
+		return err
+	}
+
+	sum := sha256.Sum256(buf.Bytes())
+	etag := `W/"` + hex.EncodeToString(sum[:16]) + `"`
+
+	w.Header().Set("ETag", etag)
+
+	if notModified(r, etag) {
+		return SendStatus(w, http.StatusNotModified)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_, err := buf.WriteTo(w)
+	return err
+}
+
+func notModified(r *http.Request, etag string) bool {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		return false
+	}
+
+	for _, tag := range etagList(r.Header.Get("If-None-Match")) {
+		if tag == "*" || strings.TrimPrefix(tag, "W/") == strings.TrimPrefix(etag, "W/") {
+			return true
+		}
+	}
+
+	return false
+}
+
+// IfMatch returns the entity tags a write is conditional on. Writes without If-Match are
+// rejected so concurrent edits cannot silently overwrite each other.
+func IfMatch(r *http.Request) ([]string, error) {
+	tags := etagList(r.Header.Get("If-Match"))
+
+	if len(tags) == 0 {
+		return nil, errPreconditionRequired
+	}
+
+	return tags, nil
+}
+
+func MatchETag(tags []string, etag string) bool {
+	for _, tag := range tags {
+		if tag == "*" || (!strings.HasPrefix(tag, "W/") && tag == etag) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func etagList(header string) []string {
+	var tags []string
+
+	for _, tag := range strings.Split(header, ",") {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+
+	return tags
+}
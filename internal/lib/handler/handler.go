@@ -9,6 +9,7 @@ import (
 	"io"
 	"log"
 	"math"
+	"net"
 	"net/http"
 	"net/url"
 	"strconv"
@@ -20,44 +21,12 @@ type RespMsg struct {
 	Message string `json:"message"`
 }
 
-type ErrResp struct {
-	Detail string `json:"error"`
-}
-
-type HandlerError struct {
-	msg  string
-	code int
-}
-
-func (e HandlerError) Error() string {
-	return e.msg
-}
-
-func NewError(code int, msg string) *HandlerError {
-	return &HandlerError{
-		msg,
-		code,
-	}
-}
-
 type HandlerFunc func(http.ResponseWriter, *http.Request) error
 
 func Wrap(fn HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		var he *HandlerError
-
-		err := fn(w, r)
-
-		if err != nil && errors.As(err, &he) {
-			if err := WriteJson(w, he.code, ErrResp{Detail: he.msg}); err != nil {
-				panic(err)
-			}
-
-			return
-		}
-
-		if err != nil {
-			panic(err)
+		if err := fn(w, r); err != nil {
+			WriteProblem(w, r, err)
 		}
 	}
 }
@@ -68,6 +37,13 @@ func WriteJson[T any](w http.ResponseWriter, code int, data T) error {
 	return json.NewEncoder(w).Encode(data)
 }
 
+// WriteJsonNoStore is for bodies carrying credentials, which must not be kept by caches or
+// replayed from stored responses.
+func WriteJsonNoStore[T any](w http.ResponseWriter, code int, data T) error {
+	w.Header().Set("Cache-Control", "no-store")
+	return WriteJson(w, code, data)
+}
+
 func Bind[T any](w http.ResponseWriter, r *http.Request) (T, error) {
 	var t T
 
@@ -88,14 +64,14 @@ func Bind[T any](w http.ResponseWriter, r *http.Request) (T, error) {
 	var invaliderr *json.InvalidUnmarshalError
 
 	if err != nil && errors.As(err, &synerr) {
-		return t, fmt.Errorf("invalid json at position %d", synerr.Offset)
+		return t, bindError(fmt.Sprintf("invalid json at position %d", synerr.Offset))
 	}
 
 	if err != nil && errors.As(err, &typeerr) {
 		if typeerr.Field != "" {
-			return t, fmt.Errorf("invalid json at %s", typeerr.Field)
+			return t, bindError(fmt.Sprintf("invalid json at %s", typeerr.Field))
 		}
-		return t, fmt.Errorf("invalid json at position %d", typeerr.Offset)
+		return t, bindError(fmt.Sprintf("invalid json at position %d", typeerr.Offset))
 	}
 
 	if err != nil && errors.As(err, &invaliderr) {
@@ -103,20 +79,24 @@ func Bind[T any](w http.ResponseWriter, r *http.Request) (T, error) {
 	}
 
 	if err != nil && errors.Is(err, io.EOF) {
-		return t, fmt.Errorf("request body has no content")
+		return t, bindError("request body has no content")
 	}
 
 	if err != nil && errors.Is(err, io.ErrUnexpectedEOF) {
-		return t, fmt.Errorf("malformed json")
+		return t, bindError("malformed json")
 	}
 
 	if err != nil {
-		return t, err
+		return t, bindError(err.Error())
 	}
 
 	return t, nil
 }
 
+func bindError(detail string) *HandlerError {
+	return NewError(http.StatusUnprocessableEntity, CodeMalformedBody, detail)
+}
+
 func SendStatus(w http.ResponseWriter, code int) error {
 	w.WriteHeader(code)
 	return nil
@@ -197,6 +177,24 @@ func GetQueryInt(r *http.Request, name string, fallback int) int {
 	return data
 }
 
+func ClientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+
+	return host
+}
+
+func SetCtxRequestID(r *http.Request, id string) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), entity.RequestIDCtxKey, id))
+}
+
+func GetCtxRequestID(r *http.Request) string {
+	id, _ := r.Context().Value(entity.RequestIDCtxKey).(string)
+	return id
+}
+
 func SetCtxUser(r *http.Request, user *entity.User) *http.Request {
 	return r.WithContext(context.WithValue(r.Context(), entity.UserCtxKey, user))
 }
@@ -210,3 +208,30 @@ func GetCtxUser(r *http.Request) *entity.User {
 
 	return user
 }
+
+func SetCtxAPIKey(r *http.Request, key *entity.APIKey) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), entity.APIKeyCtxKey, key))
+}
+
+func GetCtxAPIKey(r *http.Request) *entity.APIKey {
+	key, _ := r.Context().Value(entity.APIKeyCtxKey).(*entity.APIKey)
+	return key
+}
+
+func SetCtxAuditMeta(r *http.Request, meta *entity.AuditMeta) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), entity.AuditCtxKey, meta))
+}
+
+func SetCtxVisibility(r *http.Request, visibility *entity.Visibility) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), entity.VisibilityCtxKey, visibility))
+}
+
+func GetCtxVisibility(r *http.Request) *entity.Visibility {
+	visibility, ok := r.Context().Value(entity.VisibilityCtxKey).(*entity.Visibility)
+
+	if !ok {
+		panic("no visibility in request context")
+	}
+
+	return visibility
+}
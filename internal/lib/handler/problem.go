@@ -0,0 +1,135 @@
+package handlerlib
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	loglib "github.com/emma769/a-realtor/internal/lib/log"
+	"github.com/emma769/a-realtor/internal/repository"
+	"github.com/emma769/a-realtor/internal/tracing"
+)
+
+const ContentTypeProblem = "application/problem+json"
+
+const (
+	CodeBadRequest           = "bad_request"
+	CodeUnauthorized         = "unauthorized"
+	CodeForbidden            = "forbidden"
+	CodeNotFound             = "not_found"
+	CodeConflict             = "conflict"
+	CodeValidationFailed     = "validation_failed"
+	CodeMalformedBody        = "malformed_body"
+	CodeTooManyRequests      = "too_many_requests"
+	CodeTimeout              = "timeout"
+	CodeInternal             = "internal_error"
+	CodePreconditionFailed   = "precondition_failed"
+	CodePreconditionRequired = "precondition_required"
+)
+
+type Problem struct {
+	Type      string            `json:"type"`
+	Title     string            `json:"title"`
+	Status    int               `json:"status"`
+	Code      string            `json:"code"`
+	Detail    string            `json:"detail,omitempty"`
+	Instance  string            `json:"instance,omitempty"`
+	Errors    map[string]string `json:"errors,omitempty"`
+	RequestID string            `json:"requestId,omitempty"`
+	TraceID   string            `json:"traceId,omitempty"`
+}
+
+type HandlerError struct {
+	status int
+	code   string
+	detail string
+	fields map[string]string
+}
+
+func NewError(status int, code, detail string) *HandlerError {
+	return &HandlerError{
+		status: status,
+		code:   code,
+		detail: detail,
+	}
+}
+
+func ValidationError(fields map[string]string) *HandlerError {
+	return &HandlerError{
+		status: http.StatusUnprocessableEntity,
+		code:   CodeValidationFailed,
+		detail: "request failed validation",
+		fields: fields,
+	}
+}
+
+func (e *HandlerError) Error() string {
+	return e.detail
+}
+
+func (e *HandlerError) Status() int {
+	return e.status
+}
+
+func (e *HandlerError) Code() string {
+	return e.code
+}
+
+func (e *HandlerError) Problem(r *http.Request) *Problem {
+	return &Problem{
+		Type:      "about:blank",
+		Title:     http.StatusText(e.status),
+		Status:    e.status,
+		Code:      e.code,
+		Detail:    e.detail,
+		Instance:  r.URL.Path,
+		Errors:    e.fields,
+		RequestID: GetCtxRequestID(r),
+		TraceID:   tracing.TraceID(r.Context()),
+	}
+}
+
+var (
+	ErrInternal = NewError(500, CodeInternal, "internal server error")
+	errNotFound = NewError(404, CodeNotFound, "resource not found")
+	errConflict = NewError(409, CodeConflict, "resource already exists")
+	errTimeout  = NewError(504, CodeTimeout, "request timed out")
+)
+
+func AsError(err error) *HandlerError {
+	var he *HandlerError
+
+	switch {
+	case errors.As(err, &he):
+		return he
+	case errors.Is(err, repository.ErrNotFound):
+		return errNotFound
+	case errors.Is(err, repository.ErrDuplicateKey):
+		return errConflict
+	case errors.Is(err, repository.ErrVersionConflict):
+		return ErrPreconditionFailed
+	case errors.Is(err, context.DeadlineExceeded):
+		return errTimeout
+	default:
+		return ErrInternal
+	}
+}
+
+func WriteProblem(w http.ResponseWriter, r *http.Request, err error) error {
+	he := AsError(err)
+
+	if he.status >= 500 && he != err {
+		loglib.From(r.Context(), slog.Default()).LogAttrs(
+			r.Context(),
+			slog.LevelError,
+			"request failed",
+			slog.String("detail", err.Error()),
+		)
+	}
+
+	w.Header().Set("Content-Type", ContentTypeProblem)
+	w.WriteHeader(he.status)
+	return json.NewEncoder(w).Encode(he.Problem(r))
+}
@@ -0,0 +1,46 @@
+package loglib
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+)
+
+type scopeCtx struct{}
+
+var scopeCtxKey = scopeCtx{}
+
+type scope struct {
+	mu     sync.RWMutex
+	logger *slog.Logger
+}
+
+func NewContext(ctx context.Context, logger *slog.Logger) context.Context {
+	return context.WithValue(ctx, scopeCtxKey, &scope{logger: logger})
+}
+
+// With adds attrs to the request scoped logger in place, so middleware that
+// created the scope sees attributes added further down the chain.
+func With(ctx context.Context, args ...any) {
+	s, ok := ctx.Value(scopeCtxKey).(*scope)
+	if !ok {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.logger = s.logger.With(args...)
+}
+
+func From(ctx context.Context, fallback *slog.Logger) *slog.Logger {
+	s, ok := ctx.Value(scopeCtxKey).(*scope)
+	if !ok {
+		return fallback
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.logger
+}
@@ -0,0 +1,41 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/url"
+
+	"github.com/emma769/a-realtor/internal/config"
+)
+
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+type Mailer interface {
+	Send(context.Context, *Message) error
+}
+
+func New(cfg *config.Config, logger *slog.Logger) (Mailer, error) {
+	switch cfg.MailerDriver {
+	case "smtp":
+		return NewSMTP(&SMTPOptions{
+			Host:     cfg.SmtpHost,
+			Port:     cfg.SmtpPort,
+			Username: cfg.SmtpUsername,
+			Password: cfg.SmtpPassword,
+			From:     cfg.MailFrom,
+		}), nil
+	case "log":
+		return NewLog(logger), nil
+	default:
+		return nil, fmt.Errorf("unknown mailer driver %q", cfg.MailerDriver)
+	}
+}
+
+func Link(base, path, token string) string {
+	return base + path + "?token=" + url.QueryEscape(token)
+}
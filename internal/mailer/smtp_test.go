@@ -0,0 +1,144 @@
+package mailer
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+type session struct {
+	from, to string
+	data     []string
+}
+
+// serveSMTP accepts one connection and speaks just enough SMTP for net/smtp.
+func serveSMTP(ln net.Listener, got chan<- session) {
+	conn, err := ln.Accept()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	r := bufio.NewReader(conn)
+	reply := func(line string) {
+		conn.Write([]byte(line + "\r\n"))
+	}
+
+	var s session
+
+	reply("220 localhost ESMTP")
+
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+
+		cmd := strings.TrimRight(line, "\r\n")
+
+		switch verb := strings.ToUpper(strings.SplitN(cmd, " ", 2)[0]); verb {
+		case "EHLO", "HELO":
+			reply("250 localhost")
+		case "MAIL":
+			s.from = cmd
+			reply("250 OK")
+		case "RCPT":
+			s.to = cmd
+			reply("250 OK")
+		case "DATA":
+			reply("354 go ahead")
+
+			for {
+				line, err := r.ReadString('\n')
+				if err != nil {
+					return
+				}
+
+				if line = strings.TrimRight(line, "\r\n"); line == "." {
+					break
+				}
+
+				s.data = append(s.data, line)
+			}
+
+			reply("250 OK")
+		case "QUIT":
+			reply("221 bye")
+			got <- s
+			return
+		default:
+			reply("502 unsupported")
+		}
+	}
+}
+
+func TestSMTPSend(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	got := make(chan session, 1)
+	go serveSMTP(ln, got)
+
+	host, port, _ := net.SplitHostPort(ln.Addr().String())
+	p, _ := strconv.Atoi(port)
+
+	m := NewSMTP(&SMTPOptions{Host: host, Port: p, From: "noreply@example.com"})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	err = m.Send(ctx, &Message{
+		To:      "jane@example.com",
+		Subject: "Reset your password",
+		Body:    "line one\nline two",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := <-got
+
+	if s.from != "MAIL FROM:<noreply@example.com>" {
+		t.Errorf("from = %q", s.from)
+	}
+
+	if s.to != "RCPT TO:<jane@example.com>" {
+		t.Errorf("to = %q", s.to)
+	}
+
+	data := strings.Join(s.data, "\n")
+
+	for _, want := range []string{
+		"From: noreply@example.com",
+		"To: jane@example.com",
+		"Subject: Reset your password",
+		"line one\nline two",
+	} {
+		if !strings.Contains(data, want) {
+			t.Errorf("message missing %q:\n%s", want, data)
+		}
+	}
+}
+
+func TestSMTPSendDialError(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	addr := ln.Addr().(*net.TCPAddr)
+	ln.Close()
+
+	m := NewSMTP(&SMTPOptions{Host: "127.0.0.1", Port: addr.Port, From: "noreply@example.com"})
+
+	if err := m.Send(context.Background(), &Message{To: "jane@example.com"}); err == nil {
+		t.Fatal("expected dial error")
+	}
+}
@@ -0,0 +1,159 @@
+package metrics
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	"github.com/emma769/a-realtor/internal/entity"
+)
+
+const namespace = "arealtor"
+
+type Metrics struct {
+	registry *prometheus.Registry
+	requests *prometheus.CounterVec
+	duration *prometheus.HistogramVec
+	queries  *prometheus.HistogramVec
+}
+
+func New() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "http",
+			Name:      "requests_total",
+			Help:      "HTTP requests by method, route pattern and status code.",
+		}, []string{"method", "route", "status"}),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "http",
+			Name:      "request_duration_seconds",
+			Help:      "HTTP request latency by method, route pattern and status code.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"method", "route", "status"}),
+		queries: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "db",
+			Name:      "query_duration_seconds",
+			Help:      "Database query latency by repository method and outcome.",
+			Buckets:   []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+		}, []string{"query", "outcome"}),
+	}
+
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.requests,
+		m.duration,
+		m.queries,
+	)
+
+	return m
+}
+
+func (m *Metrics) ObserveRequest(method, route string, status int, d time.Duration) {
+	code := strconv.Itoa(status)
+	m.requests.WithLabelValues(method, route, code).Inc()
+	m.duration.WithLabelValues(method, route, code).Observe(d.Seconds())
+}
+
+func (m *Metrics) ObserveQuery(name string, d time.Duration, err error) {
+	outcome := "ok"
+
+	if err != nil && err != sql.ErrNoRows {
+		outcome = "error"
+	}
+
+	m.queries.WithLabelValues(name, outcome).Observe(d.Seconds())
+}
+
+func (m *Metrics) RegisterDB(db *sql.DB) {
+	m.registry.MustRegister(collectors.NewDBStatsCollector(db, "postgres"))
+}
+
+type statsStorer interface {
+	BusinessStats(context.Context) (*entity.BusinessStats, error)
+}
+
+func (m *Metrics) RegisterBusiness(store statsStorer, timeout time.Duration) {
+	m.registry.MustRegister(&business{
+		store:   store,
+		timeout: timeout,
+		landlords: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "business", "landlords"),
+			"Registered landlords.", nil, nil,
+		),
+		tenants: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "business", "tenants"),
+			"Registered tenants.", nil, nil,
+		),
+		activeTenancies: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "business", "active_tenancies"),
+			"Tenancies that have started and not yet matured.", nil, nil,
+		),
+		expiringLeases: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "business", "leases_expiring_30d"),
+			"Property leases ending within the next 30 days.", nil, nil,
+		),
+		up: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "business", "stats_up"),
+			"Whether the business statistics query succeeded.", nil, nil,
+		),
+	})
+}
+
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{Registry: m.registry})
+}
+
+type business struct {
+	store   statsStorer
+	timeout time.Duration
+
+	landlords,
+	tenants,
+	activeTenancies,
+	expiringLeases,
+	up *prometheus.Desc
+}
+
+func (b *business) Describe(ch chan<- *prometheus.Desc) {
+	ch <- b.landlords
+	ch <- b.tenants
+	ch <- b.activeTenancies
+	ch <- b.expiringLeases
+	ch <- b.up
+}
+
+func (b *business) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), b.timeout)
+	defer cancel()
+
+	stats, err := b.store.BusinessStats(ctx)
+	if err != nil {
+		ch <- prometheus.MustNewConstMetric(b.up, prometheus.GaugeValue, 0)
+		return
+	}
+
+	ch <- prometheus.MustNewConstMetric(b.up, prometheus.GaugeValue, 1)
+	ch <- prometheus.MustNewConstMetric(b.landlords, prometheus.GaugeValue, float64(stats.Landlords))
+	ch <- prometheus.MustNewConstMetric(b.tenants, prometheus.GaugeValue, float64(stats.Tenants))
+	ch <- prometheus.MustNewConstMetric(
+		b.activeTenancies,
+		prometheus.GaugeValue,
+		float64(stats.ActiveTenancies),
+	)
+	ch <- prometheus.MustNewConstMetric(
+		b.expiringLeases,
+		prometheus.GaugeValue,
+		float64(stats.ExpiringLeases),
+	)
+}
@@ -0,0 +1,23 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/emma769/a-realtor/internal/entity"
+	handlerlib "github.com/emma769/a-realtor/internal/lib/handler"
+)
+
+func Audit(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		meta := &entity.AuditMeta{
+			RequestID: handlerlib.GetCtxRequestID(r),
+			IP:        handlerlib.ClientIP(r),
+		}
+
+		if user := handlerlib.GetCtxUser(r); !user.IsAnonymous() {
+			meta.ActorID = &user.UserID
+		}
+
+		next.ServeHTTP(w, handlerlib.SetCtxAuditMeta(r, meta))
+	})
+}
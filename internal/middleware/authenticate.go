@@ -10,7 +10,9 @@ import (
 
 	"github.com/emma769/a-realtor/internal/entity"
 	handlerlib "github.com/emma769/a-realtor/internal/lib/handler"
+	loglib "github.com/emma769/a-realtor/internal/lib/log"
 	"github.com/emma769/a-realtor/internal/repository"
+	"github.com/emma769/a-realtor/internal/token"
 )
 
 type manager interface {
@@ -19,6 +21,8 @@ type manager interface {
 
 type storer interface {
 	FindUserByID(context.Context, uuid.UUID) (*entity.User, error)
+	FindActiveAPIKey(context.Context, string) (*entity.APIKey, error)
+	TouchAPIKey(context.Context, uuid.UUID) error
 }
 
 type AuthService struct {
@@ -30,33 +34,109 @@ func NewAuthService(mgr manager, store storer) *AuthService {
 	return &AuthService{mgr, store}
 }
 
+var errUnauthorized = errors.New("unauthorized")
+
+func (svc *AuthService) userFromAPIKey(
+	ctx context.Context,
+	plain string,
+) (*entity.User, *entity.APIKey, error) {
+	prefix, ok := token.ParseAPIKey(plain)
+	if !ok {
+		return nil, nil, errUnauthorized
+	}
+
+	key, err := svc.store.FindActiveAPIKey(ctx, prefix)
+
+	if err != nil && errors.Is(err, repository.ErrNotFound) {
+		return nil, nil, errUnauthorized
+	}
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if !token.CompareAPIKey(plain, key.Hash) {
+		return nil, nil, errUnauthorized
+	}
+
+	user, err := svc.store.FindUserByID(ctx, key.UserID)
+
+	if err != nil && errors.Is(err, repository.ErrNotFound) {
+		return nil, nil, errUnauthorized
+	}
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if err := svc.store.TouchAPIKey(ctx, key.APIKeyID); err != nil {
+		return nil, nil, err
+	}
+
+	return user, key, nil
+}
+
 func Authenticate(svc *AuthService) func(next http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			w.Header().Add("Vary", "Authorization")
+			w.Header().Add("Vary", "X-API-Key")
 
 			auth := r.Header.Get("Authorization")
+			apiKey := r.Header.Get("X-API-Key")
 
-			if strings.TrimSpace(auth) == "" {
+			if strings.TrimSpace(auth) == "" && strings.TrimSpace(apiKey) == "" {
 				next.ServeHTTP(w, handlerlib.SetCtxUser(r, entity.AnonymousUser))
 				return
 			}
 
 			parts := strings.Fields(auth)
 
-			if len(parts) != 2 {
-				handlerlib.WriteJson(w, 401, handlerlib.ErrResp{
-					Detail: "unauthorized",
-				})
+			if apiKey == "" && len(parts) != 2 {
+				handlerlib.WriteProblem(w, r, errUnauthenticated)
 
 				return
 			}
 
+			if apiKey == "" && parts[0] == "ApiKey" {
+				apiKey = parts[1]
+			}
+
+			if apiKey != "" {
+				user, key, err := svc.userFromAPIKey(r.Context(), apiKey)
+
+				if err != nil && errors.Is(err, errUnauthorized) {
+					w.Header().Set("WWW-Authenticate", "ApiKey")
+					handlerlib.WriteProblem(w, r, errUnauthenticated)
+
+					return
+				}
+
+				if err != nil {
+					handlerlib.WriteProblem(w, r, err)
+					return
+				}
+
+				if !user.IsActive() {
+					handlerlib.WriteProblem(w, r, errUnauthenticated)
+
+					return
+				}
+
+				loglib.With(
+					r.Context(),
+					"user_id", user.UserID.String(),
+					"api_key_id", key.APIKeyID.String(),
+				)
+
+				r = handlerlib.SetCtxAPIKey(r, key)
+				next.ServeHTTP(w, handlerlib.SetCtxUser(r, user))
+				return
+			}
+
 			if parts[0] != "Bearer" {
 				w.Header().Set("WWW-Authenticate", "Bearer")
-				handlerlib.WriteJson(w, 401, handlerlib.ErrResp{
-					Detail: "unauthorized",
-				})
+				handlerlib.WriteProblem(w, r, errUnauthenticated)
 
 				return
 			}
@@ -64,9 +144,7 @@ func Authenticate(svc *AuthService) func(next http.Handler) http.Handler {
 			id, err := svc.mgr.DecodeAccessToken(parts[1])
 			if err != nil {
 				w.Header().Set("WWW-Authenticate", "Bearer")
-				handlerlib.WriteJson(w, 401, handlerlib.ErrResp{
-					Detail: "unauthorized",
-				})
+				handlerlib.WriteProblem(w, r, errUnauthenticated)
 
 				return
 			}
@@ -74,17 +152,24 @@ func Authenticate(svc *AuthService) func(next http.Handler) http.Handler {
 			user, err := svc.store.FindUserByID(r.Context(), id)
 
 			if err != nil && errors.Is(err, repository.ErrNotFound) {
-				handlerlib.WriteJson(w, 401, handlerlib.ErrResp{
-					Detail: "unauthorized",
-				})
+				handlerlib.WriteProblem(w, r, errUnauthenticated)
 
 				return
 			}
 
 			if err != nil {
-				panic(err)
+				handlerlib.WriteProblem(w, r, err)
+				return
 			}
 
+			if !user.IsActive() {
+				handlerlib.WriteProblem(w, r, errUnauthenticated)
+
+				return
+			}
+
+			loglib.With(r.Context(), "user_id", user.UserID.String())
+
 			next.ServeHTTP(w, handlerlib.SetCtxUser(r, user))
 		})
 	}
@@ -6,9 +6,11 @@ import (
 )
 
 type CorsOptions struct {
-	Origins []string
-	Headers []string
-	Methods []string
+	Origins          []string
+	Headers          []string
+	Methods          []string
+	ExposedHeaders   []string
+	AllowCredentials bool
 }
 
 func EnableCorsWithOptions(opts *CorsOptions) func(next http.Handler) http.Handler {
@@ -25,6 +27,15 @@ func EnableCorsWithOptions(opts *CorsOptions) func(next http.Handler) http.Handl
 					if opts.Origins[i] == origin {
 						w.Header().Set("Access-Control-Allow-Origin", origin)
 
+						if opts.AllowCredentials {
+							w.Header().Set("Access-Control-Allow-Credentials", "true")
+						}
+
+						if len(opts.ExposedHeaders) > 0 {
+							exposed := strings.Join(opts.ExposedHeaders, ", ")
+							w.Header().Set("Access-Control-Expose-Headers", exposed)
+						}
+
 						if r.Method == "OPTIONS" && method != "" {
 							methods := strings.Join(opts.Methods, ", ")
 							w.Header().Set("Access-Control-Allow-Methods", methods)
@@ -0,0 +1,23 @@
+package middleware
+
+import handlerlib "github.com/emma769/a-realtor/internal/lib/handler"
+
+var (
+	errUnauthenticated = handlerlib.NewError(401, handlerlib.CodeUnauthorized, "unauthorized")
+	errForbidden       = handlerlib.NewError(403, handlerlib.CodeForbidden, "forbidden")
+	errSessionRequired = handlerlib.NewError(
+		403,
+		"auth.session_required",
+		"not available to api keys",
+	)
+	errUnverified = handlerlib.NewError(
+		403,
+		"auth.email_unverified",
+		"email address not verified",
+	)
+	errTwoFactorEnrolment = handlerlib.NewError(
+		403,
+		"auth.two_factor_enrolment_required",
+		"two-factor enrolment required",
+	)
+)
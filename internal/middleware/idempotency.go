@@ -0,0 +1,267 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"io"
+	"log/slog"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/emma769/a-realtor/internal/entity"
+	handlerlib "github.com/emma769/a-realtor/internal/lib/handler"
+	loglib "github.com/emma769/a-realtor/internal/lib/log"
+)
+
+const (
+	idempotencyHeader    = "Idempotency-Key"
+	idempotencyReplayed  = "Idempotent-Replayed"
+	maxIdempotencyKeyLen = 255
+	maxIdempotentBody    = 1_048_576
+)
+
+var replayHeaders = []string{"Content-Type", "Location", "ETag", "Cache-Control"}
+
+var (
+	errIdempotencyKeyInvalid = handlerlib.NewError(
+		400,
+		"idempotency.invalid_key",
+		"idempotency key must be between 1 and 255 characters",
+	)
+	errIdempotencyKeyReused = handlerlib.NewError(
+		422,
+		"idempotency.key_reused",
+		"idempotency key was already used for a different request",
+	)
+	errIdempotencyInProgress = handlerlib.NewError(
+		409,
+		"idempotency.in_progress",
+		"a request with this idempotency key is still being processed, retry after Retry-After",
+	)
+	errIdempotencyNotReplayable = handlerlib.NewError(
+		409,
+		"idempotency.not_replayable",
+		"the original response carried credentials and cannot be replayed",
+	)
+)
+
+type idempotencyStore interface {
+	ReserveIdempotencyKey(
+		context.Context,
+		*entity.IdempotencyRecord,
+	) (*entity.IdempotencyRecord, bool, error)
+	CompleteIdempotencyKey(context.Context, *entity.IdempotencyRecord) error
+	ReleaseIdempotencyKey(context.Context, uuid.UUID, string) error
+}
+
+type IdempotencyOptions struct {
+	Store idempotencyStore
+	TTL   time.Duration
+	// Lease is how long a request may hold its key before a retry can take it over, which frees
+	// keys left in progress by a crashed process.
+	Lease time.Duration
+	*slog.Logger
+}
+
+func IdempotencyWithOptions(opts *IdempotencyOptions) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key, ok := r.Header[http.CanonicalHeaderKey(idempotencyHeader)]
+			user := handlerlib.GetCtxUser(r)
+
+			if !ok || !mutating(r.Method) || user.IsAnonymous() {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if len(key[0]) == 0 || len(key[0]) > maxIdempotencyKeyLen {
+				handlerlib.WriteProblem(w, r, errIdempotencyKeyInvalid)
+				return
+			}
+
+			body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxIdempotentBody))
+			if err != nil {
+				handlerlib.WriteProblem(w, r, handlerlib.NewError(
+					413,
+					handlerlib.CodeMalformedBody,
+					"request body could not be read",
+				))
+				return
+			}
+
+			r.Body = io.NopCloser(bytes.NewReader(body))
+
+			rec := &entity.IdempotencyRecord{
+				UserID:      user.UserID,
+				Key:         key[0],
+				RequestHash: requestHash(r, body),
+				ValidTill:   time.Now().Add(opts.TTL),
+				LockedUntil: time.Now().Add(opts.Lease),
+			}
+
+			existing, reserved, err := opts.Store.ReserveIdempotencyKey(r.Context(), rec)
+			if err != nil {
+				handlerlib.WriteProblem(w, r, err)
+				return
+			}
+
+			if !reserved {
+				replay(w, r, rec, existing)
+				return
+			}
+
+			capture := &captureWriter{ResponseWriter: w}
+			completed := false
+
+			defer func() {
+				if completed {
+					return
+				}
+
+				ctx, cancel := detachedContext(r)
+				defer cancel()
+
+				if err := opts.Store.ReleaseIdempotencyKey(ctx, rec.UserID, rec.Key); err != nil {
+					idempotencyFailed(ctx, opts.Logger, err)
+				}
+			}()
+
+			next.ServeHTTP(capture, r)
+
+			if capture.Status() >= 500 {
+				return
+			}
+
+			rec.Status = capture.Status()
+			rec.Header = map[string]string{}
+
+			for _, name := range replayHeaders {
+				if v := capture.Header().Get(name); v != "" {
+					rec.Header[name] = v
+				}
+			}
+
+			// Secrets such as API keys and recovery codes are never written to the store; only
+			// the fact that the request completed is.
+			if !noStore(rec.Header) {
+				rec.Body = capture.body.Bytes()
+			}
+
+			ctx, cancel := detachedContext(r)
+			defer cancel()
+
+			if err := opts.Store.CompleteIdempotencyKey(ctx, rec); err != nil {
+				idempotencyFailed(ctx, opts.Logger, err)
+				return
+			}
+
+			completed = true
+		})
+	}
+}
+
+func replay(
+	w http.ResponseWriter,
+	r *http.Request,
+	rec, existing *entity.IdempotencyRecord,
+) {
+	if subtle.ConstantTimeCompare(rec.RequestHash, existing.RequestHash) != 1 {
+		handlerlib.WriteProblem(w, r, errIdempotencyKeyReused)
+		return
+	}
+
+	if !existing.Completed() {
+		wait := max(time.Until(existing.LockedUntil), time.Second)
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+		handlerlib.WriteProblem(w, r, errIdempotencyInProgress)
+		return
+	}
+
+	if noStore(existing.Header) {
+		handlerlib.WriteProblem(w, r, errIdempotencyNotReplayable)
+		return
+	}
+
+	for name, v := range existing.Header {
+		w.Header().Set(name, v)
+	}
+
+	w.Header().Set(idempotencyReplayed, "true")
+	w.WriteHeader(existing.Status)
+	w.Write(existing.Body)
+}
+
+func noStore(header map[string]string) bool {
+	return strings.Contains(header["Cache-Control"], "no-store")
+}
+
+func mutating(method string) bool {
+	switch method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		return true
+	}
+
+	return false
+}
+
+func requestHash(r *http.Request, body []byte) []byte {
+	h := sha256.New()
+	h.Write([]byte(r.Method + " " + r.URL.Path + "\n"))
+	h.Write(body)
+	return h.Sum(nil)
+}
+
+func detachedContext(r *http.Request) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.WithoutCancel(r.Context()), 5*time.Second)
+}
+
+func idempotencyFailed(ctx context.Context, logger *slog.Logger, err error) {
+	loglib.From(ctx, logger).LogAttrs(
+		ctx,
+		slog.LevelError,
+		"could not store idempotent response",
+		slog.String("detail", err.Error()),
+	)
+}
+
+type captureWriter struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (cw *captureWriter) WriteHeader(code int) {
+	if cw.status == 0 {
+		cw.status = code
+	}
+
+	cw.ResponseWriter.WriteHeader(code)
+}
+
+func (cw *captureWriter) Write(b []byte) (int, error) {
+	if cw.status == 0 {
+		cw.status = http.StatusOK
+	}
+
+	cw.body.Write(b)
+	return cw.ResponseWriter.Write(b)
+}
+
+func (cw *captureWriter) Status() int {
+	if cw.status == 0 {
+		return http.StatusOK
+	}
+
+	return cw.status
+}
+
+func (cw *captureWriter) Unwrap() http.ResponseWriter {
+	return cw.ResponseWriter
+}
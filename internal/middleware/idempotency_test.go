@@ -0,0 +1,162 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/emma769/a-realtor/internal/entity"
+	handlerlib "github.com/emma769/a-realtor/internal/lib/handler"
+)
+
+type memoryIdempotency struct {
+	records map[string]*entity.IdempotencyRecord
+}
+
+func (m *memoryIdempotency) ReserveIdempotencyKey(
+	_ context.Context,
+	rec *entity.IdempotencyRecord,
+) (*entity.IdempotencyRecord, bool, error) {
+	existing, ok := m.records[rec.Key]
+
+	if ok && (existing.Completed() || time.Now().Before(existing.LockedUntil)) {
+		return existing, false, nil
+	}
+
+	m.records[rec.Key] = &entity.IdempotencyRecord{
+		RequestHash: rec.RequestHash,
+		LockedUntil: rec.LockedUntil,
+	}
+
+	return nil, true, nil
+}
+
+func (m *memoryIdempotency) CompleteIdempotencyKey(
+	_ context.Context,
+	rec *entity.IdempotencyRecord,
+) error {
+	stored := *rec
+	m.records[rec.Key] = &stored
+	return nil
+}
+
+func (m *memoryIdempotency) ReleaseIdempotencyKey(
+	_ context.Context,
+	_ uuid.UUID,
+	key string,
+) error {
+	delete(m.records, key)
+	return nil
+}
+
+func idempotent(t *testing.T, h http.HandlerFunc) (http.Handler, *memoryIdempotency) {
+	t.Helper()
+
+	store := &memoryIdempotency{records: map[string]*entity.IdempotencyRecord{}}
+	user := &entity.User{UserID: uuid.New()}
+
+	mw := IdempotencyWithOptions(&IdempotencyOptions{
+		Store: store,
+		TTL:   time.Hour,
+		Lease: time.Minute,
+	})
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mw(h).ServeHTTP(w, handlerlib.SetCtxUser(r, user))
+	}), store
+}
+
+func post(h http.Handler, key string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/api/keys", strings.NewReader(`{}`))
+	req.Header.Set(idempotencyHeader, key)
+
+	res := httptest.NewRecorder()
+	h.ServeHTTP(res, req)
+
+	return res
+}
+
+func TestIdempotencyReplaysResponse(t *testing.T) {
+	calls := 0
+
+	h, _ := idempotent(t, func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		handlerlib.WriteJson(w, 201, map[string]string{"id": "1"})
+	})
+
+	first, second := post(h, "k1"), post(h, "k1")
+
+	if calls != 1 {
+		t.Fatalf("handler ran %d times, want 1", calls)
+	}
+
+	if second.Code != 201 || second.Body.String() != first.Body.String() {
+		t.Errorf("replay = %d %q, want 201 %q", second.Code, second.Body, first.Body)
+	}
+
+	if second.Header().Get(idempotencyReplayed) != "true" {
+		t.Error("replay is not marked")
+	}
+}
+
+func TestIdempotencyNeverStoresSecrets(t *testing.T) {
+	const secret = "ak_live_plaintext"
+
+	calls := 0
+
+	h, store := idempotent(t, func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		handlerlib.WriteJsonNoStore(w, 201, map[string]string{"key": secret})
+	})
+
+	if first := post(h, "k1"); !strings.Contains(first.Body.String(), secret) {
+		t.Fatalf("first response = %q, want the secret", first.Body)
+	}
+
+	if rec := store.records["k1"]; !rec.Completed() || len(rec.Body) != 0 {
+		t.Fatalf("stored record = %d %q, want completed without a body", rec.Status, rec.Body)
+	}
+
+	second := post(h, "k1")
+
+	if calls != 1 {
+		t.Fatalf("handler ran %d times, want 1", calls)
+	}
+
+	if second.Code != 409 || strings.Contains(second.Body.String(), secret) {
+		t.Errorf("replay = %d %q, want a 409 without the secret", second.Code, second.Body)
+	}
+}
+
+func TestIdempotencyLeaseExpires(t *testing.T) {
+	calls := 0
+
+	h, store := idempotent(t, func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		handlerlib.WriteJson(w, 201, map[string]string{"id": "1"})
+	})
+
+	// A first attempt that reserved the key and never completed.
+	post(h, "k1")
+	store.records["k1"].Status = 0
+	store.records["k1"].LockedUntil = time.Now().Add(30 * time.Second)
+
+	held := post(h, "k1")
+
+	if held.Code != 409 || held.Header().Get("Retry-After") != "30" {
+		t.Fatalf("held key = %d, Retry-After %q, want 409 after 30s", held.Code,
+			held.Header().Get("Retry-After"))
+	}
+
+	store.records["k1"].LockedUntil = time.Now().Add(-time.Second)
+
+	if taken := post(h, "k1"); taken.Code != 201 || calls != 2 {
+		t.Fatalf("expired lease = %d after %d calls, want the request to run again", taken.Code,
+			calls)
+	}
+}
@@ -1,10 +1,12 @@
 package middleware
 
 import (
-	"fmt"
 	"log/slog"
 	"net/http"
 	"time"
+
+	handlerlib "github.com/emma769/a-realtor/internal/lib/handler"
+	loglib "github.com/emma769/a-realtor/internal/lib/log"
 )
 
 type LoggerOptions struct {
@@ -15,17 +17,24 @@ func LoggerWithOptions(opts *LoggerOptions) func(next http.Handler) http.Handler
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			now := time.Now()
+			rec := wrapWriter(w)
+
+			ctx := loglib.NewContext(r.Context(), opts.With(
+				slog.String("request_id", handlerlib.GetCtxRequestID(r)),
+			))
 
 			defer func() {
-				attrs := []slog.Attr{
-					{Key: "method", Value: slog.StringValue(r.Method)},
-					{Key: "path", Value: slog.StringValue(r.RequestURI)},
-					{Key: "latency", Value: slog.StringValue(fmt.Sprintf("%v", time.Since(now)))},
-				}
-				opts.LogAttrs(r.Context(), slog.LevelInfo, "incoming request", attrs...)
+				loglib.From(ctx, opts.Logger).LogAttrs(ctx, slog.LevelInfo, "incoming request",
+					slog.String("method", r.Method),
+					slog.String("path", r.RequestURI),
+					slog.Int("status", rec.Status()),
+					slog.Int("bytes", rec.Bytes()),
+					slog.String("ip", handlerlib.ClientIP(r)),
+					slog.Duration("latency", time.Since(now)),
+				)
 			}()
 
-			next.ServeHTTP(w, r)
+			next.ServeHTTP(rec, r.WithContext(ctx))
 		})
 	}
 }
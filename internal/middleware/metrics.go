@@ -0,0 +1,65 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	handlerlib "github.com/emma769/a-realtor/internal/lib/handler"
+)
+
+type requestObserver interface {
+	ObserveRequest(method, route string, status int, d time.Duration)
+}
+
+func Metrics(m requestObserver) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			now := time.Now()
+			rec := wrapWriter(w)
+
+			defer func() {
+				route := "unmatched"
+
+				if rctx := chi.RouteContext(r.Context()); rctx != nil && rctx.RoutePattern() != "" {
+					route = rctx.RoutePattern()
+				}
+
+				m.ObserveRequest(r.Method, route, rec.Status(), time.Since(now))
+			}()
+
+			next.ServeHTTP(rec, r)
+		})
+	}
+}
+
+func RestrictMetrics(token string, networks []string) (func(http.Handler) http.Handler, error) {
+	nets, err := parseNetworks(networks)
+	if err != nil {
+		return nil, err
+	}
+
+	allowed := func(r *http.Request) bool {
+		if token != "" {
+			given := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+			return subtle.ConstantTimeCompare([]byte(given), []byte(token)) == 1
+		}
+
+		return nets.contains(net.ParseIP(handlerlib.ClientIP(r)))
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !allowed(r) {
+				handlerlib.WriteProblem(w, r, errForbidden)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}, nil
+}
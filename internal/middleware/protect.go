@@ -3,6 +3,7 @@ package middleware
 import (
 	"net/http"
 
+	"github.com/emma769/a-realtor/internal/entity"
 	handlerlib "github.com/emma769/a-realtor/internal/lib/handler"
 )
 
@@ -11,9 +12,61 @@ func RequireAuth(next http.Handler) http.Handler {
 		user := handlerlib.GetCtxUser(r)
 
 		if user.IsAnonymous() {
-			handlerlib.WriteJson(w, 401, map[string]string{
-				"error": "unauthorized",
-			})
+			handlerlib.WriteProblem(w, r, errUnauthenticated)
+
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func RequireRole(roles ...entity.Role) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			user := handlerlib.GetCtxUser(r)
+
+			if user.IsAnonymous() {
+				handlerlib.WriteProblem(w, r, errUnauthenticated)
+
+				return
+			}
+
+			if !user.HasRole(roles...) {
+				handlerlib.WriteProblem(w, r, errForbidden)
+
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func RequireScope(scope string) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := handlerlib.GetCtxAPIKey(r)
+
+			if key != nil && !key.HasScope(scope) {
+				handlerlib.WriteProblem(w, r, handlerlib.NewError(
+					403,
+					"auth.missing_scope",
+					"api key is missing scope "+scope,
+				))
+
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func RequireSession(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if handlerlib.GetCtxAPIKey(r) != nil {
+			handlerlib.WriteProblem(w, r, errSessionRequired)
 
 			return
 		}
@@ -0,0 +1,125 @@
+package middleware
+
+import (
+	"fmt"
+	"log/slog"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	handlerlib "github.com/emma769/a-realtor/internal/lib/handler"
+	loglib "github.com/emma769/a-realtor/internal/lib/log"
+	"github.com/emma769/a-realtor/internal/ratelimit"
+)
+
+var errRateLimited = handlerlib.NewError(
+	429,
+	handlerlib.CodeTooManyRequests,
+	"rate limit exceeded, try again later",
+)
+
+type RateLimitPolicy struct {
+	Name  string
+	Limit ratelimit.Limit
+	Match func(*http.Request) bool
+	Key   func(*http.Request) string
+}
+
+type RateLimitOptions struct {
+	Limiter  ratelimit.Limiter
+	Policies []RateLimitPolicy
+	*slog.Logger
+}
+
+func MatchPrefix(prefix string) func(*http.Request) bool {
+	return func(r *http.Request) bool {
+		return strings.HasPrefix(r.URL.Path, prefix)
+	}
+}
+
+func MatchSuffix(suffix string) func(*http.Request) bool {
+	return func(r *http.Request) bool {
+		return strings.HasSuffix(r.URL.Path, suffix)
+	}
+}
+
+func KeyByIP(r *http.Request) string {
+	return "ip:" + handlerlib.ClientIP(r)
+}
+
+func KeyByClient(r *http.Request) string {
+	if key := handlerlib.GetCtxAPIKey(r); key != nil {
+		return "key:" + key.APIKeyID.String()
+	}
+
+	if user := handlerlib.GetCtxUser(r); !user.IsAnonymous() {
+		return "user:" + user.UserID.String()
+	}
+
+	return KeyByIP(r)
+}
+
+func RateLimitWithOptions(opts *RateLimitOptions) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			policy, ok := matchPolicy(opts.Policies, r)
+			if !ok || r.Method == http.MethodOptions {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			res, err := opts.Limiter.Take(r.Context(), policy.Name+":"+policy.Key(r), policy.Limit)
+			if err != nil {
+				loglib.From(r.Context(), opts.Logger).LogAttrs(
+					r.Context(),
+					slog.LevelError,
+					"rate limiter unavailable",
+					slog.String("policy", policy.Name),
+					slog.String("detail", err.Error()),
+				)
+
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			w.Header().Set("RateLimit-Policy", fmt.Sprintf(
+				"%d;w=%d",
+				policy.Limit.Requests,
+				int(policy.Limit.Period.Seconds()),
+			))
+			w.Header().Set("RateLimit-Limit", strconv.Itoa(res.Limit))
+			w.Header().Set("RateLimit-Remaining", strconv.Itoa(res.Remaining))
+			w.Header().Set("RateLimit-Reset", ceilSeconds(res.Reset))
+
+			if !res.Allowed {
+				w.Header().Set("Retry-After", ceilSeconds(res.RetryAfter))
+				handlerlib.WriteProblem(w, r, errRateLimited)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func matchPolicy(policies []RateLimitPolicy, r *http.Request) (RateLimitPolicy, bool) {
+	for _, p := range policies {
+		if p.Match != nil && !p.Match(r) {
+			continue
+		}
+
+		if p.Key == nil {
+			p.Key = KeyByClient
+		}
+
+		return p, true
+	}
+
+	return RateLimitPolicy{}, false
+}
+
+func ceilSeconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
+}
@@ -0,0 +1,92 @@
+package middleware
+
+import (
+	"net"
+	"net/http"
+	"strings"
+)
+
+type networks []*net.IPNet
+
+func parseNetworks(cidrs []string) (networks, error) {
+	nets := make(networks, 0, len(cidrs))
+
+	for _, cidr := range cidrs {
+		_, n, err := net.ParseCIDR(strings.TrimSpace(cidr))
+		if err != nil {
+			return nil, err
+		}
+
+		nets = append(nets, n)
+	}
+
+	return nets, nil
+}
+
+func (nets networks) contains(ip net.IP) bool {
+	for _, n := range nets {
+		if ip != nil && n.Contains(ip) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// X-Forwarded-For is walked right to left so clients cannot spoof past the proxies.
+func RealIP(trusted []string) (func(http.Handler) http.Handler, error) {
+	nets, err := parseNetworks(trusted)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			host, port, err := net.SplitHostPort(r.RemoteAddr)
+			if err != nil {
+				host = r.RemoteAddr
+			}
+
+			if nets.contains(net.ParseIP(host)) {
+				if ip := forwardedFor(r, nets); ip != "" {
+					r.RemoteAddr = net.JoinHostPort(ip, port)
+				}
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}, nil
+}
+
+func forwardedFor(r *http.Request, trusted networks) string {
+	var hops []string
+
+	for _, h := range r.Header.Values("X-Forwarded-For") {
+		hops = append(hops, strings.Split(h, ",")...)
+	}
+
+	client := ""
+
+	for i := len(hops) - 1; i >= 0; i-- {
+		ip := net.ParseIP(strings.TrimSpace(hops[i]))
+		if ip == nil {
+			break
+		}
+
+		client = ip.String()
+
+		if !trusted.contains(ip) {
+			return client
+		}
+	}
+
+	if client != "" {
+		return client
+	}
+
+	if ip := net.ParseIP(strings.TrimSpace(r.Header.Get("X-Real-IP"))); ip != nil {
+		return ip.String()
+	}
+
+	return ""
+}
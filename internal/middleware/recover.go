@@ -7,6 +7,7 @@ import (
 	"runtime/debug"
 
 	handlerlib "github.com/emma769/a-realtor/internal/lib/handler"
+	loglib "github.com/emma769/a-realtor/internal/lib/log"
 )
 
 type RecoverOptions struct {
@@ -22,13 +23,10 @@ func RecoverWithOptions(opts *RecoverOptions) func(next http.Handler) http.Handl
 						{Key: "detail", Value: slog.StringValue(fmt.Sprintf("%v", err))},
 						{Key: "trace", Value: slog.StringValue(string(debug.Stack()))},
 					}
-					opts.LogAttrs(r.Context(), slog.LevelError, "server error", attrs...)
+					loglib.From(r.Context(), opts.Logger).
+						LogAttrs(r.Context(), slog.LevelError, "server error", attrs...)
 
-					if err := handlerlib.WriteJson(w, 500, handlerlib.ErrResp{
-						Detail: "internal server error",
-					}); err != nil {
-						panic(err)
-					}
+					handlerlib.WriteProblem(w, r, handlerlib.ErrInternal)
 				}
 			}()
 			next.ServeHTTP(w, r)
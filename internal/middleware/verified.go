@@ -0,0 +1,35 @@
+package middleware
+
+import (
+	"net/http"
+
+	handlerlib "github.com/emma769/a-realtor/internal/lib/handler"
+)
+
+func RequireVerified(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user := handlerlib.GetCtxUser(r)
+
+		if !user.IsAnonymous() && !user.IsVerified() {
+			handlerlib.WriteProblem(w, r, errUnverified)
+
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func RequireTwoFactor(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user := handlerlib.GetCtxUser(r)
+
+		if !user.IsAnonymous() && user.MFARequired && !user.HasTOTP() {
+			handlerlib.WriteProblem(w, r, errTwoFactorEnrolment)
+
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
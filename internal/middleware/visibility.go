@@ -0,0 +1,43 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/google/uuid"
+
+	"github.com/emma769/a-realtor/internal/entity"
+	handlerlib "github.com/emma769/a-realtor/internal/lib/handler"
+)
+
+type teamStorer interface {
+	FindTeamMemberIDs(context.Context, uuid.UUID) ([]uuid.UUID, error)
+}
+
+func Visibility(store teamStorer, mode string) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			user := handlerlib.GetCtxUser(r)
+
+			visibility := &entity.Visibility{All: true}
+
+			if mode == entity.VisibilityScoped && !user.IsAnonymous() {
+				switch user.Role {
+				case entity.RoleAdmin:
+				case entity.RoleManager:
+					team, err := store.FindTeamMemberIDs(r.Context(), user.UserID)
+					if err != nil {
+						handlerlib.WriteProblem(w, r, err)
+						return
+					}
+
+					visibility = &entity.Visibility{UserIDs: append(team, user.UserID)}
+				default:
+					visibility = &entity.Visibility{UserIDs: []uuid.UUID{user.UserID}}
+				}
+			}
+
+			next.ServeHTTP(w, handlerlib.SetCtxVisibility(r, visibility))
+		})
+	}
+}
@@ -0,0 +1,381 @@
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"regexp"
+	"slices"
+	"strconv"
+)
+
+const lockID int64 = 7289401752
+
+var (
+	ErrDirty       = errors.New("database is dirty, fix it and force a version")
+	ErrNoChange    = errors.New("no change")
+	ErrUnknownStep = errors.New("unknown migration version")
+)
+
+var filename = regexp.MustCompile(`^(\d+)_(.+)\.(up|down)\.sql$`)
+
+type Migration struct {
+	Version uint
+	Name    string
+	Up      string
+	Down    string
+}
+
+func Load(fsys fs.FS) ([]*Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := map[uint]*Migration{}
+
+	for _, e := range entries {
+		match := filename.FindStringSubmatch(e.Name())
+		if e.IsDir() || match == nil {
+			continue
+		}
+
+		v, err := strconv.ParseUint(match[1], 10, 64)
+		if err != nil {
+			return nil, err
+		}
+
+		body, err := fs.ReadFile(fsys, e.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		m, ok := byVersion[uint(v)]
+		if !ok {
+			m = &Migration{Version: uint(v), Name: match[2]}
+			byVersion[uint(v)] = m
+		}
+
+		if match[3] == "up" {
+			m.Up = string(body)
+		} else {
+			m.Down = string(body)
+		}
+	}
+
+	migrations := make([]*Migration, 0, len(byVersion))
+
+	for _, m := range byVersion {
+		migrations = append(migrations, m)
+	}
+
+	slices.SortFunc(migrations, func(a, b *Migration) int {
+		return int(a.Version) - int(b.Version)
+	})
+
+	return migrations, nil
+}
+
+type Migrator struct {
+	db         *sql.DB
+	migrations []*Migration
+	logger     *slog.Logger
+}
+
+func New(db *sql.DB, fsys fs.FS, logger *slog.Logger) (*Migrator, error) {
+	migrations, err := Load(fsys)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Migrator{db, migrations, logger}, nil
+}
+
+func (m *Migrator) Latest() uint {
+	if len(m.migrations) == 0 {
+		return 0
+	}
+
+	return m.migrations[len(m.migrations)-1].Version
+}
+
+// Compatible reports whether a build whose latest migration is latest can serve against the
+// schema at version. A newer schema counts, so replicas of the previous release stay in rotation
+// once a new one has migrated forward during a rolling deploy.
+func Compatible(version, latest uint, dirty bool) bool {
+	return !dirty && version >= latest
+}
+
+func (m *Migrator) index(version uint) int {
+	if version == 0 {
+		return -1
+	}
+
+	return slices.IndexFunc(m.migrations, func(mg *Migration) bool {
+		return mg.Version == version
+	})
+}
+
+func ensureTable(ctx context.Context, conn *sql.Conn) error {
+	const stmt = `
+  CREATE TABLE IF NOT EXISTS schema_migrations (
+    version BIGINT NOT NULL PRIMARY KEY,
+    dirty BOOLEAN NOT NULL
+  );
+  `
+	_, err := conn.ExecContext(ctx, stmt)
+	return err
+}
+
+func version(ctx context.Context, conn interface {
+	QueryRowContext(context.Context, string, ...any) *sql.Row
+}) (uint, bool, error) {
+	const query = `SELECT version, dirty FROM schema_migrations LIMIT 1;`
+
+	var (
+		v     int64
+		dirty bool
+	)
+
+	err := conn.QueryRowContext(ctx, query).Scan(&v, &dirty)
+
+	if err != nil && errors.Is(err, sql.ErrNoRows) {
+		return 0, false, nil
+	}
+
+	if err != nil {
+		return 0, false, err
+	}
+
+	return uint(v), dirty, nil
+}
+
+func (m *Migrator) Version(ctx context.Context) (uint, bool, error) {
+	const exists = `SELECT to_regclass('schema_migrations') IS NOT NULL;`
+
+	var ok bool
+
+	if err := m.db.QueryRowContext(ctx, exists).Scan(&ok); err != nil || !ok {
+		return 0, false, err
+	}
+
+	return version(ctx, m.db)
+}
+
+func setVersion(ctx context.Context, conn *sql.Conn, v uint, dirty bool) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `TRUNCATE schema_migrations;`); err != nil {
+		return err
+	}
+
+	if v > 0 {
+		const stmt = `INSERT INTO schema_migrations (version, dirty) VALUES ($1, $2);`
+
+		if _, err := tx.ExecContext(ctx, stmt, v, dirty); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (m *Migrator) lock(ctx context.Context) (*sql.Conn, func(), error) {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if _, err := conn.ExecContext(ctx, `SELECT pg_advisory_lock($1);`, lockID); err != nil {
+		conn.Close()
+		return nil, nil, fmt.Errorf("acquire migration lock: %w", err)
+	}
+
+	unlock := func() {
+		_, _ = conn.ExecContext(context.Background(), `SELECT pg_advisory_unlock($1);`, lockID)
+		conn.Close()
+	}
+
+	if err := ensureTable(ctx, conn); err != nil {
+		unlock()
+		return nil, nil, err
+	}
+
+	return conn, unlock, nil
+}
+
+func (m *Migrator) withLock(ctx context.Context, fn func(*sql.Conn, uint) error) error {
+	conn, unlock, err := m.lock(ctx)
+	if err != nil {
+		return err
+	}
+
+	defer unlock()
+
+	current, dirty, err := version(ctx, conn)
+	if err != nil {
+		return err
+	}
+
+	if dirty {
+		return fmt.Errorf("%w: version %d", ErrDirty, current)
+	}
+
+	return fn(conn, current)
+}
+
+func (m *Migrator) apply(ctx context.Context, conn *sql.Conn, from, to uint) error {
+	i, j := m.index(from), m.index(to)
+
+	if (from != 0 && i < 0) || (to != 0 && j < 0) {
+		return ErrUnknownStep
+	}
+
+	if i == j {
+		return ErrNoChange
+	}
+
+	for i < j {
+		i++
+		mg := m.migrations[i]
+
+		if err := m.run(ctx, conn, mg, "up", mg.Version); err != nil {
+			return err
+		}
+	}
+
+	for i > j {
+		mg := m.migrations[i]
+		i--
+
+		var prev uint
+
+		if i >= 0 {
+			prev = m.migrations[i].Version
+		}
+
+		if err := m.run(ctx, conn, mg, "down", prev); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (m *Migrator) run(
+	ctx context.Context,
+	conn *sql.Conn,
+	mg *Migration,
+	direction string,
+	target uint,
+) error {
+	body := mg.Up
+
+	if direction == "down" {
+		body = mg.Down
+	}
+
+	if err := setVersion(ctx, conn, mg.Version, true); err != nil {
+		return err
+	}
+
+	if _, err := conn.ExecContext(ctx, body); err != nil {
+		return fmt.Errorf("migration %d_%s.%s failed: %w", mg.Version, mg.Name, direction, err)
+	}
+
+	if err := setVersion(ctx, conn, target, false); err != nil {
+		return err
+	}
+
+	m.logger.LogAttrs(ctx, slog.LevelInfo, "applied migration",
+		slog.Uint64("version", uint64(mg.Version)),
+		slog.String("name", mg.Name),
+		slog.String("direction", direction),
+	)
+
+	return nil
+}
+
+func (m *Migrator) Up(ctx context.Context) error {
+	return m.withLock(ctx, func(conn *sql.Conn, current uint) error {
+		return m.apply(ctx, conn, current, m.Latest())
+	})
+}
+
+func (m *Migrator) Down(ctx context.Context, steps int) error {
+	return m.withLock(ctx, func(conn *sql.Conn, current uint) error {
+		i := m.index(current)
+
+		if current != 0 && i < 0 {
+			return ErrUnknownStep
+		}
+
+		var target uint
+
+		if i-steps >= 0 {
+			target = m.migrations[i-steps].Version
+		}
+
+		return m.apply(ctx, conn, current, target)
+	})
+}
+
+func (m *Migrator) To(ctx context.Context, version uint) error {
+	return m.withLock(ctx, func(conn *sql.Conn, current uint) error {
+		return m.apply(ctx, conn, current, version)
+	})
+}
+
+func (m *Migrator) Force(ctx context.Context, version uint) error {
+	if version != 0 && m.index(version) < 0 {
+		return ErrUnknownStep
+	}
+
+	conn, unlock, err := m.lock(ctx)
+	if err != nil {
+		return err
+	}
+
+	defer unlock()
+
+	return setVersion(ctx, conn, version, false)
+}
+
+type MigrationStatus struct {
+	Version uint
+	Name    string
+	Applied bool
+}
+
+type Status struct {
+	Version    uint
+	Dirty      bool
+	Migrations []MigrationStatus
+}
+
+func (m *Migrator) Status(ctx context.Context) (*Status, error) {
+	current, dirty, err := m.Version(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	status := &Status{Version: current, Dirty: dirty}
+
+	for _, mg := range m.migrations {
+		status.Migrations = append(status.Migrations, MigrationStatus{
+			Version: mg.Version,
+			Name:    mg.Name,
+			Applied: mg.Version <= current,
+		})
+	}
+
+	return status, nil
+}
@@ -0,0 +1,118 @@
+package oidc
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/emma769/a-realtor/internal/config"
+	"github.com/emma769/a-realtor/internal/oidc/oidctest"
+)
+
+func newProvider(idp *oidctest.IdP) *Provider {
+	return New(&config.Config{
+		OIDCIssuer:       idp.URL,
+		OIDCClientID:     oidctest.ClientID,
+		OIDCClientSecret: oidctest.ClientSecret,
+		OIDCRedirectURL:  "http://app.test/api/auth/oidc/callback",
+		OIDCScopes:       []string{"openid", "email"},
+	})
+}
+
+func authorize(t *testing.T, idp *oidctest.IdP, p *Provider, nonce, verifier string) string {
+	t.Helper()
+
+	uri, err := p.AuthCodeURL(context.Background(), "state-1", nonce, verifier)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	code, state := idp.Authorize(t, uri)
+
+	if state != "state-1" {
+		t.Fatalf("state = %q", state)
+	}
+
+	return code
+}
+
+func TestCodeFlow(t *testing.T) {
+	idp := oidctest.New(t)
+	idp.Claims["email"] = "jane@corp.test"
+	idp.Claims["email_verified"] = true
+	idp.Claims["groups"] = []string{"realtor-managers", "everyone"}
+
+	p := newProvider(idp)
+	ctx := context.Background()
+
+	code := authorize(t, idp, p, "nonce-1", "verifier-1")
+
+	raw, err := p.Exchange(ctx, code, "verifier-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	claims, err := p.Verify(ctx, raw, "nonce-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if claims.Subject != "subject-1" || claims.Email != "jane@corp.test" || !claims.Verified() {
+		t.Errorf("claims = %+v", claims)
+	}
+
+	if groups := claims.Strings("groups"); !slices.Equal(groups, []string{
+		"realtor-managers",
+		"everyone",
+	}) {
+		t.Errorf("groups = %v", groups)
+	}
+}
+
+func TestExchangeRejectsWrongVerifier(t *testing.T) {
+	idp := oidctest.New(t)
+	p := newProvider(idp)
+
+	code := authorize(t, idp, p, "nonce-1", "verifier-1")
+
+	_, err := p.Exchange(context.Background(), code, "someone-elses-verifier")
+
+	if !errors.Is(err, ErrExchange) {
+		t.Fatalf("err = %v, want ErrExchange", err)
+	}
+}
+
+func TestVerifyRejectsNonceMismatch(t *testing.T) {
+	idp := oidctest.New(t)
+	p := newProvider(idp)
+	ctx := context.Background()
+
+	code := authorize(t, idp, p, "nonce-1", "verifier-1")
+
+	raw, err := p.Exchange(ctx, code, "verifier-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := p.Verify(ctx, raw, "nonce-2"); !errors.Is(err, ErrInvalidToken) {
+		t.Fatalf("err = %v, want ErrInvalidToken", err)
+	}
+}
+
+func TestClaimsVerified(t *testing.T) {
+	for _, tc := range []struct {
+		value any
+		want  bool
+	}{
+		{true, true},
+		{"true", true},
+		{false, false},
+		{"false", false},
+		{nil, false},
+	} {
+		if got := (&Claims{EmailVerified: tc.value}).Verified(); got != tc.want {
+			t.Errorf("Verified(%v) = %v, want %v", tc.value, got, tc.want)
+		}
+	}
+}
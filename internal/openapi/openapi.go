@@ -0,0 +1,262 @@
+package openapi
+
+import (
+	"encoding/json"
+	"maps"
+	"net/http"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"unicode"
+
+	handlerlib "github.com/emma769/a-realtor/internal/lib/handler"
+)
+
+const Version = "3.1.0"
+
+var pathParam = regexp.MustCompile(`\{([^}:]+)(:[^}]*)?\}`)
+
+type Param struct {
+	Name        string
+	Description string
+	Format      string
+}
+
+type Operation struct {
+	Summary     string
+	Description string
+	Request     any
+	Response    any
+	Status      int
+	ContentType string
+	Paginated   bool
+	Conditional bool
+	Query       []Param
+	Responses   map[int]any
+
+	tags    []string
+	scope   string
+	roles   []string
+	public  bool
+	session bool
+}
+
+type Option func(*Operation)
+
+func Tag(name string) Option {
+	return func(op *Operation) {
+		op.tags = []string{name}
+	}
+}
+
+func Scope(scope string) Option {
+	return func(op *Operation) {
+		op.scope = scope
+	}
+}
+
+func Roles(roles ...string) Option {
+	return func(op *Operation) {
+		op.roles = roles
+	}
+}
+
+func Public(op *Operation) {
+	op.public = true
+}
+
+func Session(op *Operation) {
+	op.session = true
+}
+
+type Group struct {
+	doc    *Doc
+	prefix string
+	opts   []Option
+}
+
+func (g *Group) With(opts ...Option) *Group {
+	return &Group{
+		doc:    g.doc,
+		prefix: g.prefix,
+		opts:   append(slices.Clone(g.opts), opts...),
+	}
+}
+
+func (g *Group) Route(pattern string, fn func(*Group)) {
+	sub := g.With()
+	sub.prefix = g.prefix + pattern
+	fn(sub)
+}
+
+func (g *Group) Get(pattern string, op Operation) {
+	g.add(http.MethodGet, pattern, op)
+}
+
+func (g *Group) Post(pattern string, op Operation) {
+	g.add(http.MethodPost, pattern, op)
+}
+
+func (g *Group) Put(pattern string, op Operation) {
+	g.add(http.MethodPut, pattern, op)
+}
+
+func (g *Group) Patch(pattern string, op Operation) {
+	g.add(http.MethodPatch, pattern, op)
+}
+
+func (g *Group) Delete(pattern string, op Operation) {
+	g.add(http.MethodDelete, pattern, op)
+}
+
+func (g *Group) add(method, pattern string, op Operation) {
+	for _, opt := range g.opts {
+		opt(&op)
+	}
+
+	g.doc.mu.Lock()
+	defer g.doc.mu.Unlock()
+
+	path := normalize(g.prefix + pattern)
+
+	if g.doc.ops[path] == nil {
+		g.doc.ops[path] = map[string]*Operation{}
+	}
+
+	g.doc.ops[path][strings.ToLower(method)] = &op
+}
+
+type Doc struct {
+	*Group
+	title   string
+	version string
+	mu      sync.Mutex
+	ops     map[string]map[string]*Operation
+}
+
+func New(title, version string) *Doc {
+	doc := &Doc{
+		title:   title,
+		version: version,
+		ops:     map[string]map[string]*Operation{},
+	}
+
+	doc.Group = &Group{doc: doc}
+
+	return doc
+}
+
+// Route documents a mounted sub-router, tagging its operations after the last path segment.
+func (doc *Doc) Route(pattern string, fn func(*Group)) {
+	doc.With(Tag(pattern[strings.LastIndex(pattern, "/")+1:])).Route(pattern, fn)
+}
+
+func (doc *Doc) has(method, path string) bool {
+	doc.mu.Lock()
+	defer doc.mu.Unlock()
+
+	_, ok := doc.ops[path][strings.ToLower(method)]
+	return ok
+}
+
+func (doc *Doc) Handler() http.HandlerFunc {
+	var once sync.Once
+	var spec []byte
+	var err error
+
+	return handlerlib.Wrap(func(w http.ResponseWriter, r *http.Request) error {
+		once.Do(func() {
+			spec, err = json.Marshal(doc.Spec())
+		})
+
+		if err != nil {
+			return err
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "public, max-age=300")
+		_, err := w.Write(spec)
+		return err
+	})
+}
+
+func (doc *Doc) Spec() map[string]any {
+	doc.mu.Lock()
+	defer doc.mu.Unlock()
+
+	b := newBuilder()
+	paths := map[string]any{}
+
+	for _, path := range slices.Sorted(maps.Keys(doc.ops)) {
+		item := map[string]any{}
+
+		for _, method := range slices.Sorted(maps.Keys(doc.ops[path])) {
+			item[method] = b.operation(method, path, doc.ops[path][method])
+		}
+
+		paths[path] = item
+	}
+
+	return map[string]any{
+		"openapi": Version,
+		"info": map[string]any{
+			"title":   doc.title,
+			"version": doc.version,
+		},
+		"paths": paths,
+		"security": []map[string][]string{
+			{"bearerAuth": {}},
+			{"apiKeyAuth": {}},
+		},
+		"components": map[string]any{
+			"schemas":   b.schemas,
+			"responses": b.responses,
+			"securitySchemes": map[string]any{
+				"bearerAuth": map[string]any{
+					"type":         "http",
+					"scheme":       "bearer",
+					"bearerFormat": "JWT",
+				},
+				"apiKeyAuth": map[string]any{
+					"type": "apiKey",
+					"in":   "header",
+					"name": "X-API-Key",
+				},
+			},
+		},
+	}
+}
+
+func normalize(path string) string {
+	if len(path) > 1 {
+		path = strings.TrimSuffix(path, "/")
+	}
+
+	return pathParam.ReplaceAllString(path, "{$1}")
+}
+
+func operationID(method, path string) string {
+	var b strings.Builder
+
+	b.WriteString(method)
+
+	for _, part := range strings.Split(path, "/") {
+		if part == "" || part == "api" {
+			continue
+		}
+
+		if strings.HasPrefix(part, "{") {
+			b.WriteString("By")
+			part = strings.Trim(part, "{}")
+		}
+
+		for _, word := range strings.FieldsFunc(part, func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+		}) {
+			b.WriteString(strings.ToUpper(word[:1]) + word[1:])
+		}
+	}
+
+	return b.String()
+}
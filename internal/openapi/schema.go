@@ -0,0 +1,412 @@
+package openapi
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/emma769/a-realtor/internal/entity"
+	handlerlib "github.com/emma769/a-realtor/internal/lib/handler"
+)
+
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 any                `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+}
+
+var formats = map[reflect.Type]*Schema{
+	reflect.TypeFor[time.Time]():       {Type: "string", Format: "date-time"},
+	reflect.TypeFor[entity.DateTime](): {Type: "string", Format: "date"},
+	reflect.TypeFor[uuid.UUID]():       {Type: "string", Format: "uuid"},
+	reflect.TypeFor[json.RawMessage](): {},
+}
+
+type builder struct {
+	schemas   map[string]*Schema
+	responses map[string]any
+	names     map[reflect.Type]string
+}
+
+func newBuilder() *builder {
+	return &builder{
+		schemas:   map[string]*Schema{},
+		responses: map[string]any{},
+		names:     map[reflect.Type]string{},
+	}
+}
+
+func (b *builder) operation(method, p string, op *Operation) map[string]any {
+	out := map[string]any{
+		"operationId": operationID(method, p),
+		"tags":        op.tags,
+		"summary":     op.Summary,
+	}
+
+	if desc := b.description(op); desc != "" {
+		out["description"] = desc
+	}
+
+	if params := b.parameters(method, p, op); len(params) > 0 {
+		out["parameters"] = params
+	}
+
+	if op.Request != nil {
+		out["requestBody"] = map[string]any{
+			"required": true,
+			"content": map[string]any{
+				"application/json": map[string]any{"schema": b.schemaOf(op.Request)},
+			},
+		}
+	}
+
+	switch {
+	case op.public:
+		out["security"] = []map[string][]string{}
+	case op.session:
+		out["security"] = []map[string][]string{{"bearerAuth": {}}}
+	case op.scope != "":
+		out["security"] = []map[string][]string{
+			{"bearerAuth": {}},
+			{"apiKeyAuth": {op.scope}},
+		}
+	}
+
+	out["responses"] = b.responsesFor(method, p, op)
+
+	return out
+}
+
+func (b *builder) description(op *Operation) string {
+	desc := []string{}
+
+	if op.Description != "" {
+		desc = append(desc, op.Description)
+	}
+
+	if len(op.roles) > 0 {
+		desc = append(desc, "Requires role "+strings.Join(op.roles, " or ")+".")
+	}
+
+	if op.scope != "" {
+		desc = append(desc, "API keys need the `"+op.scope+"` scope.")
+	}
+
+	if op.session {
+		desc = append(desc, "Not available to API keys.")
+	}
+
+	return strings.Join(desc, " ")
+}
+
+func (b *builder) parameters(method, p string, op *Operation) []map[string]any {
+	params := []map[string]any{}
+
+	for _, m := range pathParam.FindAllStringSubmatch(p, -1) {
+		schema := &Schema{Type: "string"}
+
+		if m[1] == "id" {
+			schema.Format = "uuid"
+		}
+
+		params = append(params, map[string]any{
+			"name":     m[1],
+			"in":       "path",
+			"required": true,
+			"schema":   schema,
+		})
+	}
+
+	for _, q := range op.Query {
+		params = append(params, map[string]any{
+			"name":        q.Name,
+			"in":          "query",
+			"description": q.Description,
+			"schema":      &Schema{Type: "string", Format: q.Format},
+		})
+	}
+
+	if op.Paginated {
+		for _, name := range []string{"page", "page_size"} {
+			params = append(params, map[string]any{
+				"name":   name,
+				"in":     "query",
+				"schema": &Schema{Type: "integer", Format: "int32"},
+			})
+		}
+	}
+
+	switch {
+	case op.Conditional && method == "get":
+		params = append(params, header("If-None-Match", false))
+	case op.Conditional:
+		params = append(params, header("If-Match", true))
+	}
+
+	if method != "get" && !op.public {
+		params = append(params, header("Idempotency-Key", false))
+	}
+
+	return params
+}
+
+func header(name string, required bool) map[string]any {
+	return map[string]any{
+		"name":     name,
+		"in":       "header",
+		"required": required,
+		"schema":   &Schema{Type: "string"},
+	}
+}
+
+func (b *builder) responsesFor(method, p string, op *Operation) map[string]any {
+	status := cmp.Or(op.Status, http.StatusOK)
+	success := map[string]any{"description": http.StatusText(status)}
+
+	if content := b.content(op); content != nil && status != http.StatusNoContent {
+		success["content"] = content
+	}
+
+	if op.Conditional {
+		success["headers"] = map[string]any{
+			"ETag": map[string]any{"schema": &Schema{Type: "string"}},
+		}
+	}
+
+	out := map[string]any{
+		fmt.Sprint(status): success,
+		"default":          b.problem(0),
+	}
+
+	for code, v := range op.Responses {
+		out[fmt.Sprint(code)] = map[string]any{
+			"description": http.StatusText(code),
+			"content": map[string]any{
+				"application/json": map[string]any{"schema": b.schemaOf(v)},
+			},
+		}
+	}
+
+	codes := []int{}
+
+	if strings.Contains(p, "{") || len(op.Query) > 0 {
+		codes = append(codes, http.StatusBadRequest)
+	}
+
+	if !op.public {
+		codes = append(codes, http.StatusUnauthorized, http.StatusForbidden)
+	}
+
+	if strings.Contains(p, "{") {
+		codes = append(codes, http.StatusNotFound)
+	}
+
+	if op.Request != nil {
+		codes = append(codes, http.StatusUnprocessableEntity)
+	}
+
+	if op.Conditional && method == "get" {
+		out["304"] = map[string]any{"description": http.StatusText(http.StatusNotModified)}
+	} else if op.Conditional {
+		codes = append(codes, http.StatusPreconditionFailed, http.StatusPreconditionRequired)
+	}
+
+	if strings.HasPrefix(p, "/api/") {
+		codes = append(codes, http.StatusTooManyRequests)
+	}
+
+	for _, code := range codes {
+		out[fmt.Sprint(code)] = b.problem(code)
+	}
+
+	if _, ok := out["409"]; !ok && method != "get" && !op.public {
+		out["409"] = b.idempotencyConflict()
+	}
+
+	return out
+}
+
+func (b *builder) idempotencyConflict() map[string]any {
+	const name = "IdempotencyConflict"
+
+	if _, ok := b.responses[name]; !ok {
+		b.responses[name] = map[string]any{
+			"description": "A request with the same Idempotency-Key is still in progress, or its " +
+				"response carried credentials and is not replayed. An in-progress key is leased; " +
+				"a retry after Retry-After takes it over if the first request never finished.",
+			"headers": map[string]any{
+				"Retry-After": map[string]any{"schema": &Schema{Type: "integer"}},
+			},
+			"content": map[string]any{
+				handlerlib.ContentTypeProblem: map[string]any{
+					"schema": b.schemaOf(handlerlib.Problem{}),
+				},
+			},
+		}
+	}
+
+	return map[string]any{"$ref": "#/components/responses/" + name}
+}
+
+func (b *builder) content(op *Operation) map[string]any {
+	contentType := cmp.Or(op.ContentType, "application/json")
+
+	var schema *Schema
+
+	switch {
+	case op.Response == nil && op.ContentType == "":
+		return nil
+	case op.Response == nil:
+		schema = &Schema{Type: "string", Format: "binary"}
+	case op.Paginated:
+		schema = &Schema{
+			Type: "object",
+			Properties: map[string]*Schema{
+				"metadata": b.schemaOf(handlerlib.PageMetadata{}),
+				"data":     {Type: "array", Items: b.schemaOf(op.Response)},
+			},
+		}
+	default:
+		schema = b.schemaOf(op.Response)
+	}
+
+	return map[string]any{contentType: map[string]any{"schema": schema}}
+}
+
+func (b *builder) problem(status int) map[string]any {
+	name := "Error"
+
+	if status != 0 {
+		name = strings.ReplaceAll(http.StatusText(status), " ", "")
+	}
+
+	if _, ok := b.responses[name]; !ok {
+		desc := "Error"
+
+		if status != 0 {
+			desc = http.StatusText(status)
+		}
+
+		b.responses[name] = map[string]any{
+			"description": desc,
+			"content": map[string]any{
+				handlerlib.ContentTypeProblem: map[string]any{
+					"schema": b.schemaOf(handlerlib.Problem{}),
+				},
+			},
+		}
+	}
+
+	return map[string]any{"$ref": "#/components/responses/" + name}
+}
+
+func (b *builder) schemaOf(v any) *Schema {
+	return b.schema(reflect.TypeOf(v))
+}
+
+func (b *builder) schema(t reflect.Type) *Schema {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	if s, ok := formats[t]; ok {
+		return s
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return &Schema{Type: "integer", Format: "int32"}
+	case reflect.Int64, reflect.Uint64:
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte"}
+		}
+
+		return &Schema{Type: "array", Items: b.schema(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: b.schema(t.Elem())}
+	case reflect.Struct:
+		return b.object(t)
+	}
+
+	return &Schema{}
+}
+
+func (b *builder) object(t reflect.Type) *Schema {
+	if t.Name() == "" {
+		return b.properties(t)
+	}
+
+	name, ok := b.names[t]
+	if !ok {
+		name = t.Name()
+
+		if _, taken := b.schemas[name]; taken {
+			name = path.Base(t.PkgPath()) + name
+		}
+
+		b.names[t] = name
+		b.schemas[name] = &Schema{}
+		*b.schemas[name] = *b.properties(t)
+	}
+
+	return &Schema{Ref: "#/components/schemas/" + name}
+}
+
+func (b *builder) properties(t reflect.Type) *Schema {
+	out := &Schema{Type: "object", Properties: map[string]*Schema{}}
+
+	for _, field := range reflect.VisibleFields(t) {
+		if !field.IsExported() || len(field.Index) > 1 && !embeddedPath(t, field.Index) {
+			continue
+		}
+
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+
+		if name == "-" || field.Anonymous && name == "" {
+			continue
+		}
+
+		out.Properties[cmp.Or(name, field.Name)] = b.schema(field.Type)
+	}
+
+	return out
+}
+
+// embeddedPath reports whether a promoted field is reached only through untagged embedded
+// structs, which encoding/json flattens into the parent object.
+func embeddedPath(t reflect.Type, index []int) bool {
+	for _, i := range index[:len(index)-1] {
+		for t.Kind() == reflect.Pointer {
+			t = t.Elem()
+		}
+
+		field := t.Field(i)
+
+		if !field.Anonymous || field.Tag.Get("json") != "" {
+			return false
+		}
+
+		t = field.Type
+	}
+
+	return true
+}
@@ -0,0 +1,22 @@
+package openapi
+
+import (
+	_ "embed"
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+//go:embed ui.html
+var ui string
+
+// UI serves the bundled docs page, which renders the spec found at specURL.
+func UI(specURL string) http.HandlerFunc {
+	url, _ := json.Marshal(specURL)
+	page := []byte(strings.Replace(ui, "{{SPEC}}", string(url), 1))
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write(page)
+	}
+}
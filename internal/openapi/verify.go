@@ -0,0 +1,56 @@
+package openapi
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+)
+
+var (
+	ErrUndocumentedRoute = errors.New("route missing from openapi spec")
+	ErrStaleOperation    = errors.New("openapi operation has no route")
+)
+
+// Verify walks the router and fails when a registered route is not documented, or when the
+// spec describes an operation that is no longer routed.
+func Verify(routes chi.Routes, doc *Doc) error {
+	var errs []error
+
+	routed := map[string]bool{}
+
+	err := chi.Walk(routes, func(
+		method, route string,
+		_ http.Handler,
+		_ ...func(http.Handler) http.Handler,
+	) error {
+		path := normalize(strings.ReplaceAll(route, "/*/", "/"))
+		routed[method+" "+path] = true
+
+		if !doc.has(method, path) {
+			errs = append(errs, fmt.Errorf("%w: %s %s", ErrUndocumentedRoute, method, path))
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	doc.mu.Lock()
+	defer doc.mu.Unlock()
+
+	for path, methods := range doc.ops {
+		for method := range methods {
+			method = strings.ToUpper(method)
+
+			if !routed[method+" "+path] {
+				errs = append(errs, fmt.Errorf("%w: %s %s", ErrStaleOperation, method, path))
+			}
+		}
+	}
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,216 @@
+package passhash
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/emma769/a-realtor/internal/config"
+)
+
+var (
+	ErrMismatch      = errors.New("password does not match hash")
+	ErrUnknownFormat = errors.New("unknown password hash format")
+	ErrUnknownScheme = errors.New("unknown password hash scheme")
+)
+
+const (
+	SchemeArgon2id = "argon2id"
+	SchemeBcrypt   = "bcrypt"
+)
+
+type Hasher interface {
+	Hash(plain string) ([]byte, error)
+	Verify(hash []byte, plain string) (rehash bool, err error)
+}
+
+func New(cfg *config.Config) (Hasher, error) {
+	switch cfg.PasswordHasher {
+	case SchemeArgon2id:
+		return &Argon2id{
+			Memory:      cfg.Argon2Memory,
+			Iterations:  cfg.Argon2Iterations,
+			Parallelism: cfg.Argon2Parallelism,
+			SaltLength:  cfg.Argon2SaltLength,
+			KeyLength:   cfg.Argon2KeyLength,
+		}, nil
+	case SchemeBcrypt:
+		return &Bcrypt{Cost: cfg.BcryptCost}, nil
+	}
+
+	return nil, fmt.Errorf("%w: %q", ErrUnknownScheme, cfg.PasswordHasher)
+}
+
+type Argon2id struct {
+	Memory      uint32
+	Iterations  uint32
+	Parallelism uint8
+	SaltLength  uint32
+	KeyLength   uint32
+}
+
+func (a *Argon2id) Hash(plain string) ([]byte, error) {
+	salt := make([]byte, a.SaltLength)
+
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+
+	key := argon2.IDKey([]byte(plain), salt, a.Iterations, a.Memory, a.Parallelism, a.KeyLength)
+
+	return []byte(fmt.Sprintf(
+		"$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version,
+		a.Memory,
+		a.Iterations,
+		a.Parallelism,
+		b64.EncodeToString(salt),
+		b64.EncodeToString(key),
+	)), nil
+}
+
+func (a *Argon2id) Verify(hash []byte, plain string) (bool, error) {
+	if !isArgon2id(hash) {
+		err := compare(hash, plain)
+		return err == nil, err
+	}
+
+	phc, err := parseArgon2id(hash)
+	if err != nil {
+		return false, err
+	}
+
+	if err := phc.compare(plain); err != nil {
+		return false, err
+	}
+
+	return phc.params != *a, nil
+}
+
+type Bcrypt struct {
+	Cost int
+}
+
+func (b *Bcrypt) Hash(plain string) ([]byte, error) {
+	return bcrypt.GenerateFromPassword([]byte(plain), b.Cost)
+}
+
+func (b *Bcrypt) Verify(hash []byte, plain string) (bool, error) {
+	if err := compare(hash, plain); err != nil {
+		return false, err
+	}
+
+	if !isBcrypt(hash) {
+		return true, nil
+	}
+
+	cost, err := bcrypt.Cost(hash)
+	if err != nil {
+		return false, err
+	}
+
+	return cost != b.Cost, nil
+}
+
+var b64 = base64.RawStdEncoding
+
+func isArgon2id(hash []byte) bool {
+	return bytes.HasPrefix(hash, []byte("$argon2id$"))
+}
+
+func isBcrypt(hash []byte) bool {
+	return bytes.HasPrefix(hash, []byte("$2"))
+}
+
+func compare(hash []byte, plain string) error {
+	switch {
+	case isArgon2id(hash):
+		phc, err := parseArgon2id(hash)
+		if err != nil {
+			return err
+		}
+		return phc.compare(plain)
+	case isBcrypt(hash):
+		err := bcrypt.CompareHashAndPassword(hash, []byte(plain))
+		if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+			return ErrMismatch
+		}
+		return err
+	}
+
+	return ErrUnknownFormat
+}
+
+type argon2idHash struct {
+	params    Argon2id
+	salt, key []byte
+}
+
+func parseArgon2id(hash []byte) (*argon2idHash, error) {
+	parts := strings.Split(string(hash), "$")
+
+	if len(parts) != 6 {
+		return nil, ErrUnknownFormat
+	}
+
+	var version int
+
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil {
+		return nil, ErrUnknownFormat
+	}
+
+	if version != argon2.Version {
+		return nil, ErrUnknownFormat
+	}
+
+	var phc argon2idHash
+
+	if _, err := fmt.Sscanf(
+		parts[3],
+		"m=%d,t=%d,p=%d",
+		&phc.params.Memory,
+		&phc.params.Iterations,
+		&phc.params.Parallelism,
+	); err != nil {
+		return nil, ErrUnknownFormat
+	}
+
+	var err error
+
+	if phc.salt, err = b64.DecodeString(parts[4]); err != nil {
+		return nil, ErrUnknownFormat
+	}
+
+	if phc.key, err = b64.DecodeString(parts[5]); err != nil {
+		return nil, ErrUnknownFormat
+	}
+
+	phc.params.SaltLength = uint32(len(phc.salt))
+	phc.params.KeyLength = uint32(len(phc.key))
+
+	return &phc, nil
+}
+
+func (phc *argon2idHash) compare(plain string) error {
+	key := argon2.IDKey(
+		[]byte(plain),
+		phc.salt,
+		phc.params.Iterations,
+		phc.params.Memory,
+		phc.params.Parallelism,
+		phc.params.KeyLength,
+	)
+
+	if subtle.ConstantTimeCompare(key, phc.key) != 1 {
+		return ErrMismatch
+	}
+
+	return nil
+}
@@ -0,0 +1,115 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/emma769/a-realtor/internal/config"
+)
+
+const (
+	BackendMemory   = "memory"
+	BackendPostgres = "postgres"
+)
+
+var (
+	ErrInvalidLimit   = errors.New("invalid rate limit")
+	ErrUnknownBackend = errors.New("unknown rate limit backend")
+)
+
+type Limit struct {
+	Requests int
+	Period   time.Duration
+}
+
+// ParseLimit reads limits written as "<requests>/<period>", e.g. "20/1m" or "300/h".
+func ParseLimit(s string) (Limit, error) {
+	requests, period, ok := strings.Cut(strings.TrimSpace(s), "/")
+	if !ok {
+		return Limit{}, fmt.Errorf("%w: %q", ErrInvalidLimit, s)
+	}
+
+	n, err := strconv.Atoi(requests)
+	if err != nil || n < 1 {
+		return Limit{}, fmt.Errorf("%w: %q", ErrInvalidLimit, s)
+	}
+
+	if period != "" && (period[0] < '0' || period[0] > '9') {
+		period = "1" + period
+	}
+
+	d, err := time.ParseDuration(period)
+	if err != nil || d <= 0 {
+		return Limit{}, fmt.Errorf("%w: %q", ErrInvalidLimit, s)
+	}
+
+	return Limit{Requests: n, Period: d}, nil
+}
+
+func (l Limit) rate() float64 {
+	return float64(l.Requests) / l.Period.Seconds()
+}
+
+type Result struct {
+	Allowed    bool
+	Limit      int
+	Remaining  int
+	Reset      time.Duration
+	RetryAfter time.Duration
+}
+
+func newResult(l Limit, allowed bool, tokens float64) Result {
+	res := Result{
+		Allowed:   allowed,
+		Limit:     l.Requests,
+		Remaining: int(math.Max(0, math.Floor(tokens))),
+		Reset:     seconds((float64(l.Requests) - tokens) / l.rate()),
+	}
+
+	if !allowed {
+		res.RetryAfter = seconds((1 - tokens) / l.rate())
+	}
+
+	return res
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(math.Max(0, s) * float64(time.Second))
+}
+
+type Limiter interface {
+	Take(ctx context.Context, key string, limit Limit) (Result, error)
+}
+
+type storer interface {
+	TakeRateLimitToken(context.Context, string, int, float64) (bool, float64, error)
+}
+
+func New(cfg *config.Config, store storer) (Limiter, error) {
+	switch cfg.RateLimitBackend {
+	case BackendMemory:
+		return NewMemory(), nil
+	case BackendPostgres:
+		return &Postgres{store}, nil
+	}
+
+	return nil, fmt.Errorf("%w: %q", ErrUnknownBackend, cfg.RateLimitBackend)
+}
+
+type Postgres struct {
+	store storer
+}
+
+func (p *Postgres) Take(ctx context.Context, key string, limit Limit) (Result, error) {
+	allowed, tokens, err := p.store.TakeRateLimitToken(ctx, key, limit.Requests, limit.rate())
+	if err != nil {
+		return Result{}, err
+	}
+
+	return newResult(limit, allowed, tokens), nil
+}
@@ -3,6 +3,8 @@ package repository
 import "errors"
 
 var (
-	ErrDuplicateKey = errors.New("duplicate key")
-	ErrNotFound     = errors.New("not found")
+	ErrDuplicateKey    = errors.New("duplicate key")
+	ErrNotFound        = errors.New("not found")
+	ErrVersionConflict = errors.New("version conflict")
+	ErrLocked          = errors.New("locked")
 )
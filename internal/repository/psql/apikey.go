@@ -0,0 +1,154 @@
+package psql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+
+	"github.com/emma769/a-realtor/internal/entity"
+	"github.com/emma769/a-realtor/internal/repository"
+)
+
+const apiKeyColumns = `
+  api_key_id, user_id, name, prefix, hash, scopes,
+  expires_at, last_used_at, revoked_at, created_at`
+
+type APIKeyParam interface {
+	UserID() uuid.UUID
+	Name() string
+	Prefix() string
+	Hash() []byte
+	Scopes() []string
+	ExpiresAt() *time.Time
+}
+
+func (q *queries) CreateAPIKey(ctx context.Context, param APIKeyParam) (*entity.APIKey, error) {
+	const query = `
+  INSERT INTO api_keys (user_id, name, prefix, hash, scopes, expires_at)
+  VALUES ($1, $2, $3, $4, $5, $6)
+  RETURNING ` + apiKeyColumns + `;
+  `
+	row := q.db.op("CreateAPIKey").QueryRowContext(
+		ctx,
+		query,
+		param.UserID(),
+		param.Name(),
+		param.Prefix(),
+		param.Hash(),
+		pq.Array(param.Scopes()),
+		param.ExpiresAt(),
+	)
+
+	var key entity.APIKey
+
+	err := scanAPIKey(row, &key)
+
+	if err != nil && strings.Contains(err.Error(), "duplicate") {
+		return nil, repository.ErrDuplicateKey
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &key, nil
+}
+
+func (q *queries) FindAPIKeys(ctx context.Context, userID uuid.UUID) ([]*entity.APIKey, error) {
+	const query = `
+  SELECT ` + apiKeyColumns + ` FROM api_keys
+  WHERE user_id = $1 ORDER BY created_at DESC;
+  `
+	rows, err := q.db.op("FindAPIKeys").QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := []*entity.APIKey{}
+
+	for rows.Next() {
+		var key entity.APIKey
+
+		if err := scanAPIKey(rows, &key); err != nil {
+			return nil, err
+		}
+
+		keys = append(keys, &key)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+
+	return keys, nil
+}
+
+func (q *queries) FindActiveAPIKey(ctx context.Context, prefix string) (*entity.APIKey, error) {
+	const query = `
+  SELECT ` + apiKeyColumns + ` FROM api_keys
+  WHERE prefix = $1 AND revoked_at IS NULL
+    AND (expires_at IS NULL OR expires_at > current_timestamp);
+  `
+	row := q.db.op("FindActiveAPIKey").QueryRowContext(ctx, query, prefix)
+
+	var key entity.APIKey
+
+	err := scanAPIKey(row, &key)
+
+	if err != nil && errors.Is(err, sql.ErrNoRows) {
+		return nil, repository.ErrNotFound
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &key, nil
+}
+
+func (q *queries) TouchAPIKey(ctx context.Context, id uuid.UUID) error {
+	const stmt = `
+  UPDATE api_keys SET last_used_at = current_timestamp
+  WHERE api_key_id = $1
+    AND (last_used_at IS NULL OR last_used_at < current_timestamp - INTERVAL '1 minute');
+  `
+	_, err := q.db.op("TouchAPIKey").ExecContext(ctx, stmt, id)
+	return err
+}
+
+func (q *queries) RevokeAPIKey(ctx context.Context, userID, id uuid.UUID) error {
+	const stmt = `
+  UPDATE api_keys SET revoked_at = current_timestamp
+  WHERE api_key_id = $1 AND user_id = $2 AND revoked_at IS NULL;
+  `
+	res, err := q.db.op("RevokeAPIKey").ExecContext(ctx, stmt, id, userID)
+	if err != nil {
+		return err
+	}
+
+	return expectRows(res)
+}
+
+func scanAPIKey(row scanner, key *entity.APIKey) error {
+	return row.Scan(
+		&key.APIKeyID,
+		&key.UserID,
+		&key.Name,
+		&key.Prefix,
+		&key.Hash,
+		pq.Array(&key.Scopes),
+		&key.ExpiresAt,
+		&key.LastUsedAt,
+		&key.RevokedAt,
+		&key.CreatedAt,
+	)
+}
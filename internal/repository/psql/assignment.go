@@ -0,0 +1,217 @@
+package psql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/google/uuid"
+
+	"github.com/emma769/a-realtor/internal/entity"
+	"github.com/emma769/a-realtor/internal/repository"
+)
+
+func (q *queries) FindTeamMemberIDs(ctx context.Context, managerID uuid.UUID) ([]uuid.UUID, error) {
+	const query = `SELECT user_id FROM users WHERE manager_id = $1;`
+
+	rows, err := q.db.op("FindTeamMemberIDs").QueryContext(ctx, query, managerID)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := []uuid.UUID{}
+
+	for rows.Next() {
+		var id uuid.UUID
+
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+
+		ids = append(ids, id)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+
+	return ids, nil
+}
+
+func (q *queries) SetUserManager(
+	ctx context.Context,
+	id uuid.UUID,
+	managerID *uuid.UUID,
+) (*entity.User, error) {
+	const query = `
+  UPDATE users SET manager_id = $2 WHERE user_id = $1
+  RETURNING ` + userColumns + `;
+  `
+	row := q.db.op("SetUserManager").QueryRowContext(ctx, query, id, managerID)
+
+	var user entity.User
+
+	err := ScanUser(row, &user)
+
+	if err != nil && errors.Is(err, sql.ErrNoRows) {
+		return nil, repository.ErrNotFound
+	}
+
+	return &user, err
+}
+
+type assignment struct {
+	AssignedTo *uuid.UUID `json:"assignedTo"`
+}
+
+func (repo *Repository) AssignLandlord(ctx context.Context, id, assignee uuid.UUID) error {
+	const stmt = `
+  UPDATE landlords SET assigned_to = $2, version = version + 1, updated_at = current_timestamp
+  WHERE landlord_id = $1;
+  `
+	return repo.inTx(ctx, func(q *queries) error {
+		before, err := q.findLandlordForUpdate(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		if _, err := q.db.op("AssignLandlord").ExecContext(ctx, stmt, id, assignee); err != nil {
+			return err
+		}
+
+		return q.writeAudit(
+			ctx,
+			entity.AuditUpdate,
+			entity.AuditLandlord,
+			id,
+			assignment{before.AssignedTo},
+			assignment{&assignee},
+		)
+	})
+}
+
+func (repo *Repository) AssignTenant(ctx context.Context, id, assignee uuid.UUID) error {
+	const stmt = `
+  UPDATE tenants SET assigned_to = $2, version = version + 1, updated_at = current_timestamp
+  WHERE tenant_id = $1;
+  `
+	return repo.inTx(ctx, func(q *queries) error {
+		before, err := q.findTenantForUpdate(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		if _, err := q.db.op("AssignTenant").ExecContext(ctx, stmt, id, assignee); err != nil {
+			return err
+		}
+
+		return q.writeAudit(
+			ctx,
+			entity.AuditUpdate,
+			entity.AuditTenant,
+			id,
+			assignment{before.AssignedTo},
+			assignment{&assignee},
+		)
+	})
+}
+
+func (repo *Repository) TransferPortfolio(
+	ctx context.Context,
+	from, to uuid.UUID,
+) (*entity.PortfolioTransfer, error) {
+	const landlords = `
+  UPDATE landlords l SET assigned_to = $2, version = version + 1, updated_at = current_timestamp
+  FROM (
+    SELECT landlord_id, assigned_to FROM landlords
+    WHERE assigned_to = $1 OR (assigned_to IS NULL AND registered_by = $1)
+    FOR UPDATE
+  ) old
+  WHERE l.landlord_id = old.landlord_id
+  RETURNING l.landlord_id, old.assigned_to;
+  `
+	const tenants = `
+  UPDATE tenants t SET assigned_to = $2, version = version + 1, updated_at = current_timestamp
+  FROM (
+    SELECT tenant_id, assigned_to FROM tenants
+    WHERE assigned_to = $1 OR (assigned_to IS NULL AND registered_by = $1)
+    FOR UPDATE
+  ) old
+  WHERE t.tenant_id = old.tenant_id
+  RETURNING t.tenant_id, old.assigned_to;
+  `
+	transfer := &entity.PortfolioTransfer{From: from, To: to}
+
+	err := repo.inTx(ctx, func(q *queries) error {
+		var err error
+
+		transfer.Landlords, err = q.transfer(ctx, landlords, entity.AuditLandlord, from, to)
+		if err != nil {
+			return err
+		}
+
+		transfer.Tenants, err = q.transfer(ctx, tenants, entity.AuditTenant, from, to)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return transfer, nil
+}
+
+func (q *queries) transfer(
+	ctx context.Context,
+	stmt, entityType string,
+	from, to uuid.UUID,
+) (int, error) {
+	rows, err := q.db.op("transfer").QueryContext(ctx, stmt, from, to)
+	if err != nil {
+		return 0, err
+	}
+
+	type moved struct {
+		id       uuid.UUID
+		previous *uuid.UUID
+	}
+
+	records := []moved{}
+
+	for rows.Next() {
+		var record moved
+
+		if err := rows.Scan(&record.id, &record.previous); err != nil {
+			return 0, err
+		}
+
+		records = append(records, record)
+	}
+
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	if err := rows.Close(); err != nil {
+		return 0, err
+	}
+
+	for _, record := range records {
+		err := q.writeAudit(
+			ctx,
+			entity.AuditUpdate,
+			entityType,
+			record.id,
+			assignment{record.previous},
+			assignment{&to},
+		)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	return len(records), nil
+}
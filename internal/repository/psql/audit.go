@@ -0,0 +1,195 @@
+package psql
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+
+	"github.com/emma769/a-realtor/internal/entity"
+)
+
+const auditColumns = `
+  audit_id, actor_id, action, entity_type, entity_id,
+  before, after, request_id, ip, created_at`
+
+func (q *queries) writeAudit(
+	ctx context.Context,
+	action entity.AuditAction,
+	entityType string,
+	entityID uuid.UUID,
+	before, after any,
+) error {
+	const stmt = `
+  INSERT INTO audit_log (
+    actor_id, action, entity_type, entity_id, before, after, request_id, ip
+  ) VALUES ($1, $2, $3, $4, $5, $6, $7, $8);
+  `
+	meta, _ := ctx.Value(entity.AuditCtxKey).(*entity.AuditMeta)
+
+	if meta == nil {
+		meta = &entity.AuditMeta{}
+	}
+
+	beforeJSON, err := auditJSON(before)
+	if err != nil {
+		return err
+	}
+
+	afterJSON, err := auditJSON(after)
+	if err != nil {
+		return err
+	}
+
+	_, err = q.db.op("writeAudit").ExecContext(
+		ctx,
+		stmt,
+		meta.ActorID,
+		action,
+		entityType,
+		entityID,
+		beforeJSON,
+		afterJSON,
+		meta.RequestID,
+		meta.IP,
+	)
+
+	return err
+}
+
+func auditJSON(v any) ([]byte, error) {
+	if v == nil {
+		return nil, nil
+	}
+
+	return json.Marshal(v)
+}
+
+type AuditFilterParam interface {
+	EntityType() string
+	EntityID() *uuid.UUID
+	ActorID() *uuid.UUID
+	From() *time.Time
+	To() *time.Time
+	Owners() []string
+}
+
+func (q *queries) FindAuditLog(
+	ctx context.Context,
+	filterParam AuditFilterParam,
+	paginator PaginationParam,
+) ([]*entity.AuditEntry, error) {
+	const query = `
+  SELECT COUNT(*) OVER(), ` + auditColumns + `
+  FROM audit_log
+  WHERE ($1 = '' OR entity_type = $1)
+    AND ($2::UUID IS NULL OR entity_id = $2)
+    AND ($3::UUID IS NULL OR actor_id = $3)
+    AND ($4::TIMESTAMPTZ IS NULL OR created_at >= $4)
+    AND ($5::TIMESTAMPTZ IS NULL OR created_at < $5)
+    AND ($8::UUID[] IS NULL OR actor_id = ANY($8)
+      OR (entity_type = 'landlord' AND entity_id IN (
+        SELECT landlord_id FROM landlords WHERE COALESCE(assigned_to, registered_by) = ANY($8)
+      ))
+      OR (entity_type = 'tenant' AND entity_id IN (
+        SELECT tenant_id FROM tenants WHERE COALESCE(assigned_to, registered_by) = ANY($8)
+      )))
+  ORDER BY created_at DESC, audit_id DESC
+  LIMIT $6 OFFSET $7;
+  `
+	rows, err := q.db.op("FindAuditLog").QueryContext(
+		ctx,
+		query,
+		filterParam.EntityType(),
+		filterParam.EntityID(),
+		filterParam.ActorID(),
+		filterParam.From(),
+		filterParam.To(),
+		paginator.Limit(),
+		paginator.Offset(),
+		pq.StringArray(filterParam.Owners()),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	var total int
+	entries := []*entity.AuditEntry{}
+
+	for rows.Next() {
+		var entry entity.AuditEntry
+
+		if err := rows.Scan(append([]any{&total}, auditFields(&entry)...)...); err != nil {
+			return nil, err
+		}
+
+		entries = append(entries, &entry)
+	}
+
+	paginator.SetTotal(total)
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+type historyFilter struct {
+	entityType string
+	entityID   uuid.UUID
+}
+
+func (f historyFilter) EntityType() string {
+	return f.entityType
+}
+
+func (f historyFilter) EntityID() *uuid.UUID {
+	return &f.entityID
+}
+
+func (f historyFilter) ActorID() *uuid.UUID {
+	return nil
+}
+
+func (f historyFilter) From() *time.Time {
+	return nil
+}
+
+func (f historyFilter) To() *time.Time {
+	return nil
+}
+
+func (f historyFilter) Owners() []string {
+	return nil
+}
+
+func (q *queries) FindEntityHistory(
+	ctx context.Context,
+	entityType string,
+	entityID uuid.UUID,
+	paginator PaginationParam,
+) ([]*entity.AuditEntry, error) {
+	return q.FindAuditLog(ctx, historyFilter{entityType, entityID}, paginator)
+}
+
+func auditFields(entry *entity.AuditEntry) []any {
+	return []any{
+		&entry.AuditID,
+		&entry.ActorID,
+		&entry.Action,
+		&entry.EntityType,
+		&entry.EntityID,
+		(*[]byte)(&entry.Before),
+		(*[]byte)(&entry.After),
+		&entry.RequestID,
+		&entry.IP,
+		&entry.CreatedAt,
+	}
+}
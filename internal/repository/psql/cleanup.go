@@ -0,0 +1,81 @@
+package psql
+
+import (
+	"context"
+	"time"
+)
+
+func (q *queries) purge(ctx context.Context, name, stmt string, args ...any) (int64, error) {
+	res, err := q.db.op(name).ExecContext(ctx, stmt, args...)
+	if err != nil {
+		return 0, err
+	}
+
+	return res.RowsAffected()
+}
+
+func (q *queries) PurgeExpiredSessions(ctx context.Context, limit int) (int64, error) {
+	const stmt = `
+  DELETE FROM sessions WHERE session_id IN (
+    SELECT session_id FROM sessions
+    WHERE valid_till < current_timestamp
+    LIMIT $1
+  );
+  `
+	return q.purge(ctx, "PurgeExpiredSessions", stmt, limit)
+}
+
+func (q *queries) PurgeExpiredUserTokens(ctx context.Context, limit int) (int64, error) {
+	const stmt = `
+  DELETE FROM user_tokens WHERE hash IN (
+    SELECT hash FROM user_tokens WHERE valid_till < current_timestamp LIMIT $1
+  );
+  `
+	return q.purge(ctx, "PurgeExpiredUserTokens", stmt, limit)
+}
+
+func (q *queries) PurgeStaleLoginThrottles(
+	ctx context.Context,
+	window time.Duration,
+	limit int,
+) (int64, error) {
+	const stmt = `
+  DELETE FROM login_throttles WHERE key IN (
+    SELECT key FROM login_throttles
+    WHERE (locked_until IS NULL OR locked_until < current_timestamp)
+      AND (
+        last_failure_at IS NULL
+        OR last_failure_at < current_timestamp - $1 * INTERVAL '1 second'
+      )
+    LIMIT $2
+  );
+  `
+	return q.purge(ctx, "PurgeStaleLoginThrottles", stmt, window.Seconds(), limit)
+}
+
+func (q *queries) PurgeExpiredOIDCStates(ctx context.Context, limit int) (int64, error) {
+	const stmt = `
+  DELETE FROM oidc_states WHERE hash IN (
+    SELECT hash FROM oidc_states WHERE valid_till < current_timestamp LIMIT $1
+  );
+  `
+	return q.purge(ctx, "PurgeExpiredOIDCStates", stmt, limit)
+}
+
+func (q *queries) PurgeFullRateLimits(ctx context.Context, limit int) (int64, error) {
+	const stmt = `
+  DELETE FROM rate_limits WHERE key IN (
+    SELECT key FROM rate_limits WHERE full_at < current_timestamp LIMIT $1
+  );
+  `
+	return q.purge(ctx, "PurgeFullRateLimits", stmt, limit)
+}
+
+func (q *queries) PurgeExpiredIdempotencyKeys(ctx context.Context, limit int) (int64, error) {
+	const stmt = `
+  DELETE FROM idempotency_keys WHERE (user_id, key) IN (
+    SELECT user_id, key FROM idempotency_keys WHERE valid_till < current_timestamp LIMIT $1
+  );
+  `
+	return q.purge(ctx, "PurgeExpiredIdempotencyKeys", stmt, limit)
+}
@@ -0,0 +1,132 @@
+package psql
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+
+	"github.com/google/uuid"
+
+	"github.com/emma769/a-realtor/internal/entity"
+	"github.com/emma769/a-realtor/internal/repository"
+)
+
+// ReserveIdempotencyKey claims key for rec, taking over a row that has expired or whose
+// in-progress lease ran out without the request completing.
+func (q *queries) ReserveIdempotencyKey(
+	ctx context.Context,
+	rec *entity.IdempotencyRecord,
+) (*entity.IdempotencyRecord, bool, error) {
+	const stmt = `
+  INSERT INTO idempotency_keys (user_id, key, request_hash, valid_till, locked_until)
+  VALUES ($1, $2, $3, $4, $5)
+  ON CONFLICT (user_id, key) DO UPDATE SET
+    request_hash = EXCLUDED.request_hash,
+    status = NULL,
+    header = NULL,
+    body = NULL,
+    valid_till = EXCLUDED.valid_till,
+    locked_until = EXCLUDED.locked_until,
+    created_at = current_timestamp
+  WHERE idempotency_keys.valid_till < current_timestamp
+    OR (
+      idempotency_keys.status IS NULL
+      AND COALESCE(idempotency_keys.locked_until, idempotency_keys.created_at) < current_timestamp
+    )
+  RETURNING user_id;
+  `
+	var id uuid.UUID
+
+	err := q.db.op("ReserveIdempotencyKey").QueryRowContext(
+		ctx,
+		stmt,
+		rec.UserID,
+		rec.Key,
+		rec.RequestHash,
+		rec.ValidTill,
+		rec.LockedUntil,
+	).Scan(&id)
+
+	if err == nil {
+		return rec, true, nil
+	}
+
+	if !errors.Is(err, sql.ErrNoRows) {
+		return nil, false, err
+	}
+
+	existing, err := q.findIdempotencyKey(ctx, rec.UserID, rec.Key)
+	return existing, false, err
+}
+
+func (q *queries) findIdempotencyKey(
+	ctx context.Context,
+	userID uuid.UUID,
+	key string,
+) (*entity.IdempotencyRecord, error) {
+	const query = `
+  SELECT user_id, key, request_hash, COALESCE(status, 0), header, body, valid_till,
+    COALESCE(locked_until, created_at)
+  FROM idempotency_keys WHERE user_id = $1 AND key = $2;
+  `
+	var rec entity.IdempotencyRecord
+	var header []byte
+
+	err := q.db.op("findIdempotencyKey").QueryRowContext(ctx, query, userID, key).Scan(
+		&rec.UserID,
+		&rec.Key,
+		&rec.RequestHash,
+		&rec.Status,
+		&header,
+		&rec.Body,
+		&rec.ValidTill,
+		&rec.LockedUntil,
+	)
+
+	if err != nil && errors.Is(err, sql.ErrNoRows) {
+		return nil, repository.ErrNotFound
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if len(header) > 0 {
+		if err := json.Unmarshal(header, &rec.Header); err != nil {
+			return nil, err
+		}
+	}
+
+	return &rec, nil
+}
+
+func (q *queries) CompleteIdempotencyKey(ctx context.Context, rec *entity.IdempotencyRecord) error {
+	const stmt = `
+  UPDATE idempotency_keys SET status = $3, header = $4, body = $5
+  WHERE user_id = $1 AND key = $2 AND status IS NULL;
+  `
+	header, err := json.Marshal(rec.Header)
+	if err != nil {
+		return err
+	}
+
+	_, err = q.db.op("CompleteIdempotencyKey").ExecContext(
+		ctx,
+		stmt,
+		rec.UserID,
+		rec.Key,
+		rec.Status,
+		header,
+		rec.Body,
+	)
+	return err
+}
+
+func (q *queries) ReleaseIdempotencyKey(ctx context.Context, userID uuid.UUID, key string) error {
+	const stmt = `
+  DELETE FROM idempotency_keys WHERE user_id = $1 AND key = $2 AND status IS NULL;
+  `
+	_, err := q.db.op("ReleaseIdempotencyKey").ExecContext(ctx, stmt, userID, key)
+	return err
+}
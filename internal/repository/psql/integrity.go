@@ -0,0 +1,76 @@
+package psql
+
+import (
+	"context"
+)
+
+type IntegrityCheck struct {
+	Name  string
+	Query string
+}
+
+type IntegrityResult struct {
+	Name  string
+	Count int64
+}
+
+var IntegrityChecks = []IntegrityCheck{
+	{
+		Name: "landlords without property info",
+		Query: `SELECT COUNT(*) FROM landlords l WHERE NOT EXISTS (
+      SELECT 1 FROM property_info p WHERE p.landlord_id = l.landlord_id)`,
+	},
+	{
+		Name: "tenants without rent info",
+		Query: `SELECT COUNT(*) FROM tenants t WHERE NOT EXISTS (
+      SELECT 1 FROM rent_info r WHERE r.tenant_id = t.tenant_id)`,
+	},
+	{
+		Name:  "property info orphaned from landlords",
+		Query: `SELECT COUNT(*) FROM property_info WHERE landlord_id IS NULL`,
+	},
+	{
+		Name:  "property info ending before it starts",
+		Query: `SELECT COUNT(*) FROM property_info WHERE end_date < start_date`,
+	},
+	{
+		Name:  "rent info maturing before it starts",
+		Query: `SELECT COUNT(*) FROM rent_info WHERE maturity_date < start_date`,
+	},
+	{
+		Name: "records assigned to inactive users",
+		Query: `SELECT
+      (SELECT COUNT(*) FROM landlords l JOIN users u ON u.user_id = l.assigned_to
+        WHERE u.status <> 'active') +
+      (SELECT COUNT(*) FROM tenants t JOIN users u ON u.user_id = t.assigned_to
+        WHERE u.status <> 'active')`,
+	},
+	{
+		Name: "active users without a way to sign in",
+		Query: `SELECT COUNT(*) FROM users u
+      WHERE u.status = 'active' AND u.password IS NULL AND NOT EXISTS (
+        SELECT 1 FROM user_identities i WHERE i.user_id = u.user_id)`,
+	},
+	{
+		Name:  "managers reporting to themselves",
+		Query: `SELECT COUNT(*) FROM users WHERE manager_id = user_id`,
+	},
+}
+
+func (q *queries) RunIntegrityChecks(ctx context.Context) ([]*IntegrityResult, error) {
+	results := make([]*IntegrityResult, len(IntegrityChecks))
+
+	for i, check := range IntegrityChecks {
+		result := &IntegrityResult{Name: check.Name}
+
+		row := q.db.op("RunIntegrityChecks").QueryRowContext(ctx, check.Query)
+
+		if err := row.Scan(&result.Count); err != nil {
+			return nil, err
+		}
+
+		results[i] = result
+	}
+
+	return results, nil
+}
@@ -5,10 +5,12 @@ import (
 	"database/sql"
 	"encoding/json"
 	"errors"
+	"slices"
 	"strings"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 
 	"github.com/emma769/a-realtor/internal/entity"
 	"github.com/emma769/a-realtor/internal/repository"
@@ -37,22 +39,40 @@ type LandlordWithPropertyInfoParam interface {
 	PropertyInfoParam
 }
 
-func (q *queries) CreateLandlord(
+func (repo *Repository) CreateLandlord(
 	ctx context.Context,
 	param LandlordWithPropertyInfoParam,
 ) (*entity.Landlord, error) {
-	landlord, err := q.createLandlord(ctx, param)
-	if err != nil {
-		return nil, err
-	}
+	var landlord *entity.Landlord
+
+	err := repo.inTx(ctx, func(q *queries) error {
+		var err error
+
+		landlord, err = q.createLandlord(ctx, param)
+		if err != nil {
+			return err
+		}
+
+		propertyInfo, err := q.createPropertyInfo(ctx, landlord.LandlordID, param)
+		if err != nil {
+			return err
+		}
 
-	propertyInfo, err := q.CreatePropertyInfo(ctx, landlord.LandlordID, param)
+		landlord.PropertyInfo = append(landlord.PropertyInfo, propertyInfo)
+
+		return q.writeAudit(
+			ctx,
+			entity.AuditCreate,
+			entity.AuditLandlord,
+			landlord.LandlordID,
+			nil,
+			landlord,
+		)
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	landlord.PropertyInfo = append(landlord.PropertyInfo, propertyInfo)
-
 	return landlord, nil
 }
 
@@ -65,9 +85,9 @@ func (q *queries) createLandlord(
       first_name, last_name, email, phone, registered_by
     ) VALUES ($1, $2, $3, $4, $5) 
     RETURNING landlord_id, first_name, last_name, email, 
-      phone, registered_by, created_at, updated_at;
+      phone, registered_by, assigned_to, created_at, updated_at, version;
   `
-	row := q.db.QueryRowContext(
+	row := q.db.op("createLandlord").QueryRowContext(
 		ctx,
 		query,
 		param.FirstName(),
@@ -86,8 +106,10 @@ func (q *queries) createLandlord(
 		&landlord.Email,
 		&landlord.Phone,
 		&landlord.RegisteredBy,
+		&landlord.AssignedTo,
 		&landlord.CreatedAt,
 		&landlord.UpdatedAt,
+		&landlord.Version,
 	)
 
 	if err != nil && strings.Contains(err.Error(), "duplicate") {
@@ -97,7 +119,54 @@ func (q *queries) createLandlord(
 	return &landlord, err
 }
 
-func (q *queries) CreatePropertyInfo(
+func (repo *Repository) CreatePropertyInfo(
+	ctx context.Context,
+	landlordID uuid.UUID,
+	version int,
+	param PropertyInfoParam,
+) (*entity.PropertyInfo, error) {
+	var propertyInfo *entity.PropertyInfo
+
+	err := repo.inTx(ctx, func(q *queries) error {
+		before, err := q.findLandlordForUpdate(ctx, landlordID)
+		if err != nil {
+			return err
+		}
+
+		if before.Version != version {
+			return repository.ErrVersionConflict
+		}
+
+		propertyInfo, err = q.createPropertyInfo(ctx, landlordID, param)
+		if err != nil {
+			return err
+		}
+
+		if err := q.bumpLandlordVersion(ctx, landlordID); err != nil {
+			return err
+		}
+
+		after := *before
+		after.Version++
+		after.PropertyInfo = append(slices.Clone(before.PropertyInfo), propertyInfo)
+
+		return q.writeAudit(
+			ctx,
+			entity.AuditUpdate,
+			entity.AuditLandlord,
+			landlordID,
+			before,
+			&after,
+		)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return propertyInfo, nil
+}
+
+func (q *queries) createPropertyInfo(
 	ctx context.Context,
 	landlordID uuid.UUID,
 	param PropertyInfoParam,
@@ -111,7 +180,7 @@ func (q *queries) CreatePropertyInfo(
       property_info_id, address, property_type, additional_info, 
       lease_price, lease_period, start_date, end_date, landlord_id;
   `
-	row := q.db.QueryRowContext(
+	row := q.db.op("createPropertyInfo").QueryRowContext(
 		ctx,
 		query,
 		param.Address(),
@@ -155,8 +224,8 @@ func (q *queries) FindLandlord(
 ) (*entity.Landlord, error) {
 	const query = `
     SELECT l.landlord_id, l.first_name, l.last_name, l.email, 
-      l.phone, l.registered_by, l.created_at, l.updated_at, 
-      CASE WHEN count(p.property_info_id) = 0 THEN 
+      l.phone, l.registered_by, l.assigned_to, l.created_at, l.updated_at, 
+      l.version, CASE WHEN count(p.property_info_id) = 0 THEN 
         '[]'::JSON
       ELSE
         json_agg(
@@ -176,7 +245,7 @@ func (q *queries) FindLandlord(
     FROM landlords l LEFT JOIN property_info p ON l.landlord_id = p.landlord_id 
     WHERE l.landlord_id = $1 GROUP BY l.landlord_id, l.phone;
   `
-	row := q.db.QueryRowContext(ctx, query, id)
+	row := q.db.op("FindLandlord").QueryRowContext(ctx, query, id)
 
 	var propertyInfo []byte
 	var landlord entity.Landlord
@@ -188,8 +257,10 @@ func (q *queries) FindLandlord(
 		&landlord.Email,
 		&landlord.Phone,
 		&landlord.RegisteredBy,
+		&landlord.AssignedTo,
 		&landlord.CreatedAt,
 		&landlord.UpdatedAt,
+		&landlord.Version,
 		&propertyInfo,
 	)
 
@@ -207,10 +278,39 @@ func (q *queries) FindLandlord(
 	return &landlord, nil
 }
 
+func (q *queries) findLandlordForUpdate(
+	ctx context.Context,
+	id uuid.UUID,
+) (*entity.Landlord, error) {
+	const query = `SELECT landlord_id FROM landlords WHERE landlord_id = $1 FOR UPDATE;`
+
+	row := q.db.op("findLandlordForUpdate").QueryRowContext(ctx, query, id)
+
+	if err := row.Scan(&id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, repository.ErrNotFound
+		}
+
+		return nil, err
+	}
+
+	return q.FindLandlord(ctx, id)
+}
+
+func (q *queries) bumpLandlordVersion(ctx context.Context, id uuid.UUID) error {
+	const stmt = `
+    UPDATE landlords SET version = version + 1, updated_at = current_timestamp
+    WHERE landlord_id = $1;
+  `
+	_, err := q.db.op("bumpLandlordVersion").ExecContext(ctx, stmt, id)
+	return err
+}
+
 type LandlordFilterParam interface {
 	FirstName() string
 	Phone() string
 	Address() string
+	Owners() []string
 }
 
 func (q *queries) FindLandlords(
@@ -228,15 +328,17 @@ func (q *queries) FindLandlords(
       (LOWER(l.first_name) = LOWER($1) OR $1 = '') 
       AND (l.phone = $2 OR $2 = '')
       AND (to_tsvector('simple', p.address) @@ plainto_tsquery('simple', $3) OR $3 = '')
-    LIMIT $4 OFFSET $5;
+      AND ($4::UUID[] IS NULL OR COALESCE(l.assigned_to, l.registered_by) = ANY($4))
+    LIMIT $5 OFFSET $6;
   `
 
-	rows, err := q.db.QueryContext(
+	rows, err := q.db.op("FindLandlords").QueryContext(
 		ctx,
 		query,
 		filterParam.FirstName(),
 		filterParam.Phone(),
 		filterParam.Address(),
+		pq.StringArray(filterParam.Owners()),
 		paginator.Limit(),
 		paginator.Offset(),
 	)
@@ -300,20 +402,34 @@ func (q *queries) UpdateLandlord(
 	return nil, nil
 }
 
-func (q *queries) DeleteLandlord(ctx context.Context, id uuid.UUID) error {
-	const query = `DELETE FROM landlords WHERE landlord_id = $1;`
+func (repo *Repository) DeleteLandlord(ctx context.Context, id uuid.UUID, version int) error {
+	const stmt = `DELETE FROM landlords WHERE landlord_id = $1;`
 
-	if _, err := q.db.ExecContext(ctx, query, id); err != nil {
-		return err
-	}
+	return repo.inTx(ctx, func(q *queries) error {
+		before, err := q.findLandlordForUpdate(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		if before.Version != version {
+			return repository.ErrVersionConflict
+		}
 
-	return nil
+		if _, err := q.db.op("DeleteLandlord").ExecContext(ctx, stmt, id); err != nil {
+			return err
+		}
+
+		return q.writeAudit(ctx, entity.AuditDelete, entity.AuditLandlord, id, before, nil)
+	})
 }
 
-func (q *queries) TotalLandlordCount(ctx context.Context) (int64, error) {
-	const query = "SELECT COUNT(*) FROM landlords;"
+func (q *queries) TotalLandlordCount(ctx context.Context, owners []string) (int64, error) {
+	const query = `
+    SELECT COUNT(*) FROM landlords
+    WHERE $1::UUID[] IS NULL OR COALESCE(assigned_to, registered_by) = ANY($1);
+  `
 
-	row := q.db.QueryRowContext(ctx, query)
+	row := q.db.op("TotalLandlordCount").QueryRowContext(ctx, query, pq.StringArray(owners))
 
 	var total int64
 
@@ -325,16 +441,20 @@ func (q *queries) TotalLandlordCount(ctx context.Context) (int64, error) {
 	return total, nil
 }
 
-func (q *queries) GetAllLandlords(ctx context.Context) ([]*entity.LandlordOut, error) {
+func (q *queries) GetAllLandlords(
+	ctx context.Context,
+	owners []string,
+) ([]*entity.LandlordOut, error) {
 	const query = `
     SELECT  
       l.landlord_id, l.first_name, l.last_name, l.email, l.phone, l.registered_by, 
       p.address, p.property_type, p.lease_price, p.lease_period, p.start_date, 
       p.end_date, p.additional_info, l.created_at, l.updated_at
-    FROM landlords l LEFT JOIN property_info p ON l.landlord_id = p.landlord_id;
+    FROM landlords l LEFT JOIN property_info p ON l.landlord_id = p.landlord_id
+    WHERE $1::UUID[] IS NULL OR COALESCE(l.assigned_to, l.registered_by) = ANY($1);
   `
 
-	rows, err := q.db.QueryContext(ctx, query)
+	rows, err := q.db.op("GetAllLandlords").QueryContext(ctx, query, pq.StringArray(owners))
 	if err != nil {
 		return nil, err
 	}
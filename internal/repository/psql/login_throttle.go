@@ -0,0 +1,230 @@
+package psql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+
+	"github.com/emma769/a-realtor/internal/entity"
+	"github.com/emma769/a-realtor/internal/repository"
+)
+
+func (q *queries) FindLoginLock(ctx context.Context, keys ...string) (*time.Time, error) {
+	const query = `
+  SELECT MAX(locked_until) FROM login_throttles
+  WHERE key = ANY($1) AND locked_until > current_timestamp;
+  `
+	row := q.db.op("FindLoginLock").QueryRowContext(ctx, query, pq.Array(keys))
+
+	var lockedUntil *time.Time
+
+	if err := row.Scan(&lockedUntil); err != nil {
+		return nil, err
+	}
+
+	return lockedUntil, nil
+}
+
+// ReserveLoginAttempt counts an attempt against key before the credentials are checked. It
+// returns repository.ErrLocked without counting while the key is locked.
+func (q *queries) ReserveLoginAttempt(
+	ctx context.Context,
+	key string,
+	window time.Duration,
+) (int, error) {
+	const query = `
+  INSERT INTO login_throttles (key, failures, last_failure_at)
+  VALUES ($1, 1, current_timestamp)
+  ON CONFLICT (key) DO UPDATE SET
+    failures = CASE
+      WHEN login_throttles.last_failure_at < current_timestamp - $2 * INTERVAL '1 second' THEN 1
+      ELSE login_throttles.failures + 1
+    END,
+    last_failure_at = current_timestamp
+  WHERE login_throttles.locked_until IS NULL
+    OR login_throttles.locked_until <= current_timestamp
+  RETURNING failures;
+  `
+	row := q.db.op("ReserveLoginAttempt").QueryRowContext(ctx, query, key, window.Seconds())
+
+	var failures int
+
+	err := row.Scan(&failures)
+
+	if err != nil && errors.Is(err, sql.ErrNoRows) {
+		return 0, repository.ErrLocked
+	}
+
+	return failures, err
+}
+
+func (q *queries) ReleaseLoginAttempt(ctx context.Context, key string) error {
+	const stmt = `
+  UPDATE login_throttles SET failures = GREATEST(failures - 1, 0) WHERE key = $1;
+  `
+	_, err := q.db.op("ReleaseLoginAttempt").ExecContext(ctx, stmt, key)
+	return err
+}
+
+func (q *queries) SetLoginLock(ctx context.Context, key string, until time.Time) error {
+	const stmt = `UPDATE login_throttles SET locked_until = $2 WHERE key = $1;`
+	_, err := q.db.op("SetLoginLock").ExecContext(ctx, stmt, key, until)
+	return err
+}
+
+func (q *queries) ResetLoginFailures(ctx context.Context, key string) error {
+	const stmt = `DELETE FROM login_throttles WHERE key = $1;`
+	_, err := q.db.op("ResetLoginFailures").ExecContext(ctx, stmt, key)
+	return err
+}
+
+func (repo *Repository) LockLogin(ctx context.Context, lockout *entity.Lockout) error {
+	const stmt = `
+  UPDATE login_throttles SET locked_until = $2, failures = 0 WHERE key = $1;
+  `
+	return repo.inTx(ctx, func(q *queries) error {
+		if _, err := q.db.op("LockLogin").ExecContext(
+			ctx,
+			stmt,
+			lockout.Key,
+			lockout.LockedUntil,
+		); err != nil {
+			return err
+		}
+
+		return q.createLockout(ctx, lockout)
+	})
+}
+
+func (q *queries) createLockout(ctx context.Context, lockout *entity.Lockout) error {
+	const stmt = `
+  INSERT INTO lockouts (key, user_id, ip, failures, locked_until) VALUES ($1, $2, $3, $4, $5);
+  `
+	_, err := q.db.op("createLockout").ExecContext(
+		ctx,
+		stmt,
+		lockout.Key,
+		lockout.UserID,
+		lockout.IP,
+		lockout.Failures,
+		lockout.LockedUntil,
+	)
+	return err
+}
+
+// UnlockLogin clears key along with every key still locked out against the user, which covers
+// IP lockouts tripped by their login attempts.
+func (repo *Repository) UnlockLogin(
+	ctx context.Context,
+	userID uuid.UUID,
+	key string,
+	by uuid.UUID,
+) error {
+	const query = `
+  SELECT DISTINCT key FROM lockouts
+  WHERE user_id = $1 AND unlocked_at IS NULL AND locked_until > current_timestamp;
+  `
+	const reset = `DELETE FROM login_throttles WHERE key = ANY($1);`
+	const stmt = `
+  UPDATE lockouts SET unlocked_by = $2, unlocked_at = current_timestamp
+  WHERE key = ANY($1) AND unlocked_at IS NULL AND locked_until > current_timestamp;
+  `
+	return repo.inTx(ctx, func(q *queries) error {
+		rows, err := q.db.op("UnlockLogin").QueryContext(ctx, query, userID)
+		if err != nil {
+			return err
+		}
+
+		keys := []string{key}
+
+		for rows.Next() {
+			var k string
+
+			if err := rows.Scan(&k); err != nil {
+				return err
+			}
+
+			keys = append(keys, k)
+		}
+
+		if err := rows.Err(); err != nil {
+			return err
+		}
+
+		if err := rows.Close(); err != nil {
+			return err
+		}
+
+		if _, err := q.db.op("UnlockLogin").ExecContext(ctx, reset, pq.Array(keys)); err != nil {
+			return err
+		}
+
+		_, err = q.db.op("UnlockLogin").ExecContext(ctx, stmt, pq.Array(keys), by)
+		return err
+	})
+}
+
+func (q *queries) FindLockouts(
+	ctx context.Context,
+	key string,
+	paginator PaginationParam,
+) ([]*entity.Lockout, error) {
+	const query = `
+  SELECT COUNT(*) OVER(), lockout_id, key, user_id, ip, failures, locked_until,
+    unlocked_by, unlocked_at, created_at
+  FROM lockouts WHERE (key = $1 OR $1 = '')
+  ORDER BY created_at DESC
+  LIMIT $2 OFFSET $3;
+  `
+	rows, err := q.db.op("FindLockouts").QueryContext(
+		ctx,
+		query,
+		key,
+		paginator.Limit(),
+		paginator.Offset(),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	var total int
+	lockouts := []*entity.Lockout{}
+
+	for rows.Next() {
+		var lockout entity.Lockout
+
+		err := rows.Scan(
+			&total,
+			&lockout.LockoutID,
+			&lockout.Key,
+			&lockout.UserID,
+			&lockout.IP,
+			&lockout.Failures,
+			&lockout.LockedUntil,
+			&lockout.UnlockedBy,
+			&lockout.UnlockedAt,
+			&lockout.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		lockouts = append(lockouts, &lockout)
+	}
+
+	paginator.SetTotal(total)
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+
+	return lockouts, nil
+}
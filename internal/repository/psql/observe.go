@@ -0,0 +1,148 @@
+package psql
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/emma769/a-realtor/internal/tracing"
+)
+
+type QueryObserver func(name string, duration time.Duration, err error)
+
+type observedDB struct {
+	db      dbtx
+	observe QueryObserver
+}
+
+func (o *observedDB) with(db dbtx) *observedDB {
+	return &observedDB{db, o.observe}
+}
+
+func (o *observedDB) start(ctx context.Context, name string) (context.Context, trace.Span) {
+	return tracing.Start(
+		ctx,
+		"psql."+name,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("db.system", "postgresql"),
+			attribute.String("db.operation.name", name),
+		),
+	)
+}
+
+func (o *observedDB) record(name string, start time.Time, err error) {
+	if o.observe != nil {
+		o.observe(name, time.Since(start), err)
+	}
+}
+
+func endSpan(span trace.Span, rows int64, err error) {
+	span.SetAttributes(attribute.Int64("db.response.rows", rows))
+	tracing.Error(span, err)
+	span.End()
+}
+
+type row struct {
+	*sql.Row
+	span trace.Span
+}
+
+func (r *row) Scan(dest ...any) error {
+	err := r.Row.Scan(dest...)
+
+	switch {
+	case err == nil:
+		endSpan(r.span, 1, nil)
+	case err == sql.ErrNoRows:
+		endSpan(r.span, 0, nil)
+	default:
+		endSpan(r.span, 0, err)
+	}
+
+	return err
+}
+
+type rows struct {
+	*sql.Rows
+	span trace.Span
+	n    int64
+	once sync.Once
+}
+
+func (r *rows) Next() bool {
+	if r.Rows.Next() {
+		r.n++
+		return true
+	}
+
+	r.end()
+
+	return false
+}
+
+func (r *rows) Close() error {
+	err := r.Rows.Close()
+	r.end()
+	return err
+}
+
+func (r *rows) end() {
+	r.once.Do(func() {
+		endSpan(r.span, r.n, r.Rows.Err())
+	})
+}
+
+// op names the statement that follows for its span and query metrics; repository methods pass
+// their own name.
+func (o *observedDB) op(name string) operation {
+	return operation{o, name}
+}
+
+type operation struct {
+	*observedDB
+	name string
+}
+
+func (o operation) QueryRowContext(ctx context.Context, query string, args ...any) *row {
+	ctx, span := o.start(ctx, o.name)
+	start := time.Now()
+	r := o.db.QueryRowContext(ctx, query, args...)
+	o.record(o.name, start, r.Err())
+	return &row{r, span}
+}
+
+func (o operation) QueryContext(ctx context.Context, query string, args ...any) (*rows, error) {
+	ctx, span := o.start(ctx, o.name)
+	start := time.Now()
+	r, err := o.db.QueryContext(ctx, query, args...)
+	o.record(o.name, start, err)
+
+	if err != nil {
+		endSpan(span, 0, err)
+		return nil, err
+	}
+
+	return &rows{Rows: r, span: span}, nil
+}
+
+func (o operation) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	ctx, span := o.start(ctx, o.name)
+	start := time.Now()
+	res, err := o.db.ExecContext(ctx, query, args...)
+	o.record(o.name, start, err)
+
+	var n int64
+
+	if err == nil {
+		n, _ = res.RowsAffected()
+	}
+
+	endSpan(span, n, err)
+
+	return res, err
+}
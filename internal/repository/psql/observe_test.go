@@ -0,0 +1,287 @@
+package psql
+
+import (
+	"context"
+	"database/sql"
+	sqldriver "database/sql/driver"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	coltracepb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/emma769/a-realtor/internal/config"
+	"github.com/emma769/a-realtor/internal/middleware"
+	"github.com/emma769/a-realtor/internal/tracing"
+)
+
+// emptyDB answers every query with no rows and every statement with one affected row.
+type emptyDB struct{}
+
+func (emptyDB) Connect(context.Context) (sqldriver.Conn, error) { return emptyConn{}, nil }
+func (emptyDB) Driver() sqldriver.Driver                        { return nil }
+
+type emptyConn struct{}
+
+func (emptyConn) Prepare(string) (sqldriver.Stmt, error) { return nil, sqldriver.ErrSkip }
+func (emptyConn) Close() error                           { return nil }
+func (emptyConn) Begin() (sqldriver.Tx, error)           { return nil, sqldriver.ErrSkip }
+
+func (emptyConn) QueryContext(
+	context.Context,
+	string,
+	[]sqldriver.NamedValue,
+) (sqldriver.Rows, error) {
+	return emptyRows{}, nil
+}
+
+func (emptyConn) ExecContext(
+	context.Context,
+	string,
+	[]sqldriver.NamedValue,
+) (sqldriver.Result, error) {
+	return sqldriver.RowsAffected(1), nil
+}
+
+type emptyRows struct{}
+
+func (emptyRows) Columns() []string            { return nil }
+func (emptyRows) Close() error                 { return nil }
+func (emptyRows) Next([]sqldriver.Value) error { return io.EOF }
+
+const (
+	callerTrace = "4bf92f3577b34da6a3ce929d0e0e4736"
+	callerSpan  = "00f067aa0ba902b7"
+)
+
+// span is the part of an exported span the parenting checks look at, in hex.
+type span struct {
+	name, traceID, spanID, parentID string
+	kind                            trace.SpanKind
+}
+
+// restoreOtel puts back the global provider and propagator that the test replaces.
+func restoreOtel(t *testing.T) {
+	prevProvider, prevPropagator := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+
+	t.Cleanup(func() {
+		otel.SetTracerProvider(prevProvider)
+		otel.SetTextMapPropagator(prevPropagator)
+	})
+}
+
+// serveTraced runs one request from a remote caller through the tracing middleware, a service
+// span and a query and a statement on the observed database.
+func serveTraced(t *testing.T) {
+	t.Helper()
+
+	db := sql.OpenDB(emptyDB{})
+	defer db.Close()
+
+	q := newQueries(db, nil)
+
+	router := chi.NewRouter()
+	router.Use(middleware.Tracing)
+	router.Get("/users/{id}", func(w http.ResponseWriter, r *http.Request) {
+		ctx, span := tracing.Start(r.Context(), "user.Service.find")
+		defer span.End()
+
+		if _, err := q.FindUserByID(ctx, uuid.New()); err == nil {
+			t.Error("expected no user")
+		}
+
+		if err := q.SetLoginLock(ctx, "email:jane@corp.test", time.Now()); err != nil {
+			t.Error(err)
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/users/"+uuid.NewString(), nil)
+	req.Header.Set("traceparent", "00-"+callerTrace+"-"+callerSpan+"-01")
+
+	router.ServeHTTP(httptest.NewRecorder(), req)
+}
+
+func checkParenting(t *testing.T, spans []span) {
+	t.Helper()
+
+	byName := map[string]span{}
+
+	for _, s := range spans {
+		byName[s.name] = s
+	}
+
+	server, ok1 := byName["GET /users/{id}"]
+	service, ok2 := byName["user.Service.find"]
+	query, ok3 := byName["psql.FindUserByID"]
+	exec, ok4 := byName["psql.SetLoginLock"]
+
+	if !ok1 || !ok2 || !ok3 || !ok4 {
+		t.Fatalf("missing spans, got %v", spans)
+	}
+
+	for _, s := range []span{server, service, query, exec} {
+		if s.traceID != callerTrace {
+			t.Errorf("%s trace = %s, want %s", s.name, s.traceID, callerTrace)
+		}
+	}
+
+	for _, tc := range []struct {
+		child  span
+		parent string
+	}{
+		{server, callerSpan},
+		{service, server.spanID},
+		{query, service.spanID},
+		{exec, service.spanID},
+	} {
+		if tc.child.parentID != tc.parent {
+			t.Errorf("%s parent = %s, want %s", tc.child.name, tc.child.parentID, tc.parent)
+		}
+	}
+
+	if server.kind != trace.SpanKindServer {
+		t.Errorf("server span kind = %s", server.kind)
+	}
+
+	if query.kind != trace.SpanKindClient || exec.kind != trace.SpanKindClient {
+		t.Errorf("sql span kinds = %s, %s", query.kind, exec.kind)
+	}
+}
+
+func TestSpansNestUnderRequest(t *testing.T) {
+	restoreOtel(t)
+
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	serveTraced(t)
+
+	var spans []span
+
+	for _, s := range recorder.Ended() {
+		spans = append(spans, span{
+			name:     s.Name(),
+			traceID:  s.SpanContext().TraceID().String(),
+			spanID:   s.SpanContext().SpanID().String(),
+			parentID: s.Parent().SpanID().String(),
+			kind:     s.SpanKind(),
+		})
+	}
+
+	checkParenting(t, spans)
+}
+
+// collector accepts OTLP/HTTP protobuf exports the way a local collector would.
+type collector struct {
+	mu       sync.Mutex
+	requests []*coltracepb.ExportTraceServiceRequest
+}
+
+func (c *collector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost || r.URL.Path != "/v1/traces" ||
+		r.Header.Get("Content-Type") != "application/x-protobuf" {
+		http.Error(w, "unexpected export", http.StatusBadRequest)
+		return
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var req coltracepb.ExportTraceServiceRequest
+
+	if err := proto.Unmarshal(body, &req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	c.mu.Lock()
+	c.requests = append(c.requests, &req)
+	c.mu.Unlock()
+
+	out, _ := proto.Marshal(&coltracepb.ExportTraceServiceResponse{})
+
+	w.Header().Set("Content-Type", "application/x-protobuf")
+	w.Write(out)
+}
+
+func TestSpansExportToCollector(t *testing.T) {
+	restoreOtel(t)
+
+	c := &collector{}
+	srv := httptest.NewServer(c)
+	defer srv.Close()
+
+	ctx := context.Background()
+
+	shutdown, err := tracing.Setup(ctx, &config.Config{
+		TracingEnabled:   true,
+		ServiceName:      "a-realtor-test",
+		GoEnv:            "test",
+		OTLPEndpoint:     strings.TrimPrefix(srv.URL, "http://"),
+		OTLPInsecure:     true,
+		TraceSampleRatio: 1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	serveTraced(t)
+
+	if err := shutdown(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var spans []span
+
+	for _, req := range c.requests {
+		for _, rs := range req.ResourceSpans {
+			service := ""
+
+			for _, attr := range rs.Resource.GetAttributes() {
+				if attr.Key == "service.name" {
+					service = attr.Value.GetStringValue()
+				}
+			}
+
+			if service != "a-realtor-test" {
+				t.Errorf("service.name = %q", service)
+			}
+
+			for _, ss := range rs.ScopeSpans {
+				for _, s := range ss.Spans {
+					spans = append(spans, span{
+						name:     s.Name,
+						traceID:  hex.EncodeToString(s.TraceId),
+						spanID:   hex.EncodeToString(s.SpanId),
+						parentID: hex.EncodeToString(s.ParentSpanId),
+						kind:     trace.SpanKind(s.Kind),
+					})
+				}
+			}
+		}
+	}
+
+	checkParenting(t, spans)
+}
@@ -0,0 +1,185 @@
+package psql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+
+	"github.com/google/uuid"
+
+	"github.com/emma769/a-realtor/internal/entity"
+	"github.com/emma769/a-realtor/internal/repository"
+)
+
+func (q *queries) CreateOIDCState(ctx context.Context, state *entity.OIDCState) error {
+	const stmt = `
+  INSERT INTO oidc_states (hash, nonce, verifier, valid_till) VALUES ($1, $2, $3, $4);
+  `
+	_, err := q.db.op("CreateOIDCState").ExecContext(
+		ctx,
+		stmt,
+		state.Hash,
+		state.Nonce,
+		state.Verifier,
+		state.ValidTill,
+	)
+	return err
+}
+
+func (q *queries) ConsumeOIDCState(ctx context.Context, hash []byte) (*entity.OIDCState, error) {
+	const query = `
+  DELETE FROM oidc_states WHERE hash = $1 AND valid_till > current_timestamp
+  RETURNING hash, nonce, verifier, valid_till;
+  `
+	row := q.db.op("ConsumeOIDCState").QueryRowContext(ctx, query, hash)
+
+	var state entity.OIDCState
+
+	err := row.Scan(&state.Hash, &state.Nonce, &state.Verifier, &state.ValidTill)
+
+	if err != nil && errors.Is(err, sql.ErrNoRows) {
+		return nil, repository.ErrNotFound
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &state, nil
+}
+
+func (q *queries) FindUserByIdentity(
+	ctx context.Context,
+	issuer, subject string,
+) (*entity.User, error) {
+	const query = `
+  WITH identity AS (
+    UPDATE user_identities SET last_login_at = current_timestamp
+    WHERE issuer = $1 AND subject = $2
+    RETURNING user_id
+  )
+  SELECT ` + userColumns + ` FROM users WHERE user_id = (SELECT user_id FROM identity);
+  `
+	row := q.db.op("FindUserByIdentity").QueryRowContext(ctx, query, issuer, subject)
+
+	var user entity.User
+
+	err := ScanUser(row, &user)
+
+	if err != nil && errors.Is(err, sql.ErrNoRows) {
+		return nil, repository.ErrNotFound
+	}
+
+	return &user, err
+}
+
+func (q *queries) linkIdentity(ctx context.Context, identity *entity.Identity) error {
+	const stmt = `
+  INSERT INTO user_identities (issuer, subject, user_id, email, last_login_at)
+  VALUES ($1, $2, $3, $4, current_timestamp);
+  `
+	_, err := q.db.op("linkIdentity").ExecContext(
+		ctx,
+		stmt,
+		identity.Issuer,
+		identity.Subject,
+		identity.UserID,
+		identity.Email,
+	)
+
+	if err != nil && strings.Contains(err.Error(), "duplicate") {
+		return repository.ErrDuplicateKey
+	}
+
+	return err
+}
+
+func (repo *Repository) LinkIdentity(
+	ctx context.Context,
+	identity *entity.Identity,
+) (*entity.User, error) {
+	const query = `
+  UPDATE users SET email_verified_at = COALESCE(email_verified_at, current_timestamp)
+  WHERE user_id = $1
+  RETURNING ` + userColumns + `;
+  `
+	var user entity.User
+
+	err := repo.inTx(ctx, func(q *queries) error {
+		if err := q.linkIdentity(ctx, identity); err != nil {
+			return err
+		}
+
+		err := ScanUser(q.db.op("LinkIdentity").QueryRowContext(ctx, query, identity.UserID), &user)
+
+		if err != nil && errors.Is(err, sql.ErrNoRows) {
+			return repository.ErrNotFound
+		}
+
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &user, nil
+}
+
+type SSOUserParam interface {
+	Name() string
+	Email() string
+	Role() entity.Role
+}
+
+func (repo *Repository) ProvisionSSOUser(
+	ctx context.Context,
+	param SSOUserParam,
+	identity *entity.Identity,
+) (*entity.User, error) {
+	const query = `
+  INSERT INTO users (name, email, role, status, email_verified_at)
+  VALUES ($1, $2, $3, 'active', current_timestamp)
+  RETURNING ` + userColumns + `;
+  `
+	var user entity.User
+
+	err := repo.inTx(ctx, func(q *queries) error {
+		row := q.db.op("ProvisionSSOUser").QueryRowContext(
+			ctx,
+			query,
+			param.Name(),
+			param.Email(),
+			param.Role(),
+		)
+
+		err := ScanUser(row, &user)
+
+		if err != nil && strings.Contains(err.Error(), "duplicate") {
+			return repository.ErrDuplicateKey
+		}
+
+		if err != nil {
+			return err
+		}
+
+		identity.UserID = user.UserID
+
+		return q.linkIdentity(ctx, identity)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &user, nil
+}
+
+func (q *queries) SetUserRole(ctx context.Context, id uuid.UUID, role entity.Role) error {
+	const stmt = `UPDATE users SET role = $2 WHERE user_id = $1;`
+	res, err := q.db.op("SetUserRole").ExecContext(ctx, stmt, id, role)
+	if err != nil {
+		return err
+	}
+
+	return expectRows(res)
+}
@@ -22,13 +22,13 @@ type dbtx interface {
 }
 
 type queries struct {
-	db dbtx
+	db *observedDB
 }
 
-func newQueries(db dbtx) *queries {
-	return &queries{db}
+func newQueries(db dbtx, observe QueryObserver) *queries {
+	return &queries{&observedDB{db, observe}}
 }
 
 func (q *queries) WithTX(db dbtx) *queries {
-	return newQueries(db)
+	return &queries{q.db.with(db)}
 }
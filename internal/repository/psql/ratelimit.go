@@ -0,0 +1,54 @@
+package psql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+)
+
+const refillTokens = `LEAST(
+    $2::float8,
+    rl.tokens + EXTRACT(EPOCH FROM current_timestamp - rl.updated_at) * $3::float8
+  )`
+
+func (q *queries) TakeRateLimitToken(
+	ctx context.Context,
+	key string,
+	burst int,
+	rate float64,
+) (bool, float64, error) {
+	const stmt = `
+  INSERT INTO rate_limits AS rl (key, tokens, updated_at, full_at)
+  VALUES (
+    $1,
+    $2::float8 - 1,
+    current_timestamp,
+    current_timestamp + make_interval(secs => 1 / $3::float8)
+  )
+  ON CONFLICT (key) DO UPDATE SET
+    tokens = ` + refillTokens + ` - 1,
+    updated_at = current_timestamp,
+    full_at = current_timestamp + make_interval(
+      secs => ($2::float8 - ` + refillTokens + ` + 1) / $3::float8
+    )
+  WHERE ` + refillTokens + ` >= 1
+  RETURNING tokens;
+  `
+	var tokens float64
+
+	err := q.db.op("TakeRateLimitToken").QueryRowContext(ctx, stmt, key, burst, rate).Scan(&tokens)
+
+	if err == nil {
+		return true, tokens, nil
+	}
+
+	if !errors.Is(err, sql.ErrNoRows) {
+		return false, 0, err
+	}
+
+	const query = `
+  SELECT ` + refillTokens + ` FROM rate_limits AS rl WHERE key = $1;
+  `
+	err = q.db.op("TakeRateLimitToken").QueryRowContext(ctx, query, key, burst, rate).Scan(&tokens)
+	return false, tokens, err
+}
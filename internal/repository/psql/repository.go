@@ -4,10 +4,14 @@ import (
 	"cmp"
 	"context"
 	"database/sql"
+	"errors"
+	"fmt"
 	"log/slog"
 	"time"
 
 	_ "github.com/lib/pq"
+
+	loglib "github.com/emma769/a-realtor/internal/lib/log"
 )
 
 const driver = "postgres"
@@ -22,6 +26,7 @@ type RepositoryOptions struct {
 	MaxIdleConns,
 	MaxOpenConns,
 	ConnMaxIdleTime int
+	QueryObserver QueryObserver
 }
 
 func New(
@@ -45,11 +50,49 @@ func New(
 
 	return &Repository{
 		db:      db,
-		queries: newQueries(db),
+		queries: newQueries(db, options.QueryObserver),
 		logger:  logger,
 	}, nil
 }
 
+func (r *Repository) DB() *sql.DB {
+	return r.db
+}
+
+func (r *Repository) Ping(ctx context.Context) error {
+	return r.db.PingContext(ctx)
+}
+
+func (r *Repository) Stats() sql.DBStats {
+	return r.db.Stats()
+}
+
 func (r *Repository) Close() error {
 	return r.db.Close()
 }
+
+func (repo *Repository) inTx(ctx context.Context, fn func(*queries) error) error {
+	tx, err := repo.db.BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		return fmt.Errorf("beginTx error: %w", err)
+	}
+
+	defer func() {
+		if err := tx.Rollback(); err != nil && !errors.Is(err, sql.ErrTxDone) {
+			loglib.From(ctx, repo.logger).LogAttrs(ctx, slog.LevelError, "rollbackTx err", slog.Attr{
+				Key:   "detail",
+				Value: slog.StringValue(err.Error()),
+			})
+		}
+	}()
+
+	if err := fn(repo.WithTX(tx)); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commitTx err: %w", err)
+	}
+
+	return nil
+}
@@ -5,23 +5,31 @@ import (
 	"database/sql"
 	"errors"
 
+	"github.com/google/uuid"
+
 	"github.com/emma769/a-realtor/internal/entity"
 	"github.com/emma769/a-realtor/internal/repository"
 )
 
 func (q *queries) CreateSession(ctx context.Context, session *entity.Session) error {
 	stmt := `INSERT INTO sessions (hash, user_id, valid_till) VALUES ($1, $2, $3);`
-	_, err := q.db.ExecContext(ctx, stmt, session.Hash, session.UserID, session.ValidTill)
+	_, err := q.db.op("CreateSession").ExecContext(
+		ctx,
+		stmt,
+		session.Hash,
+		session.UserID,
+		session.ValidTill,
+	)
 	return err
 }
 
 func (q *queries) FindUserBySession(ctx context.Context, hash []byte) (*entity.User, error) {
 	stmt := `
-  SELECT user_id, name, email, password, created_at FROM users WHERE user_id in (
+  SELECT ` + userColumns + ` FROM users WHERE user_id in (
     SELECT user_id FROM sessions WHERE hash = $1 AND valid_till > current_timestamp
   );
   `
-	row := q.db.QueryRowContext(ctx, stmt, hash)
+	row := q.db.op("FindUserBySession").QueryRowContext(ctx, stmt, hash)
 	var user entity.User
 
 	err := ScanUser(row, &user)
@@ -32,3 +40,62 @@ func (q *queries) FindUserBySession(ctx context.Context, hash []byte) (*entity.U
 
 	return &user, err
 }
+
+func (q *queries) DeleteUserSessions(ctx context.Context, userID uuid.UUID) error {
+	stmt := `DELETE FROM sessions WHERE user_id = $1;`
+	_, err := q.db.op("DeleteUserSessions").ExecContext(ctx, stmt, userID)
+	return err
+}
+
+func (q *queries) DeleteSession(ctx context.Context, hash []byte) error {
+	stmt := `DELETE FROM sessions WHERE hash = $1;`
+	_, err := q.db.op("DeleteSession").ExecContext(ctx, stmt, hash)
+	return err
+}
+
+func (q *queries) FindUserSessions(
+	ctx context.Context,
+	userID uuid.UUID,
+) ([]*entity.Session, error) {
+	const query = `
+  SELECT session_id, user_id, valid_till, created_at FROM sessions
+  WHERE user_id = $1 AND valid_till > current_timestamp
+  ORDER BY created_at DESC;
+  `
+	rows, err := q.db.op("FindUserSessions").QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	sessions := []*entity.Session{}
+
+	for rows.Next() {
+		var session entity.Session
+
+		err := rows.Scan(
+			&session.SessionID,
+			&session.UserID,
+			&session.ValidTill,
+			&session.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		sessions = append(sessions, &session)
+	}
+
+	return sessions, rows.Err()
+}
+
+func (q *queries) DeleteSessionByID(ctx context.Context, userID uuid.UUID, id int64) error {
+	const stmt = `DELETE FROM sessions WHERE user_id = $1 AND session_id = $2;`
+	res, err := q.db.op("DeleteSessionByID").ExecContext(ctx, stmt, userID, id)
+	if err != nil {
+		return err
+	}
+
+	return expectRows(res)
+}
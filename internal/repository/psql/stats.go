@@ -0,0 +1,33 @@
+package psql
+
+import (
+	"context"
+
+	"github.com/emma769/a-realtor/internal/entity"
+)
+
+func (q *queries) BusinessStats(ctx context.Context) (*entity.BusinessStats, error) {
+	const query = `
+  SELECT
+    (SELECT COUNT(*) FROM landlords),
+    (SELECT COUNT(*) FROM tenants),
+    (SELECT COUNT(DISTINCT tenant_id) FROM rent_info
+      WHERE start_date <= current_timestamp
+        AND (maturity_date IS NULL OR maturity_date >= current_timestamp)),
+    (SELECT COUNT(*) FROM property_info
+      WHERE end_date BETWEEN current_timestamp AND current_timestamp + INTERVAL '30 days');
+  `
+	var stats entity.BusinessStats
+
+	err := q.db.op("BusinessStats").QueryRowContext(ctx, query).Scan(
+		&stats.Landlords,
+		&stats.Tenants,
+		&stats.ActiveTenancies,
+		&stats.ExpiringLeases,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &stats, nil
+}
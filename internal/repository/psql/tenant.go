@@ -5,12 +5,12 @@ import (
 	"database/sql"
 	"encoding/json"
 	"errors"
-	"fmt"
-	"log/slog"
+	"slices"
 	"strings"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 
 	"github.com/emma769/a-realtor/internal/entity"
 	"github.com/emma769/a-realtor/internal/repository"
@@ -49,36 +49,29 @@ func (repo *Repository) CreateTenant(
 	ctx context.Context,
 	param TenantParam,
 ) (*entity.Tenant, error) {
-	tx, err := repo.db.BeginTx(ctx, &sql.TxOptions{})
-	if err != nil {
-		return nil, fmt.Errorf("beginTx error: %w", err)
-	}
+	var tenant *entity.Tenant
 
-	defer func() {
-		if err := tx.Rollback(); err != nil && !errors.Is(err, sql.ErrTxDone) {
-			repo.logger.LogAttrs(ctx, slog.LevelError, "rollbackTx err", slog.Attr{
-				Key:   "detail",
-				Value: slog.StringValue(err.Error()),
-			})
+	err := repo.inTx(ctx, func(q *queries) error {
+		var err error
+
+		tenant, err = q.createTenant(ctx, param)
+		if err != nil {
+			return err
 		}
-	}()
 
-	tenant, err := repo.WithTX(tx).createTenant(ctx, param)
-	if err != nil {
-		return nil, err
-	}
+		rentInfo, err := q.createRentInfo(ctx, tenant.TenantID, param)
+		if err != nil {
+			return err
+		}
+
+		tenant.RentInfo = append(tenant.RentInfo, rentInfo)
 
-	rentInfo, err := repo.WithTX(tx).CreateRentInfo(ctx, tenant.TenantID, param)
+		return q.writeAudit(ctx, entity.AuditCreate, entity.AuditTenant, tenant.TenantID, nil, tenant)
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	if err := tx.Commit(); err != nil {
-		return nil, fmt.Errorf("commitTx err: %w", err)
-	}
-
-	tenant.RentInfo = append(tenant.RentInfo, rentInfo)
-
 	return tenant, nil
 }
 
@@ -92,9 +85,9 @@ func (q *queries) createTenant(ctx context.Context, param TenantParam) (*entity.
     RETURNING 
       tenant_id, first_name, last_name, gender, dob, image, email, phone, 
       state_of_origin, nationality, occupation, additional_info, 
-      registered_by, created_at, updated_at;
+      registered_by, assigned_to, created_at, updated_at, version;
   `
-	row := q.db.QueryRowContext(
+	row := q.db.op("createTenant").QueryRowContext(
 		ctx,
 		query,
 		param.FirstName(),
@@ -128,8 +121,10 @@ func (q *queries) createTenant(ctx context.Context, param TenantParam) (*entity.
 		&tenant.Occupation,
 		&additionalInfo,
 		&tenant.RegisteredBy,
+		&tenant.AssignedTo,
 		&tenant.CreatedAt,
 		&tenant.UpdatedAt,
+		&tenant.Version,
 	)
 
 	if err != nil && strings.Contains(err.Error(), "duplicate") {
@@ -147,7 +142,47 @@ func (q *queries) createTenant(ctx context.Context, param TenantParam) (*entity.
 	return &tenant, nil
 }
 
-func (q *queries) CreateRentInfo(
+func (repo *Repository) CreateRentInfo(
+	ctx context.Context,
+	id uuid.UUID,
+	version int,
+	param RentInfoParam,
+) (*entity.RentInfo, error) {
+	var rentInfo *entity.RentInfo
+
+	err := repo.inTx(ctx, func(q *queries) error {
+		before, err := q.findTenantForUpdate(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		if before.Version != version {
+			return repository.ErrVersionConflict
+		}
+
+		rentInfo, err = q.createRentInfo(ctx, id, param)
+		if err != nil {
+			return err
+		}
+
+		if err := q.bumpTenantVersion(ctx, id); err != nil {
+			return err
+		}
+
+		after := *before
+		after.Version++
+		after.RentInfo = append(slices.Clone(before.RentInfo), rentInfo)
+
+		return q.writeAudit(ctx, entity.AuditUpdate, entity.AuditTenant, id, before, &after)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return rentInfo, nil
+}
+
+func (q *queries) createRentInfo(
 	ctx context.Context,
 	id uuid.UUID,
 	param RentInfoParam,
@@ -160,7 +195,7 @@ func (q *queries) CreateRentInfo(
       rent_info_id, start_date, maturity_date, renewal_date, 
       landlord_id, tenant_id, address, rent_fee;
   `
-	row := q.db.QueryRowContext(
+	row := q.db.op("createRentInfo").QueryRowContext(
 		ctx,
 		query,
 		param.StartDate(),
@@ -192,6 +227,7 @@ type TenantFilterParam interface {
 	FirstName() string
 	Phone() string
 	Address() string
+	Owners() []string
 }
 
 func (q *queries) FindTenants(
@@ -208,15 +244,17 @@ func (q *queries) FindTenants(
     WHERE (lower(t.first_name) = lower($1) OR $1 = '') 
     AND (t.phone = $2 OR $2 = '')
     AND (to_tsvector('simple', r.address) @@ plainto_tsquery('simple', $3) OR $3 = '')
-    LIMIT $4 OFFSET $5;
+    AND ($4::UUID[] IS NULL OR COALESCE(t.assigned_to, t.registered_by) = ANY($4))
+    LIMIT $5 OFFSET $6;
   `
 
-	rows, err := q.db.QueryContext(
+	rows, err := q.db.op("FindTenants").QueryContext(
 		ctx,
 		query,
 		filterParam.FirstName(),
 		filterParam.Phone(),
 		filterParam.Address(),
+		pq.StringArray(filterParam.Owners()),
 		paginator.Limit(),
 		paginator.Offset(),
 	)
@@ -282,7 +320,7 @@ func (q *queries) FindTenant(ctx context.Context, id uuid.UUID) (*entity.Tenant,
 	const query = `
     SELECT t.tenant_id, t.first_name, t.last_name, t.gender, t.dob, t.image, t.email, 
       t.phone, t.state_of_origin, t.nationality, t.occupation, t.additional_info, 
-      t.registered_by, t.created_at, t.updated_at,
+      t.registered_by, t.assigned_to, t.created_at, t.updated_at, t.version,
       CASE WHEN count(r.rent_info_id) = 0 THEN
         '[]'::JSON
       ELSE
@@ -301,7 +339,7 @@ func (q *queries) FindTenant(ctx context.Context, id uuid.UUID) (*entity.Tenant,
     WHERE t.tenant_id = $1 GROUP BY t.tenant_id, t.phone;
   `
 
-	row := q.db.QueryRowContext(ctx, query, id)
+	row := q.db.op("FindTenant").QueryRowContext(ctx, query, id)
 
 	var rentInfo []byte
 	var additionalInfo []byte
@@ -321,8 +359,10 @@ func (q *queries) FindTenant(ctx context.Context, id uuid.UUID) (*entity.Tenant,
 		&tenant.Occupation,
 		&additionalInfo,
 		&tenant.RegisteredBy,
+		&tenant.AssignedTo,
 		&tenant.CreatedAt,
 		&tenant.UpdatedAt,
+		&tenant.Version,
 		&rentInfo,
 	)
 
@@ -345,10 +385,36 @@ func (q *queries) FindTenant(ctx context.Context, id uuid.UUID) (*entity.Tenant,
 	return &tenant, nil
 }
 
-func (q *queries) TenantTotalCount(ctx context.Context) (int64, error) {
-	const query = "SELECT COUNT(*) FROM tenants;"
+func (q *queries) findTenantForUpdate(ctx context.Context, id uuid.UUID) (*entity.Tenant, error) {
+	const query = `SELECT tenant_id FROM tenants WHERE tenant_id = $1 FOR UPDATE;`
+
+	if err := q.db.op("findTenantForUpdate").QueryRowContext(ctx, query, id).Scan(&id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, repository.ErrNotFound
+		}
 
-	row := q.db.QueryRowContext(ctx, query)
+		return nil, err
+	}
+
+	return q.FindTenant(ctx, id)
+}
+
+func (q *queries) bumpTenantVersion(ctx context.Context, id uuid.UUID) error {
+	const stmt = `
+    UPDATE tenants SET version = version + 1, updated_at = current_timestamp
+    WHERE tenant_id = $1;
+  `
+	_, err := q.db.op("bumpTenantVersion").ExecContext(ctx, stmt, id)
+	return err
+}
+
+func (q *queries) TenantTotalCount(ctx context.Context, owners []string) (int64, error) {
+	const query = `
+    SELECT COUNT(*) FROM tenants
+    WHERE $1::UUID[] IS NULL OR COALESCE(assigned_to, registered_by) = ANY($1);
+  `
+
+	row := q.db.op("TenantTotalCount").QueryRowContext(ctx, query, pq.StringArray(owners))
 
 	var total int64
 
@@ -360,25 +426,40 @@ func (q *queries) TenantTotalCount(ctx context.Context) (int64, error) {
 	return total, nil
 }
 
-func (q *queries) DeleteTenant(ctx context.Context, id uuid.UUID) error {
-	const query = `DELETE FROM tenants WHERE tenant_id = $1;`
+func (repo *Repository) DeleteTenant(ctx context.Context, id uuid.UUID, version int) error {
+	const stmt = `DELETE FROM tenants WHERE tenant_id = $1;`
 
-	if _, err := q.db.ExecContext(ctx, query, id); err != nil {
-		return err
-	}
+	return repo.inTx(ctx, func(q *queries) error {
+		before, err := q.findTenantForUpdate(ctx, id)
+		if err != nil {
+			return err
+		}
 
-	return nil
+		if before.Version != version {
+			return repository.ErrVersionConflict
+		}
+
+		if _, err := q.db.op("DeleteTenant").ExecContext(ctx, stmt, id); err != nil {
+			return err
+		}
+
+		return q.writeAudit(ctx, entity.AuditDelete, entity.AuditTenant, id, before, nil)
+	})
 }
 
-func (q *queries) FindAllTenants(ctx context.Context) ([]*entity.TenantOut, error) {
+func (q *queries) FindAllTenants(
+	ctx context.Context,
+	owners []string,
+) ([]*entity.TenantOut, error) {
 	const query = `
     SELECT t.tenant_id, t.first_name, t.last_name, t.gender, t.dob, 
       t.image, t.email, t.phone, t.state_of_origin, t.nationality, t.occupation,
       t.additional_info, r.start_date, r.maturity_date, r.renewal_date,
       r.address, r.rent_fee, r.landlord_id, t.created_at, t.updated_at
-    FROM tenants t LEFT JOIN rent_info r ON t.tenant_id = r.tenant_id;
+    FROM tenants t LEFT JOIN rent_info r ON t.tenant_id = r.tenant_id
+    WHERE $1::UUID[] IS NULL OR COALESCE(t.assigned_to, t.registered_by) = ANY($1);
   `
-	rows, err := q.db.QueryContext(ctx, query)
+	rows, err := q.db.op("FindAllTenants").QueryContext(ctx, query, pq.StringArray(owners))
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,203 @@
+package psql
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/google/uuid"
+
+	"github.com/emma769/a-realtor/internal/entity"
+	"github.com/emma769/a-realtor/internal/repository"
+)
+
+func (q *queries) SetTOTPSecret(ctx context.Context, id uuid.UUID, secret []byte) error {
+	const stmt = `
+  UPDATE users SET totp_secret = $2 WHERE user_id = $1 AND totp_enabled_at IS NULL;
+  `
+	res, err := q.db.op("SetTOTPSecret").ExecContext(ctx, stmt, id, secret)
+	if err != nil {
+		return err
+	}
+
+	return expectRows(res)
+}
+
+func (repo *Repository) EnableTOTP(
+	ctx context.Context,
+	id uuid.UUID,
+	step int64,
+	codes [][]byte,
+) error {
+	const stmt = `
+  UPDATE users SET totp_enabled_at = current_timestamp, totp_last_step = $2
+  WHERE user_id = $1 AND totp_secret IS NOT NULL AND totp_enabled_at IS NULL;
+  `
+	return repo.inTx(ctx, func(q *queries) error {
+		res, err := q.db.op("EnableTOTP").ExecContext(ctx, stmt, id, step)
+		if err != nil {
+			return err
+		}
+
+		if err := expectRows(res); err != nil {
+			return err
+		}
+
+		return q.replaceRecoveryCodes(ctx, id, codes)
+	})
+}
+
+func (repo *Repository) DisableTOTP(ctx context.Context, id uuid.UUID) error {
+	const stmt = `
+  UPDATE users SET totp_secret = NULL, totp_enabled_at = NULL, totp_last_step = 0
+  WHERE user_id = $1;
+  `
+	return repo.inTx(ctx, func(q *queries) error {
+		if _, err := q.db.op("DisableTOTP").ExecContext(ctx, stmt, id); err != nil {
+			return err
+		}
+
+		return q.replaceRecoveryCodes(ctx, id, nil)
+	})
+}
+
+func (q *queries) AdvanceTOTPStep(ctx context.Context, id uuid.UUID, step int64) error {
+	const stmt = `
+  UPDATE users SET totp_last_step = $2 WHERE user_id = $1 AND totp_last_step < $2;
+  `
+	res, err := q.db.op("AdvanceTOTPStep").ExecContext(ctx, stmt, id, step)
+	if err != nil {
+		return err
+	}
+
+	return expectRows(res)
+}
+
+func (repo *Repository) ReplaceRecoveryCodes(
+	ctx context.Context,
+	id uuid.UUID,
+	codes [][]byte,
+) error {
+	return repo.inTx(ctx, func(q *queries) error {
+		return q.replaceRecoveryCodes(ctx, id, codes)
+	})
+}
+
+func (q *queries) replaceRecoveryCodes(ctx context.Context, id uuid.UUID, codes [][]byte) error {
+	const deleteStmt = `DELETE FROM recovery_codes WHERE user_id = $1;`
+	const insertStmt = `INSERT INTO recovery_codes (user_id, hash) VALUES ($1, $2);`
+
+	if _, err := q.db.op("replaceRecoveryCodes").ExecContext(ctx, deleteStmt, id); err != nil {
+		return err
+	}
+
+	for i := range codes {
+		if _, err := q.db.op("replaceRecoveryCodes").ExecContext(
+			ctx,
+			insertStmt,
+			id,
+			codes[i],
+		); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (q *queries) UseRecoveryCode(ctx context.Context, id uuid.UUID, hash []byte) error {
+	const stmt = `
+  UPDATE recovery_codes SET used_at = current_timestamp
+  WHERE user_id = $1 AND hash = $2 AND used_at IS NULL;
+  `
+	res, err := q.db.op("UseRecoveryCode").ExecContext(ctx, stmt, id, hash)
+	if err != nil {
+		return err
+	}
+
+	return expectRows(res)
+}
+
+func (repo *Repository) ConsumeUserToken(
+	ctx context.Context,
+	hash []byte,
+	purpose entity.TokenPurpose,
+) (uuid.UUID, error) {
+	var id uuid.UUID
+
+	err := repo.inTx(ctx, func(q *queries) error {
+		var err error
+
+		id, err = q.lockUserToken(ctx, hash, purpose)
+		if err != nil {
+			return err
+		}
+
+		return q.DeleteUserTokens(ctx, id, purpose)
+	})
+
+	return id, err
+}
+
+func (q *queries) FindRolePolicies(ctx context.Context) ([]*entity.RolePolicy, error) {
+	const query = `SELECT role, require_2fa, updated_at FROM role_policies ORDER BY role;`
+
+	rows, err := q.db.op("FindRolePolicies").QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	policies := []*entity.RolePolicy{}
+
+	for rows.Next() {
+		var policy entity.RolePolicy
+
+		if err := rows.Scan(&policy.Role, &policy.Require2FA, &policy.UpdatedAt); err != nil {
+			return nil, err
+		}
+
+		policies = append(policies, &policy)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+
+	return policies, nil
+}
+
+func (q *queries) UpsertRolePolicy(
+	ctx context.Context,
+	role entity.Role,
+	require2FA bool,
+) (*entity.RolePolicy, error) {
+	const query = `
+  INSERT INTO role_policies (role, require_2fa) VALUES ($1, $2)
+  ON CONFLICT (role) DO UPDATE SET
+    require_2fa = EXCLUDED.require_2fa, updated_at = current_timestamp
+  RETURNING role, require_2fa, updated_at;
+  `
+	row := q.db.op("UpsertRolePolicy").QueryRowContext(ctx, query, role, require2FA)
+
+	var policy entity.RolePolicy
+
+	err := row.Scan(&policy.Role, &policy.Require2FA, &policy.UpdatedAt)
+
+	return &policy, err
+}
+
+func expectRows(res sql.Result) error {
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if n == 0 {
+		return repository.ErrNotFound
+	}
+
+	return nil
+}
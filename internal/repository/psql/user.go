@@ -18,12 +18,56 @@ type UserParam interface {
 	Password() []byte
 }
 
+const userColumns = `
+  user_id, name, email, password, role, status, email_verified_at, pending_email,
+  totp_secret, totp_enabled_at, totp_last_step,
+  COALESCE((SELECT require_2fa FROM role_policies WHERE role_policies.role = users.role), false),
+  manager_id, created_at`
+
 func (q *queries) CreateUser(ctx context.Context, param UserParam) (*entity.User, error) {
 	const query = `
   INSERT INTO users (name, email, password) VALUES ($1, $2, $3)
-  RETURNING user_id, name, email, password, created_at;
+  RETURNING ` + userColumns + `;
+  `
+	row := q.db.op("CreateUser").QueryRowContext(
+		ctx,
+		query,
+		param.Name(),
+		param.Email(),
+		param.Password(),
+	)
+
+	var user entity.User
+
+	err := ScanUser(row, &user)
+
+	if err != nil && strings.Contains(err.Error(), "duplicate") {
+		return nil, repository.ErrDuplicateKey
+	}
+
+	return &user, err
+}
+
+// CreateVerifiedUser inserts a user with their role and a verified email in one statement, for
+// operators creating accounts outside the signup flow.
+func (q *queries) CreateVerifiedUser(
+	ctx context.Context,
+	param UserParam,
+	role entity.Role,
+) (*entity.User, error) {
+	const query = `
+  INSERT INTO users (name, email, password, role, email_verified_at)
+  VALUES ($1, $2, $3, $4, current_timestamp)
+  RETURNING ` + userColumns + `;
   `
-	row := q.db.QueryRowContext(ctx, query, param.Name(), param.Email(), param.Password())
+	row := q.db.op("CreateVerifiedUser").QueryRowContext(
+		ctx,
+		query,
+		param.Name(),
+		param.Email(),
+		param.Password(),
+		role,
+	)
 
 	var user entity.User
 
@@ -38,9 +82,9 @@ func (q *queries) CreateUser(ctx context.Context, param UserParam) (*entity.User
 
 func (q *queries) FindUserByEmail(ctx context.Context, email string) (*entity.User, error) {
 	const query = `
-  SELECT user_id, name, email, password, created_at FROM users WHERE email = $1;
+  SELECT ` + userColumns + ` FROM users WHERE LOWER(email) = LOWER($1);
   `
-	row := q.db.QueryRowContext(ctx, query, email)
+	row := q.db.op("FindUserByEmail").QueryRowContext(ctx, query, email)
 
 	var user entity.User
 
@@ -55,9 +99,328 @@ func (q *queries) FindUserByEmail(ctx context.Context, email string) (*entity.Us
 
 func (q *queries) FindUserByID(ctx context.Context, id uuid.UUID) (*entity.User, error) {
 	const query = `
-  SELECT user_id, name, email, password, created_at FROM users WHERE user_id = $1;
+  SELECT ` + userColumns + ` FROM users WHERE user_id = $1;
+  `
+	row := q.db.op("FindUserByID").QueryRowContext(ctx, query, id)
+
+	var user entity.User
+
+	err := ScanUser(row, &user)
+
+	if err != nil && errors.Is(err, sql.ErrNoRows) {
+		return nil, repository.ErrNotFound
+	}
+
+	return &user, err
+}
+
+func (q *queries) UpdateUserPassword(ctx context.Context, id uuid.UUID, password []byte) error {
+	const stmt = `UPDATE users SET password = $2 WHERE user_id = $1;`
+
+	res, err := q.db.op("UpdateUserPassword").ExecContext(ctx, stmt, id, password)
+	if err != nil {
+		return err
+	}
+
+	return expectRows(res)
+}
+
+func (q *queries) MarkEmailVerified(ctx context.Context, id uuid.UUID) error {
+	const stmt = `
+  UPDATE users SET email_verified_at = COALESCE(email_verified_at, current_timestamp)
+  WHERE user_id = $1;
+  `
+	res, err := q.db.op("MarkEmailVerified").ExecContext(ctx, stmt, id)
+	if err != nil {
+		return err
+	}
+
+	return expectRows(res)
+}
+
+func (repo *Repository) ChangePassword(ctx context.Context, id uuid.UUID, password []byte) error {
+	return repo.inTx(ctx, func(q *queries) error {
+		if err := q.UpdateUserPassword(ctx, id, password); err != nil {
+			return err
+		}
+
+		if err := q.DeleteUserTokens(ctx, id, entity.PurposePasswordReset); err != nil {
+			return err
+		}
+
+		return q.DeleteUserSessions(ctx, id)
+	})
+}
+
+func (q *queries) UpdateUserProfile(
+	ctx context.Context,
+	id uuid.UUID,
+	name string,
+) (*entity.User, error) {
+	const query = `
+  UPDATE users SET name = $2 WHERE user_id = $1
+  RETURNING ` + userColumns + `;
+  `
+	row := q.db.op("UpdateUserProfile").QueryRowContext(ctx, query, id, name)
+
+	var user entity.User
+
+	err := ScanUser(row, &user)
+
+	if err != nil && errors.Is(err, sql.ErrNoRows) {
+		return nil, repository.ErrNotFound
+	}
+
+	return &user, err
+}
+
+func (q *queries) SetPendingEmail(ctx context.Context, id uuid.UUID, email string) error {
+	const stmt = `UPDATE users SET pending_email = $2 WHERE user_id = $1;`
+
+	res, err := q.db.op("SetPendingEmail").ExecContext(ctx, stmt, id, email)
+	if err != nil {
+		return err
+	}
+
+	return expectRows(res)
+}
+
+// ConfirmEmailChange swaps the pending email in and returns the updated user along with the
+// address it replaced.
+func (repo *Repository) ConfirmEmailChange(
+	ctx context.Context,
+	hash []byte,
+) (*entity.User, string, error) {
+	const query = `
+  UPDATE users SET
+    email = pending_email,
+    pending_email = NULL,
+    email_verified_at = current_timestamp
+  WHERE user_id = $1 AND pending_email IS NOT NULL
+  RETURNING ` + userColumns + `;
+  `
+	var user entity.User
+	var previous string
+
+	err := repo.inTx(ctx, func(q *queries) error {
+		id, err := q.lockUserToken(ctx, hash, entity.PurposeEmailChange)
+		if err != nil {
+			return err
+		}
+
+		before, err := q.FindUserByID(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		previous = before.Email
+
+		err = ScanUser(q.db.op("ConfirmEmailChange").QueryRowContext(ctx, query, id), &user)
+
+		if err != nil && errors.Is(err, sql.ErrNoRows) {
+			return repository.ErrNotFound
+		}
+
+		if err != nil && strings.Contains(err.Error(), "duplicate") {
+			return repository.ErrDuplicateKey
+		}
+
+		if err != nil {
+			return err
+		}
+
+		return q.DeleteUserTokens(ctx, id, entity.PurposeEmailChange)
+	})
+
+	return &user, previous, err
+}
+
+func (repo *Repository) ResetPassword(
+	ctx context.Context,
+	hash []byte,
+	password []byte,
+) (uuid.UUID, error) {
+	var id uuid.UUID
+
+	err := repo.inTx(ctx, func(q *queries) error {
+		var err error
+
+		id, err = q.lockUserToken(ctx, hash, entity.PurposePasswordReset)
+		if err != nil {
+			return err
+		}
+
+		if err := q.UpdateUserPassword(ctx, id, password); err != nil {
+			return err
+		}
+
+		if err := q.DeleteUserTokens(ctx, id, entity.PurposePasswordReset); err != nil {
+			return err
+		}
+
+		return q.DeleteUserSessions(ctx, id)
+	})
+
+	return id, err
+}
+
+func (repo *Repository) VerifyEmail(ctx context.Context, hash []byte) (uuid.UUID, error) {
+	const stmt = `
+  UPDATE users SET email_verified_at = current_timestamp WHERE user_id = $1;
+  `
+	var id uuid.UUID
+
+	err := repo.inTx(ctx, func(q *queries) error {
+		var err error
+
+		id, err = q.lockUserToken(ctx, hash, entity.PurposeEmailVerification)
+		if err != nil {
+			return err
+		}
+
+		if _, err := q.db.op("VerifyEmail").ExecContext(ctx, stmt, id); err != nil {
+			return err
+		}
+
+		return q.DeleteUserTokens(ctx, id, entity.PurposeEmailVerification)
+	})
+
+	return id, err
+}
+
+type InviteParam interface {
+	Name() string
+	Email() string
+	Role() entity.Role
+}
+
+func (q *queries) CreatePendingUser(
+	ctx context.Context,
+	param InviteParam,
+) (*entity.User, error) {
+	const query = `
+  INSERT INTO users (name, email, role, status) VALUES ($1, $2, $3, 'pending')
+  RETURNING ` + userColumns + `;
+  `
+	row := q.db.op("CreatePendingUser").QueryRowContext(
+		ctx,
+		query,
+		param.Name(),
+		param.Email(),
+		param.Role(),
+	)
+
+	var user entity.User
+
+	err := ScanUser(row, &user)
+
+	if err != nil && strings.Contains(err.Error(), "duplicate") {
+		return nil, repository.ErrDuplicateKey
+	}
+
+	return &user, err
+}
+
+func (repo *Repository) AcceptInvite(
+	ctx context.Context,
+	hash []byte,
+	name string,
+	password []byte,
+) (uuid.UUID, error) {
+	const stmt = `
+  UPDATE users SET
+    name = $2, password = $3, status = 'active', email_verified_at = current_timestamp
+  WHERE user_id = $1 AND status = 'pending';
+  `
+	var id uuid.UUID
+
+	err := repo.inTx(ctx, func(q *queries) error {
+		var err error
+
+		id, err = q.lockUserToken(ctx, hash, entity.PurposeInvite)
+		if err != nil {
+			return err
+		}
+
+		res, err := q.db.op("AcceptInvite").ExecContext(ctx, stmt, id, name, password)
+		if err != nil {
+			return err
+		}
+
+		if err := expectRows(res); err != nil {
+			return err
+		}
+
+		return q.DeleteUserTokens(ctx, id, entity.PurposeInvite)
+	})
+
+	return id, err
+}
+
+type UserFilterParam interface {
+	Role() entity.Role
+	Status() entity.UserStatus
+}
+
+func (q *queries) FindUsers(
+	ctx context.Context,
+	filterParam UserFilterParam,
+	paginator PaginationParam,
+) ([]*entity.User, error) {
+	const query = `
+  SELECT COUNT(*) OVER(), ` + userColumns + ` FROM users
+  WHERE (role = $1 OR $1 = '') AND (status = $2 OR $2 = '')
+  ORDER BY created_at DESC
+  LIMIT $3 OFFSET $4;
   `
-	row := q.db.QueryRowContext(ctx, query, id)
+	rows, err := q.db.op("FindUsers").QueryContext(
+		ctx,
+		query,
+		filterParam.Role(),
+		filterParam.Status(),
+		paginator.Limit(),
+		paginator.Offset(),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	var total int
+	users := []*entity.User{}
+
+	for rows.Next() {
+		var user entity.User
+
+		if err := rows.Scan(append([]any{&total}, userFields(&user)...)...); err != nil {
+			return nil, err
+		}
+
+		users = append(users, &user)
+	}
+
+	paginator.SetTotal(total)
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+
+	return users, nil
+}
+
+func (q *queries) UpdateUserStatus(
+	ctx context.Context,
+	id uuid.UUID,
+	from, to entity.UserStatus,
+) (*entity.User, error) {
+	const query = `
+  UPDATE users SET status = $3 WHERE user_id = $1 AND status = $2
+  RETURNING ` + userColumns + `;
+  `
+	row := q.db.op("UpdateUserStatus").QueryRowContext(ctx, query, id, from, to)
 
 	var user entity.User
 
@@ -70,12 +433,53 @@ func (q *queries) FindUserByID(ctx context.Context, id uuid.UUID) (*entity.User,
 	return &user, err
 }
 
+func (repo *Repository) DeactivateUser(ctx context.Context, id uuid.UUID) (*entity.User, error) {
+	var user *entity.User
+
+	err := repo.inTx(ctx, func(q *queries) error {
+		var err error
+
+		user, err = q.UpdateUserStatus(ctx, id, entity.StatusActive, entity.StatusDeactivated)
+		if err != nil {
+			return err
+		}
+
+		return q.DeleteUserSessions(ctx, id)
+	})
+
+	return user, err
+}
+
+func (q *queries) DeletePendingUser(ctx context.Context, id uuid.UUID) error {
+	const stmt = `DELETE FROM users WHERE user_id = $1 AND status = 'pending';`
+
+	res, err := q.db.op("DeletePendingUser").ExecContext(ctx, stmt, id)
+	if err != nil {
+		return err
+	}
+
+	return expectRows(res)
+}
+
 func ScanUser(row scanner, user *entity.User) error {
-	return row.Scan(
+	return row.Scan(userFields(user)...)
+}
+
+func userFields(user *entity.User) []any {
+	return []any{
 		&user.UserID,
 		&user.Name,
 		&user.Email,
 		&user.Password,
+		&user.Role,
+		&user.Status,
+		&user.EmailVerifiedAt,
+		&user.PendingEmail,
+		&user.TOTPSecret,
+		&user.TOTPEnabledAt,
+		&user.TOTPLastStep,
+		&user.MFARequired,
+		&user.ManagerID,
 		&user.CreatedAt,
-	)
+	}
 }
@@ -0,0 +1,60 @@
+package psql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/google/uuid"
+
+	"github.com/emma769/a-realtor/internal/entity"
+	"github.com/emma769/a-realtor/internal/repository"
+)
+
+func (q *queries) CreateUserToken(ctx context.Context, t *entity.UserToken) error {
+	const stmt = `
+  INSERT INTO user_tokens (hash, user_id, purpose, valid_till) VALUES ($1, $2, $3, $4);
+  `
+	_, err := q.db.op("CreateUserToken").ExecContext(
+		ctx,
+		stmt,
+		t.Hash,
+		t.UserID,
+		t.Purpose,
+		t.ValidTill,
+	)
+	return err
+}
+
+func (q *queries) DeleteUserTokens(
+	ctx context.Context,
+	userID uuid.UUID,
+	purpose entity.TokenPurpose,
+) error {
+	const stmt = `DELETE FROM user_tokens WHERE user_id = $1 AND purpose = $2;`
+	_, err := q.db.op("DeleteUserTokens").ExecContext(ctx, stmt, userID, purpose)
+	return err
+}
+
+func (q *queries) lockUserToken(
+	ctx context.Context,
+	hash []byte,
+	purpose entity.TokenPurpose,
+) (uuid.UUID, error) {
+	const query = `
+  SELECT user_id FROM user_tokens
+  WHERE hash = $1 AND purpose = $2 AND valid_till > current_timestamp
+  FOR UPDATE;
+  `
+	row := q.db.op("lockUserToken").QueryRowContext(ctx, query, hash, purpose)
+
+	var id uuid.UUID
+
+	err := row.Scan(&id)
+
+	if err != nil && errors.Is(err, sql.ErrNoRows) {
+		return uuid.UUID{}, repository.ErrNotFound
+	}
+
+	return id, err
+}
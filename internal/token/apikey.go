@@ -0,0 +1,55 @@
+package token
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base32"
+	"strings"
+)
+
+const apiKeyPrefix = "ark"
+
+type APIKey struct {
+	Plain  string
+	Prefix string
+	Hash   []byte
+}
+
+func NewAPIKey() (*APIKey, error) {
+	enc := base32.StdEncoding.WithPadding(base32.NoPadding)
+
+	b := make([]byte, 5)
+
+	if _, err := rand.Read(b); err != nil {
+		return nil, err
+	}
+
+	prefix := strings.ToLower(enc.EncodeToString(b))
+
+	secret, err := NewOpaque()
+	if err != nil {
+		return nil, err
+	}
+
+	plain := apiKeyPrefix + "_" + prefix + "_" + secret.Plain
+
+	return &APIKey{
+		Plain:  plain,
+		Prefix: prefix,
+		Hash:   HashOpaque(plain),
+	}, nil
+}
+
+func ParseAPIKey(plain string) (string, bool) {
+	parts := strings.Split(plain, "_")
+
+	if len(parts) != 3 || parts[0] != apiKeyPrefix || parts[1] == "" || parts[2] == "" {
+		return "", false
+	}
+
+	return parts[1], true
+}
+
+func CompareAPIKey(plain string, hash []byte) bool {
+	return subtle.ConstantTimeCompare(HashOpaque(plain), hash) == 1
+}
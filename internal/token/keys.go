@@ -0,0 +1,255 @@
+package token
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"github.com/emma769/a-realtor/internal/config"
+)
+
+var ErrNoSigningKey = errors.New("no jwt signing key configured")
+
+type Key struct {
+	ID      string
+	Method  jwt.SigningMethod
+	Private crypto.Signer
+	Public  crypto.PublicKey
+}
+
+type KeySet struct {
+	signing *Key
+	keys    map[string]*Key
+	secret  []byte
+}
+
+func LoadKeySet(cfg *config.Config) (*KeySet, error) {
+	ks := &KeySet{
+		keys:   map[string]*Key{},
+		secret: []byte(cfg.JwtAccessSecret),
+	}
+
+	if cfg.JwtKeyDir != "" {
+		if err := ks.loadDir(cfg.JwtKeyDir, cfg.JwtSigningKid); err != nil {
+			return nil, err
+		}
+	}
+
+	if cfg.JwtPrivateKeyFile != "" {
+		key, err := loadKeyFile(cfg.JwtPrivateKeyFile)
+		if err != nil {
+			return nil, err
+		}
+
+		if key.Private == nil {
+			return nil, fmt.Errorf("%s: not a private key", cfg.JwtPrivateKeyFile)
+		}
+
+		ks.keys[key.ID] = key
+		ks.signing = key
+	}
+
+	for _, path := range cfg.JwtPublicKeyFiles {
+		key, err := loadKeyFile(path)
+		if err != nil {
+			return nil, err
+		}
+
+		ks.keys[key.ID] = key
+	}
+
+	if ks.signing == nil && len(ks.secret) == 0 {
+		return nil, ErrNoSigningKey
+	}
+
+	return ks, nil
+}
+
+func (ks *KeySet) loadDir(dir, kid string) error {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.pem"))
+	if err != nil {
+		return err
+	}
+
+	slices.Sort(paths)
+
+	for _, path := range paths {
+		key, err := loadKeyFile(path)
+		if err != nil {
+			return err
+		}
+
+		ks.keys[key.ID] = key
+
+		if key.Private != nil && (kid == "" || kid == key.ID) {
+			ks.signing = key
+		}
+	}
+
+	if kid != "" && (ks.signing == nil || ks.signing.ID != kid) {
+		return fmt.Errorf("signing key %q not found in %s", kid, dir)
+	}
+
+	return nil
+}
+
+func loadKeyFile(path string) (*Key, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	block, _ := pem.Decode(b)
+	if block == nil {
+		return nil, fmt.Errorf("%s: no pem block found", path)
+	}
+
+	key := &Key{
+		ID: strings.TrimSuffix(filepath.Base(path), filepath.Ext(path)),
+	}
+
+	var parsed any
+
+	switch block.Type {
+	case "PRIVATE KEY":
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PUBLIC KEY":
+		parsed, err = x509.ParsePKIXPublicKey(block.Bytes)
+	case "RSA PUBLIC KEY":
+		parsed, err = x509.ParsePKCS1PublicKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("%s: unsupported pem block %q", path, block.Type)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	switch k := parsed.(type) {
+	case *rsa.PrivateKey:
+		key.Method, key.Private, key.Public = jwt.SigningMethodRS256, k, &k.PublicKey
+	case *rsa.PublicKey:
+		key.Method, key.Public = jwt.SigningMethodRS256, k
+	case ed25519.PrivateKey:
+		key.Method, key.Private, key.Public = jwt.SigningMethodEdDSA, k, k.Public()
+	case ed25519.PublicKey:
+		key.Method, key.Public = jwt.SigningMethodEdDSA, k
+	default:
+		return nil, fmt.Errorf("%s: unsupported key type %T", path, parsed)
+	}
+
+	return key, nil
+}
+
+func (ks *KeySet) sign(t *jwt.Token) (string, error) {
+	if ks.signing == nil {
+		return t.SignedString(ks.secret)
+	}
+
+	t.Header["kid"] = ks.signing.ID
+	return t.SignedString(ks.signing.Private)
+}
+
+func (ks *KeySet) method() jwt.SigningMethod {
+	if ks.signing == nil {
+		return jwt.SigningMethodHS256
+	}
+
+	return ks.signing.Method
+}
+
+func (ks *KeySet) methods() []string {
+	methods := []string{}
+
+	if len(ks.secret) != 0 {
+		methods = append(methods, jwt.SigningMethodHS256.Alg())
+	}
+
+	for _, key := range ks.keys {
+		if !slices.Contains(methods, key.Method.Alg()) {
+			methods = append(methods, key.Method.Alg())
+		}
+	}
+
+	return methods
+}
+
+func (ks *KeySet) verificationKey(t *jwt.Token) (any, error) {
+	kid, _ := t.Header["kid"].(string)
+
+	if kid == "" && t.Method == jwt.SigningMethodHS256 && len(ks.secret) != 0 {
+		return ks.secret, nil
+	}
+
+	key, ok := ks.keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("unknown key id %q", kid)
+	}
+
+	if key.Method != t.Method {
+		return nil, fmt.Errorf("invalid signing method")
+	}
+
+	return key.Public, nil
+}
+
+type JWK struct {
+	Kty string `json:"kty"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+}
+
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+func (ks *KeySet) JWKS() *JWKS {
+	enc := base64.RawURLEncoding
+	set := &JWKS{Keys: []JWK{}}
+
+	for _, key := range ks.keys {
+		jwk := JWK{
+			Use: "sig",
+			Alg: key.Method.Alg(),
+			Kid: key.ID,
+		}
+
+		switch k := key.Public.(type) {
+		case *rsa.PublicKey:
+			jwk.Kty = "RSA"
+			jwk.N = enc.EncodeToString(k.N.Bytes())
+			jwk.E = enc.EncodeToString(big.NewInt(int64(k.E)).Bytes())
+		case ed25519.PublicKey:
+			jwk.Kty = "OKP"
+			jwk.Crv = "Ed25519"
+			jwk.X = enc.EncodeToString(k)
+		}
+
+		set.Keys = append(set.Keys, jwk)
+	}
+
+	slices.SortFunc(set.Keys, func(a, b JWK) int {
+		return strings.Compare(a.Kid, b.Kid)
+	})
+
+	return set
+}
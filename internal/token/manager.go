@@ -15,19 +15,29 @@ import (
 	"github.com/emma769/a-realtor/internal/entity"
 )
 
-var signingMethod = jwt.SigningMethodHS256
-
 type storer interface {
 	CreateSession(context.Context, *entity.Session) error
+	CreateUserToken(context.Context, *entity.UserToken) error
+	DeleteUserTokens(context.Context, uuid.UUID, entity.TokenPurpose) error
 }
 
 type Manager struct {
 	store  storer
 	config *config.Config
+	keys   *KeySet
+}
+
+func NewMgr(cfg *config.Config, store storer) (*Manager, error) {
+	keys, err := LoadKeySet(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Manager{store, cfg, keys}, nil
 }
 
-func NewMgr(cfg *config.Config, store storer) *Manager {
-	return &Manager{store, cfg}
+func (mgr *Manager) JWKS() *JWKS {
+	return mgr.keys.JWKS()
 }
 
 type RefreshToken struct {
@@ -59,12 +69,15 @@ type Payload struct {
 	jwt.RegisteredClaims
 }
 
-func newPayload(id uuid.UUID, exp time.Duration) *Payload {
+func (mgr *Manager) newPayload(id uuid.UUID) *Payload {
 	now := time.Now()
+	exp := mgr.config.JwtAccessExpire
 
 	return &Payload{
 		UserID: id,
 		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    mgr.config.JwtIssuer,
+			Audience:  jwt.ClaimStrings{mgr.config.JwtAudience},
 			ExpiresAt: jwt.NewNumericDate(now.Add(exp)),
 			IssuedAt:  jwt.NewNumericDate(now),
 			NotBefore: jwt.NewNumericDate(now),
@@ -73,12 +86,17 @@ func newPayload(id uuid.UUID, exp time.Duration) *Payload {
 }
 
 func (mgr *Manager) GetAccessToken(id uuid.UUID) (t *jwt.Token, err error) {
-	t = jwt.NewWithClaims(signingMethod, newPayload(id, mgr.config.JwtAccessExpire))
-	t.Raw, err = t.SignedString([]byte(mgr.config.JwtAccessSecret))
+	t = jwt.NewWithClaims(mgr.keys.method(), mgr.newPayload(id))
+	t.Raw, err = mgr.keys.sign(t)
 	return
 }
 
-func (mgr *Manager) getRefreshToken(ctx context.Context, id uuid.UUID) (*RefreshToken, error) {
+type Opaque struct {
+	Plain string
+	Hash  []byte
+}
+
+func NewOpaque() (*Opaque, error) {
 	b := make([]byte, 32)
 
 	if _, err := rand.Read(b); err != nil {
@@ -87,11 +105,27 @@ func (mgr *Manager) getRefreshToken(ctx context.Context, id uuid.UUID) (*Refresh
 
 	raw := base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(b)
 
+	return &Opaque{
+		Plain: raw,
+		Hash:  HashOpaque(raw),
+	}, nil
+}
+
+func HashOpaque(plain string) []byte {
+	h := sha256.Sum256([]byte(plain))
+	return h[:]
+}
+
+func (mgr *Manager) getRefreshToken(ctx context.Context, id uuid.UUID) (*RefreshToken, error) {
+	opaque, err := NewOpaque()
+	if err != nil {
+		return nil, err
+	}
+
 	validTill := time.Now().Add(mgr.config.SessionExpire)
-	h := sha256.Sum256([]byte(raw))
 
 	if err := mgr.store.CreateSession(ctx, &entity.Session{
-		Hash:      h[:],
+		Hash:      opaque.Hash,
 		ValidTill: validTill,
 		UserID:    id,
 	}); err != nil {
@@ -99,18 +133,47 @@ func (mgr *Manager) getRefreshToken(ctx context.Context, id uuid.UUID) (*Refresh
 	}
 
 	return &RefreshToken{
-		Token:     raw,
+		Token:     opaque.Plain,
 		ValidTill: validTill,
 	}, nil
 }
 
+func (mgr *Manager) GetUserToken(
+	ctx context.Context,
+	id uuid.UUID,
+	purpose entity.TokenPurpose,
+	ttl time.Duration,
+) (string, error) {
+	if err := mgr.store.DeleteUserTokens(ctx, id, purpose); err != nil {
+		return "", err
+	}
+
+	opaque, err := NewOpaque()
+	if err != nil {
+		return "", err
+	}
+
+	if err := mgr.store.CreateUserToken(ctx, &entity.UserToken{
+		Hash:      opaque.Hash,
+		UserID:    id,
+		Purpose:   purpose,
+		ValidTill: time.Now().Add(ttl),
+	}); err != nil {
+		return "", err
+	}
+
+	return opaque.Plain, nil
+}
+
 func (mgr *Manager) DecodeAccessToken(raw string) (uuid.UUID, error) {
-	t, err := jwt.ParseWithClaims(raw, &Payload{}, func(t *jwt.Token) (interface{}, error) {
-		if signingMethod != t.Method {
-			return nil, fmt.Errorf("invalid signing method")
-		}
-		return []byte(mgr.config.JwtAccessSecret), nil
-	})
+	t, err := jwt.ParseWithClaims(
+		raw,
+		&Payload{},
+		mgr.keys.verificationKey,
+		jwt.WithValidMethods(mgr.keys.methods()),
+		jwt.WithIssuer(mgr.config.JwtIssuer),
+		jwt.WithAudience(mgr.config.JwtAudience),
+	)
 	if err != nil {
 		return uuid.UUID{}, err
 	}
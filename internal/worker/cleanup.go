@@ -0,0 +1,156 @@
+package worker
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/emma769/a-realtor/internal/config"
+)
+
+type storer interface {
+	PurgeExpiredSessions(context.Context, int) (int64, error)
+	PurgeExpiredUserTokens(context.Context, int) (int64, error)
+	PurgeStaleLoginThrottles(context.Context, time.Duration, int) (int64, error)
+	PurgeExpiredOIDCStates(context.Context, int) (int64, error)
+	PurgeFullRateLimits(context.Context, int) (int64, error)
+	PurgeExpiredIdempotencyKeys(context.Context, int) (int64, error)
+}
+
+type task struct {
+	name  string
+	purge func(context.Context, int) (int64, error)
+}
+
+type Status struct {
+	Running   bool      `json:"running"`
+	LastRunAt time.Time `json:"lastRunAt"`
+	LastError string    `json:"lastError,omitempty"`
+}
+
+type Cleanup struct {
+	tasks     []task
+	interval  time.Duration
+	batchSize int
+	timeout   time.Duration
+	logger    *slog.Logger
+
+	mu     sync.RWMutex
+	status Status
+}
+
+func NewCleanup(store storer, cfg *config.Config, logger *slog.Logger) *Cleanup {
+	return &Cleanup{
+		tasks: []task{
+			{"sessions", store.PurgeExpiredSessions},
+			{"user_tokens", store.PurgeExpiredUserTokens},
+			{"oidc_states", store.PurgeExpiredOIDCStates},
+			{"rate_limits", store.PurgeFullRateLimits},
+			{"idempotency_keys", store.PurgeExpiredIdempotencyKeys},
+			{"login_throttles", func(ctx context.Context, limit int) (int64, error) {
+				return store.PurgeStaleLoginThrottles(ctx, cfg.LoginFailureWindow, limit)
+			}},
+		},
+		interval:  cfg.CleanupInterval,
+		batchSize: cfg.CleanupBatchSize,
+		timeout:   30 * time.Second,
+		logger:    logger,
+	}
+}
+
+func (c *Cleanup) Status() Status {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.status
+}
+
+func (c *Cleanup) setStatus(fn func(*Status)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	fn(&c.status)
+}
+
+func (c *Cleanup) Run(ctx context.Context) {
+	c.setStatus(func(s *Status) { s.Running = true })
+	defer c.setStatus(func(s *Status) { s.Running = false })
+
+	c.logger.LogAttrs(ctx, slog.LevelInfo, "cleanup worker started", slog.Attr{
+		Key:   "interval",
+		Value: slog.DurationValue(c.interval),
+	})
+
+	ticker := time.NewTicker(c.interval)
+	defer ticker.Stop()
+
+	for {
+		c.runOnce(ctx)
+
+		select {
+		case <-ctx.Done():
+			c.logger.LogAttrs(context.Background(), slog.LevelInfo, "cleanup worker stopped")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (c *Cleanup) runOnce(ctx context.Context) {
+	var lastErr string
+
+	for _, t := range c.tasks {
+		total, err := c.purge(ctx, t)
+
+		if err != nil && ctx.Err() != nil {
+			return
+		}
+
+		if err != nil {
+			lastErr = err.Error()
+			c.logger.LogAttrs(ctx, slog.LevelError, "cleanup failed",
+				slog.String("table", t.name),
+				slog.String("detail", err.Error()),
+			)
+			continue
+		}
+
+		if total > 0 {
+			c.logger.LogAttrs(ctx, slog.LevelInfo, "cleanup purged rows",
+				slog.String("table", t.name),
+				slog.Int64("count", total),
+			)
+		}
+	}
+
+	c.setStatus(func(s *Status) {
+		s.LastRunAt = time.Now()
+		s.LastError = lastErr
+	})
+}
+
+func (c *Cleanup) purge(ctx context.Context, t task) (int64, error) {
+	var total int64
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return total, err
+		}
+
+		n, err := c.batch(ctx, t)
+		total += n
+
+		if err != nil {
+			return total, err
+		}
+
+		if n < int64(c.batchSize) {
+			return total, nil
+		}
+	}
+}
+
+func (c *Cleanup) batch(ctx context.Context, t task) (int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+	return t.purge(ctx, c.batchSize)
+}
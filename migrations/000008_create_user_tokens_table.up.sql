@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS user_tokens (
+  hash BYTEA NOT NULL,
+  user_id UUID NOT NULL,
+  purpose VARCHAR(30) NOT NULL,
+  valid_till TIMESTAMP WITH TIME ZONE NOT NULL,
+  created_at TIMESTAMP WITH TIME ZONE DEFAULT current_timestamp,
+  PRIMARY KEY(hash),
+  CONSTRAINT user_tokens_users_fk FOREIGN KEY(user_id) REFERENCES users(user_id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS user_tokens_user_id_purpose_idx ON user_tokens(user_id, purpose);
@@ -0,0 +1,4 @@
+ALTER TABLE users ADD COLUMN IF NOT EXISTS email_verified_at TIMESTAMP WITH TIME ZONE;
+
+UPDATE users SET email_verified_at = COALESCE(created_at, current_timestamp)
+WHERE email_verified_at IS NULL;
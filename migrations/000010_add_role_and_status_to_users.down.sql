@@ -0,0 +1,6 @@
+DELETE FROM users WHERE password IS NULL;
+
+ALTER TABLE users
+  DROP COLUMN IF EXISTS role,
+  DROP COLUMN IF EXISTS status,
+  ALTER COLUMN password SET NOT NULL;
@@ -0,0 +1,24 @@
+CREATE TABLE IF NOT EXISTS login_throttles (
+  key TEXT NOT NULL,
+  failures INT NOT NULL DEFAULT 0,
+  last_failure_at TIMESTAMP WITH TIME ZONE,
+  locked_until TIMESTAMP WITH TIME ZONE,
+  PRIMARY KEY(key)
+);
+
+CREATE TABLE IF NOT EXISTS lockouts (
+  lockout_id BIGINT GENERATED ALWAYS AS IDENTITY,
+  key TEXT NOT NULL,
+  user_id UUID,
+  ip TEXT NOT NULL,
+  failures INT NOT NULL,
+  locked_until TIMESTAMP WITH TIME ZONE NOT NULL,
+  unlocked_by UUID,
+  unlocked_at TIMESTAMP WITH TIME ZONE,
+  created_at TIMESTAMP WITH TIME ZONE DEFAULT current_timestamp,
+  PRIMARY KEY(lockout_id),
+  CONSTRAINT lockouts_users_fk FOREIGN KEY(user_id) REFERENCES users(user_id) ON DELETE SET NULL,
+  CONSTRAINT lockouts_unlocked_by_fk FOREIGN KEY(unlocked_by) REFERENCES users(user_id) ON DELETE SET NULL
+);
+
+CREATE INDEX IF NOT EXISTS lockouts_key_idx ON lockouts(key);
@@ -0,0 +1,23 @@
+ALTER TABLE users
+  ADD COLUMN IF NOT EXISTS totp_secret BYTEA,
+  ADD COLUMN IF NOT EXISTS totp_enabled_at TIMESTAMP WITH TIME ZONE,
+  ADD COLUMN IF NOT EXISTS totp_last_step BIGINT NOT NULL DEFAULT 0;
+
+CREATE TABLE IF NOT EXISTS recovery_codes (
+  recovery_code_id INT GENERATED ALWAYS AS IDENTITY,
+  user_id UUID NOT NULL,
+  hash BYTEA NOT NULL,
+  used_at TIMESTAMP WITH TIME ZONE,
+  created_at TIMESTAMP WITH TIME ZONE DEFAULT current_timestamp,
+  PRIMARY KEY(recovery_code_id),
+  CONSTRAINT recovery_codes_users_fk FOREIGN KEY(user_id) REFERENCES users(user_id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS recovery_codes_user_id_idx ON recovery_codes(user_id);
+
+CREATE TABLE IF NOT EXISTS role_policies (
+  role VARCHAR(20) NOT NULL,
+  require_2fa BOOLEAN NOT NULL DEFAULT false,
+  updated_at TIMESTAMP WITH TIME ZONE DEFAULT current_timestamp,
+  PRIMARY KEY(role)
+);
@@ -0,0 +1,18 @@
+CREATE TABLE IF NOT EXISTS audit_log (
+  audit_id BIGINT GENERATED ALWAYS AS IDENTITY,
+  actor_id UUID,
+  action VARCHAR(20) NOT NULL,
+  entity_type VARCHAR(40) NOT NULL,
+  entity_id UUID NOT NULL,
+  before JSONB,
+  after JSONB,
+  request_id TEXT NOT NULL DEFAULT '',
+  ip TEXT NOT NULL DEFAULT '',
+  created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT current_timestamp,
+  PRIMARY KEY(audit_id),
+  CONSTRAINT audit_log_users_fk FOREIGN KEY(actor_id) REFERENCES users(user_id) ON DELETE SET NULL
+);
+
+CREATE INDEX IF NOT EXISTS audit_log_entity_idx ON audit_log(entity_type, entity_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS audit_log_actor_idx ON audit_log(actor_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS audit_log_created_at_idx ON audit_log(created_at DESC);
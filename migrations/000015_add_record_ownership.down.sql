@@ -0,0 +1,5 @@
+ALTER TABLE tenants DROP COLUMN IF EXISTS assigned_to;
+ALTER TABLE landlords DROP COLUMN IF EXISTS assigned_to;
+ALTER TABLE users DROP COLUMN IF EXISTS manager_id;
+DROP INDEX IF EXISTS landlords_registered_by_idx;
+DROP INDEX IF EXISTS tenants_registered_by_idx;
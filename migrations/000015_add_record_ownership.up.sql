@@ -0,0 +1,17 @@
+ALTER TABLE users
+  ADD COLUMN IF NOT EXISTS manager_id UUID,
+  ADD CONSTRAINT users_manager_fk FOREIGN KEY(manager_id) REFERENCES users(user_id) ON DELETE SET NULL;
+
+ALTER TABLE landlords
+  ADD COLUMN IF NOT EXISTS assigned_to UUID,
+  ADD CONSTRAINT landlords_assignee_fk FOREIGN KEY(assigned_to) REFERENCES users(user_id) ON DELETE SET NULL;
+
+ALTER TABLE tenants
+  ADD COLUMN IF NOT EXISTS assigned_to UUID,
+  ADD CONSTRAINT tenants_assignee_fk FOREIGN KEY(assigned_to) REFERENCES users(user_id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS users_manager_id_idx ON users(manager_id);
+CREATE INDEX IF NOT EXISTS landlords_registered_by_idx ON landlords(registered_by);
+CREATE INDEX IF NOT EXISTS landlords_assigned_to_idx ON landlords(assigned_to);
+CREATE INDEX IF NOT EXISTS tenants_registered_by_idx ON tenants(registered_by);
+CREATE INDEX IF NOT EXISTS tenants_assigned_to_idx ON tenants(assigned_to);
@@ -0,0 +1,11 @@
+DROP INDEX IF EXISTS user_tokens_valid_till_idx;
+DROP INDEX IF EXISTS sessions_valid_till_idx;
+
+CREATE OR REPLACE function remove_old_session() RETURNS TRIGGER AS $$
+BEGIN
+  DELETE FROM sessions WHERE valid_till < CURRENT_TIMESTAMP - INTERVAL '1 minute';
+  return NEW;
+END;
+$$ LANGUAGE PLPGSQL;
+
+CREATE TRIGGER remove_old_session_trigger AFTER INSERT ON sessions EXECUTE PROCEDURE remove_old_session();
@@ -0,0 +1,5 @@
+DROP TRIGGER IF EXISTS remove_old_session_trigger ON sessions;
+DROP FUNCTION IF EXISTS remove_old_session;
+
+CREATE INDEX IF NOT EXISTS sessions_valid_till_idx ON sessions(valid_till);
+CREATE INDEX IF NOT EXISTS user_tokens_valid_till_idx ON user_tokens(valid_till);
@@ -0,0 +1,23 @@
+CREATE TABLE IF NOT EXISTS oidc_states (
+  hash BYTEA NOT NULL,
+  nonce TEXT NOT NULL,
+  verifier TEXT NOT NULL,
+  valid_till TIMESTAMP WITH TIME ZONE NOT NULL,
+  created_at TIMESTAMP WITH TIME ZONE DEFAULT current_timestamp,
+  PRIMARY KEY(hash)
+);
+
+CREATE INDEX IF NOT EXISTS oidc_states_valid_till_idx ON oidc_states(valid_till);
+
+CREATE TABLE IF NOT EXISTS user_identities (
+  issuer TEXT NOT NULL,
+  subject TEXT NOT NULL,
+  user_id UUID NOT NULL,
+  email TEXT NOT NULL,
+  created_at TIMESTAMP WITH TIME ZONE DEFAULT current_timestamp,
+  last_login_at TIMESTAMP WITH TIME ZONE,
+  PRIMARY KEY(issuer, subject),
+  CONSTRAINT user_identities_users_fk FOREIGN KEY(user_id) REFERENCES users(user_id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS user_identities_user_id_idx ON user_identities(user_id);
@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS rate_limits (
+  key TEXT NOT NULL,
+  tokens DOUBLE PRECISION NOT NULL,
+  updated_at TIMESTAMP WITH TIME ZONE NOT NULL,
+  full_at TIMESTAMP WITH TIME ZONE NOT NULL,
+  PRIMARY KEY(key)
+);
+
+CREATE INDEX IF NOT EXISTS rate_limits_full_at_idx ON rate_limits(full_at);
@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS idempotency_keys (
+  user_id UUID NOT NULL,
+  key TEXT NOT NULL,
+  request_hash BYTEA NOT NULL,
+  status INT,
+  header JSONB,
+  body BYTEA,
+  valid_till TIMESTAMP WITH TIME ZONE NOT NULL,
+  created_at TIMESTAMP WITH TIME ZONE DEFAULT current_timestamp,
+  PRIMARY KEY(user_id, key),
+  CONSTRAINT idempotency_keys_users_fk FOREIGN KEY(user_id) REFERENCES users(user_id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idempotency_keys_valid_till_idx ON idempotency_keys(valid_till);
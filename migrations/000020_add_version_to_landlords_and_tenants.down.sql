@@ -0,0 +1,3 @@
+ALTER TABLE tenants DROP COLUMN IF EXISTS version;
+
+ALTER TABLE landlords DROP COLUMN IF EXISTS version;
@@ -0,0 +1,3 @@
+ALTER TABLE landlords ADD COLUMN IF NOT EXISTS version INT NOT NULL DEFAULT 1;
+
+ALTER TABLE tenants ADD COLUMN IF NOT EXISTS version INT NOT NULL DEFAULT 1;